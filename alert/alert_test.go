@@ -0,0 +1,314 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2018, 10, 20, 23, 50, 0, 0, time.UTC)
+
+//recorder sends notifications straight to a slice rather than through a dispatcher
+type recorder struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (r *recorder) send(w Webhook, n Notification) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, n)
+}
+
+func (r *recorder) take() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	taken := r.notifications
+	r.notifications = nil
+	return taken
+}
+
+func testEngine(t *testing.T, config string) (*Engine, *recorder) {
+	c, err := Parse([]byte(config))
+	require.NoError(t, err)
+
+	r := &recorder{}
+	return NewEngine(c, time.UTC, r.send), r
+}
+
+func sample(minutes int, metrics client.BaseMetrics) client.Sample {
+	return client.Sample{Time: start.Add(time.Minute * time.Duration(minutes)), Metrics: metrics}
+}
+
+func TestDemandAbove(t *testing.T) {
+	e, r := testEngine(t, `{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [{"name": "high", "type": "demand_above", "threshold": 5, "clear": 4, "for": "2m"}]
+	}`)
+	ctx := context.Background()
+
+	e.Sample(ctx, sample(0, client.BaseMetrics{Demand: 6}))
+	e.Sample(ctx, sample(1, client.BaseMetrics{Demand: 6}))
+	assert.Empty(t, r.take(), "has not held for 2m")
+
+	e.Sample(ctx, sample(2, client.BaseMetrics{Demand: 7}))
+	firing := r.take()
+	require.Len(t, firing, 1)
+	assert.Equal(t, Firing, firing[0].Status)
+	assert.Equal(t, start, firing[0].Since)
+	assert.Equal(t, 7.0, firing[0].Value)
+	assert.True(t, e.Firing()["high"])
+
+	//hysteresis, below the threshold but above clear
+	e.Sample(ctx, sample(3, client.BaseMetrics{Demand: 4.5}))
+	e.Sample(ctx, sample(4, client.BaseMetrics{Demand: 6}))
+	assert.Empty(t, r.take(), "already firing")
+
+	e.Sample(ctx, sample(5, client.BaseMetrics{Demand: 3}))
+	resolved := r.take()
+	require.Len(t, resolved, 1)
+	assert.Equal(t, Resolved, resolved[0].Status)
+	assert.False(t, e.Firing()["high"])
+}
+
+func TestDailyUsageAndDisconnected(t *testing.T) {
+	e, r := testEngine(t, `{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [
+			{"name": "budget", "type": "daily_usage_above", "threshold": 10},
+			{"name": "offline", "type": "meter_disconnected", "for": "5m"}
+		]
+	}`)
+	ctx := context.Background()
+
+	e.Sample(ctx, sample(0, client.BaseMetrics{Delivered: 100}))
+	e.Sample(ctx, sample(1, client.BaseMetrics{Delivered: 111}))
+	notifications := r.take()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "budget", notifications[0].Rule)
+	assert.Equal(t, 11.0, notifications[0].Value)
+
+	//reagled's own rate limit is not a disconnection
+	e.PollError(ctx, start.Add(time.Minute*2), client.ErrRateLimited)
+	e.PollError(ctx, start.Add(time.Minute*10), client.ErrRateLimited)
+	assert.Empty(t, r.take())
+
+	unreachable := &local.MeterUnreachableError{ConnectionStatus: local.NotJoined}
+	e.PollError(ctx, start.Add(time.Minute*3), unreachable)
+	e.PollError(ctx, start.Add(time.Minute*8), unreachable)
+	notifications = r.take()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "offline", notifications[0].Rule)
+	assert.Equal(t, 5.0, notifications[0].Value)
+
+	//the next day the budget starts again and the meter is back
+	e.Sample(ctx, sample(11, client.BaseMetrics{Delivered: 112}))
+	notifications = r.take()
+	require.Len(t, notifications, 2)
+	for _, n := range notifications {
+		assert.Equal(t, Resolved, n.Status)
+	}
+}
+
+func TestStaleReadings(t *testing.T) {
+	e, r := testEngine(t, `{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [{"name": "offline", "type": "meter_disconnected", "for": "5m"}]
+	}`)
+	e.StaleAfter = time.Minute * 2
+	ctx := context.Background()
+
+	//readings that stop without a poll error, as when the eagle stops pushing them
+	e.Sample(ctx, sample(0, client.BaseMetrics{}))
+	e.Check(start.Add(time.Minute * 2))
+	e.Check(start.Add(time.Minute * 4))
+	assert.Empty(t, r.take(), "has not been 5m since the last reading")
+
+	e.Check(start.Add(time.Minute * 5))
+	notifications := r.take()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, Firing, notifications[0].Status)
+	assert.Equal(t, start, notifications[0].Since)
+	assert.Equal(t, 5.0, notifications[0].Value)
+
+	e.Sample(ctx, sample(6, client.BaseMetrics{}))
+	notifications = r.take()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, Resolved, notifications[0].Status)
+}
+
+func TestSeedDayUsage(t *testing.T) {
+	e, r := testEngine(t, `{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [{"name": "budget", "type": "daily_usage_above", "threshold": 10}]
+	}`)
+	ctx := context.Background()
+
+	e.SeedDayUsage(start, 9)
+	e.Sample(ctx, sample(0, client.BaseMetrics{Delivered: 100}))
+	assert.Empty(t, r.take())
+
+	e.Sample(ctx, sample(1, client.BaseMetrics{Delivered: 102}))
+	notifications := r.take()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, 11.0, notifications[0].Value)
+}
+
+func TestChanges(t *testing.T) {
+	e, r := testEngine(t, `{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [
+			{"name": "price", "type": "price_changed"},
+			{"name": "message", "type": "new_message"}
+		]
+	}`)
+	ctx := context.Background()
+	require.True(t, e.WantsMessages())
+
+	e.Sample(ctx, sample(0, client.BaseMetrics{Price: 0.1, Currency: "USD"}))
+	e.Message(start, "hello")
+	assert.Empty(t, r.take(), "first values are the baseline")
+
+	e.Sample(ctx, sample(1, client.BaseMetrics{Price: 0.1, Currency: "USD"}))
+	e.Message(start.Add(time.Minute), "hello")
+	assert.Empty(t, r.take(), "duplicates are not notified")
+
+	e.Sample(ctx, sample(2, client.BaseMetrics{Price: 0.2, Currency: "USD"}))
+	e.Message(start.Add(time.Minute*2), "peak pricing today")
+	e.Message(start.Add(time.Minute*3), "")
+	e.Message(start.Add(time.Minute*4), "peak pricing today")
+
+	notifications := r.take()
+	require.Len(t, notifications, 3)
+	assert.Equal(t, "price: price changed to 0.2 USD/kWh", notifications[0].Summary)
+	assert.Equal(t, "peak pricing today", notifications[1].Text)
+	assert.Equal(t, "peak pricing today", notifications[2].Text, "sent again after it was cleared")
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"unknown_type":    `{"rules": [{"name": "a", "type": "nope"}]}`,
+		"unknown_webhook": `{"rules": [{"name": "a", "type": "new_message", "webhooks": ["missing"]}]}`,
+		"clear_above":     `{"rules": [{"name": "a", "type": "demand_above", "threshold": 1, "clear": 2}]}`,
+		"bad_template":    `{"webhooks": [{"name": "a", "url": "http://localhost", "template": "{{"}]}`,
+		"bad_duration":    `{"rules": [{"name": "a", "type": "meter_disconnected", "for": "soon"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(body))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	bodies := make(chan []byte, 10)
+	failures := 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer ts.Close()
+
+	c, err := Parse([]byte(`{"webhooks": [
+		{"name": "templated", "url": "` + ts.URL + `", "headers": {"X-Token": "secret"}, "template": "{\"text\": {{json .Summary}}}"}
+	]}`))
+	require.NoError(t, err)
+
+	results := make(chan error, 10)
+	d := NewDispatcher(ts.Client(), c.Webhooks, 10)
+	d.Backoff = time.Millisecond
+	d.Result = func(webhook string, err error) {
+		results <- err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Send(c.Webhooks[0], Notification{Summary: `said "hi"`})
+
+	select {
+	case b := <-bodies:
+		body := map[string]string{}
+		require.NoError(t, json.Unmarshal(b, &body))
+		assert.Equal(t, `said "hi"`, body["text"])
+	case <-time.After(time.Second * 5):
+		require.Fail(t, "webhook not called")
+	}
+
+	assert.NoError(t, <-results, "retried after the failure")
+
+	full := NewDispatcher(ts.Client(), c.Webhooks, 0)
+	full.Result = d.Result
+	full.Send(c.Webhooks[0], Notification{})
+	assert.True(t, errors.Is(<-results, ErrQueueFull))
+}
+
+func TestDispatcherWebhooksAreIndependent(t *testing.T) {
+	stuck := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stuck
+	}))
+	defer slow.Close()
+	//unblocks the handler before Close waits for it
+	defer close(stuck)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	c, err := Parse([]byte(`{"webhooks": [
+		{"name": "slow", "url": "` + slow.URL + `"},
+		{"name": "fast", "url": "` + fast.URL + `"}
+	]}`))
+	require.NoError(t, err)
+
+	results := make(chan string, 10)
+	d := NewDispatcher(http.DefaultClient, c.Webhooks, 10)
+	d.Result = func(webhook string, err error) {
+		if err == nil {
+			results <- webhook
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+
+	d.Send(c.Webhooks[0], Notification{})
+	d.Send(c.Webhooks[1], Notification{})
+
+	select {
+	case webhook := <-results:
+		assert.Equal(t, "fast", webhook)
+	case <-time.After(time.Second * 5):
+		require.Fail(t, "the fast webhook waited on the slow one")
+	}
+
+	d.Send(Webhook{Name: "unknown"}, Notification{})
+
+	cancel()
+	<-done
+}
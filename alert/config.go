@@ -0,0 +1,205 @@
+/*
+Package alert raises alerts from the meter's readings and sends them to webhooks, for sites without Alertmanager.
+
+Rules
+
+Threshold rules (demand_above, daily_usage_above and meter_disconnected) fire once their condition has held for the
+rule's For duration and resolve when it stops holding.  Threshold rules can resolve at a lower Clear value than they
+fire at, so a reading hovering around the threshold does not flap.
+
+Change rules (price_changed and new_message) fire each time the value changes to one that has not been seen
+immediately before, and never resolve.  The first value seen after startup is not a change.
+
+Webhooks
+
+Every notification is sent to the rule's webhooks, or all of them if the rule does not name any.  The body is the
+Notification as json unless the webhook has a text/template, which is executed with the Notification.  The template
+func json quotes a value, e.g. {"text": {{json .Summary}}}.
+*/
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+	"time"
+)
+
+const (
+	//DemandAbove fires when the instantaneous demand is above Threshold kW
+	DemandAbove = "demand_above"
+	//DailyUsageAbove fires when the energy delivered since local midnight is above Threshold kWh
+	DailyUsageAbove = "daily_usage_above"
+	//MeterDisconnected fires when the meter can not be read, or there has not been a reading for the engine's StaleAfter
+	MeterDisconnected = "meter_disconnected"
+	//PriceChanged fires when the meter's price changes, which is how a change of price tier shows up
+	PriceChanged = "price_changed"
+	//NewMessage fires when the utility sends a new message to the meter
+	NewMessage = "new_message"
+)
+
+type (
+	//Config is the rules and the webhooks their notifications are sent to
+	Config struct {
+		Webhooks []Webhook `json:"webhooks"`
+		Rules    []Rule    `json:"rules"`
+	}
+
+	//Rule is a condition to alert on
+	Rule struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+
+		Threshold float64 `json:"threshold"`
+		//Clear is the value a firing threshold rule resolves at, defaults to Threshold
+		Clear *float64 `json:"clear,omitempty"`
+		//For is how long the condition has to hold before the rule fires
+		For Duration `json:"for"`
+
+		//names of the webhooks to notify, empty means all of them
+		Webhooks []string `json:"webhooks,omitempty"`
+	}
+
+	//Webhook is an http endpoint notifications are sent to
+	Webhook struct {
+		Name    string            `json:"name"`
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers,omitempty"`
+
+		//Template is a text/template for the body, the Notification as json if empty
+		Template string `json:"template,omitempty"`
+
+		template *template.Template
+	}
+
+	//Duration is a time.Duration that is a string like "5m" in json
+	Duration time.Duration
+)
+
+//Load reads and validates a json Config from the file
+func Load(path string) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Parse(b)
+}
+
+//Parse unmarshals and validates a json Config
+func Parse(b []byte) (Config, error) {
+	c := Config{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+
+	return c, c.validate()
+}
+
+func (c *Config) validate() error {
+	webhooks := make(map[string]bool)
+	for i := range c.Webhooks {
+		w := &c.Webhooks[i]
+		if w.Name == "" || w.URL == "" {
+			return fmt.Errorf("webhooks need a name and url: %v", w)
+		}
+
+		if webhooks[w.Name] {
+			return fmt.Errorf("duplicate webhook: %s", w.Name)
+		}
+
+		webhooks[w.Name] = true
+
+		if w.Method == "" {
+			w.Method = "POST"
+		}
+
+		if w.Template != "" {
+			t, err := template.New(w.Name).Funcs(template.FuncMap{"json": jsonValue}).Parse(w.Template)
+			if err != nil {
+				return fmt.Errorf("webhook %s: %v", w.Name, err)
+			}
+
+			w.template = t
+		}
+	}
+
+	rules := make(map[string]bool)
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Name == "" {
+			return fmt.Errorf("rules need a name: %v", r)
+		}
+
+		if rules[r.Name] {
+			return fmt.Errorf("duplicate rule: %s", r.Name)
+		}
+
+		rules[r.Name] = true
+
+		switch r.Type {
+		case DemandAbove, DailyUsageAbove:
+			if r.Clear != nil && *r.Clear > r.Threshold {
+				return fmt.Errorf("rule %s: clear %v is above the threshold %v", r.Name, *r.Clear, r.Threshold)
+			}
+		case MeterDisconnected, PriceChanged, NewMessage:
+		default:
+			return fmt.Errorf("rule %s: unknown type %s", r.Name, r.Type)
+		}
+
+		for _, name := range r.Webhooks {
+			if !webhooks[name] {
+				return fmt.Errorf("rule %s: unknown webhook %s", r.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+//clear is the value the rule resolves at or below
+func (r Rule) clear() float64 {
+	if r.Clear == nil {
+		return r.Threshold
+	}
+
+	return *r.Clear
+}
+
+//threshold rules fire and resolve, the others are changes that only fire
+func (r Rule) isThreshold() bool {
+	switch r.Type {
+	case DemandAbove, DailyUsageAbove, MeterDisconnected:
+		return true
+	default:
+		return false
+	}
+}
+
+//UnmarshalJSON parses a time.ParseDuration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+//MarshalJSON is the inverse of UnmarshalJSON
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func jsonValue(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,362 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/storage"
+)
+
+const (
+	//DefaultStaleAfter is how long without a reading before the meter is disconnected, when polls are not failing
+	DefaultStaleAfter = time.Minute * 5
+
+	//Firing is the status of a notification that a rule has fired
+	Firing = "firing"
+	//Resolved is the status of a notification that a threshold rule's condition no longer holds
+	Resolved = "resolved"
+)
+
+type (
+	//Notification is sent to webhooks when a rule fires or resolves
+	Notification struct {
+		Rule   string    `json:"rule"`
+		Type   string    `json:"type"`
+		Status string    `json:"status"`
+		Time   time.Time `json:"time"`
+
+		//Since is when the condition started holding
+		Since time.Time `json:"since"`
+
+		Value     float64 `json:"value"`
+		Threshold float64 `json:"threshold"`
+
+		//Text is the message for new_message rules
+		Text    string `json:"text,omitempty"`
+		Summary string `json:"summary"`
+	}
+
+	//Sender delivers a notification to a webhook, it should not block
+	Sender func(Webhook, Notification)
+
+	//Engine evaluates the rules as readings arrive, it is safe for concurrent use
+	Engine struct {
+		config Config
+		loc    *time.Location
+		send   Sender
+
+		//StaleAfter is how long without a reading before the meter is disconnected.  Poll errors mark it disconnected
+		//straight away, this catches readings that stop arriving without errors, as when the eagle stops pushing them
+		StaleAfter time.Duration
+
+		mu    sync.Mutex
+		rules []*ruleState
+
+		demand            float64
+		price             float64
+		currency          string
+		lastSample        time.Time
+		disconnectedSince time.Time
+		message           string
+
+		dayStart      time.Time
+		dayUsage      float64
+		lastDelivered float64
+		delivered     bool
+	}
+
+	ruleState struct {
+		rule Rule
+
+		//threshold rules
+		firing       bool
+		pendingSince time.Time
+
+		//change rules
+		seen bool
+		last string
+	}
+
+	input int
+)
+
+const (
+	sampleInput input = iota
+	errorInput
+	checkInput
+	messageInput
+)
+
+//NewEngine creates an engine for the config, with days in loc, that hands notifications to send
+func NewEngine(config Config, loc *time.Location, send Sender) *Engine {
+	e := &Engine{config: config, loc: loc, send: send, StaleAfter: DefaultStaleAfter}
+	for _, r := range config.Rules {
+		e.rules = append(e.rules, &ruleState{rule: r})
+	}
+
+	return e
+}
+
+//WantsMessages is true if any rule needs the utility's messages passed to Message
+func (e *Engine) WantsMessages() bool {
+	for _, r := range e.config.Rules {
+		if r.Type == NewMessage {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Sample is a client.Sink for the poller's readings
+func (e *Engine) Sample(ctx context.Context, sample client.Sample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	day := startOfDay(sample.Time, e.loc)
+	if !day.Equal(e.dayStart) {
+		e.dayStart = day
+		e.dayUsage = 0
+	}
+
+	if e.delivered {
+		e.dayUsage += storage.CounterDelta(e.lastDelivered, sample.Metrics.Delivered)
+	}
+
+	e.lastDelivered = sample.Metrics.Delivered
+	e.delivered = true
+
+	e.demand = sample.Metrics.Demand
+	e.price = sample.Metrics.Price
+	e.currency = sample.Metrics.Currency
+	e.lastSample = sample.Time
+	e.disconnectedSince = time.Time{}
+
+	e.evaluate(sample.Time, sampleInput)
+}
+
+//SeedDayUsage sets the energy delivered so far on the day ts is in, from stored history so a restart does not lose it
+func (e *Engine) SeedDayUsage(ts time.Time, kwh float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.dayStart = startOfDay(ts, e.loc)
+	e.dayUsage = kwh
+}
+
+//Check evaluates the rules that depend on the passing of time rather than new readings, it is called periodically
+func (e *Engine) Check(ts time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.evaluate(ts, checkInput)
+}
+
+//PollError is a client.ErrorSink for the poller's failures.  reagled rate limiting itself or shutting down does not
+//mean the meter is disconnected
+func (e *Engine) PollError(ctx context.Context, ts time.Time, err error) {
+	if errors.Is(err, client.ErrRateLimited) || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.disconnectedSince.IsZero() {
+		e.disconnectedSince = ts
+	}
+
+	e.evaluate(ts, errorInput)
+}
+
+//Message passes the utility's current message, empty if there is none
+func (e *Engine) Message(ts time.Time, message string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.message = message
+	e.evaluate(ts, messageInput)
+}
+
+//Firing returns whether each threshold rule is firing
+func (e *Engine) Firing() map[string]bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	firing := make(map[string]bool)
+	for _, rs := range e.rules {
+		if rs.rule.isThreshold() {
+			firing[rs.rule.Name] = rs.firing
+		}
+	}
+
+	return firing
+}
+
+func (e *Engine) evaluate(ts time.Time, in input) {
+	for _, rs := range e.rules {
+		r := rs.rule
+		switch {
+		case r.Type == DemandAbove && in == sampleInput:
+			e.threshold(rs, ts, e.demand, e.demand > r.Threshold, e.demand <= r.clear())
+		case r.Type == DailyUsageAbove && in == sampleInput:
+			e.threshold(rs, ts, e.dayUsage, e.dayUsage > r.Threshold, e.dayUsage <= r.clear())
+		case r.Type == MeterDisconnected && in != messageInput:
+			since, disconnected := e.disconnected(ts)
+			minutes := 0.0
+			if disconnected {
+				minutes = ts.Sub(since).Minutes()
+				if rs.pendingSince.IsZero() {
+					rs.pendingSince = since
+				}
+			}
+
+			e.threshold(rs, ts, minutes, disconnected, !disconnected)
+		case r.Type == PriceChanged && in == sampleInput:
+			e.change(rs, ts, strconv.FormatFloat(e.price, 'f', -1, 64)+e.currency, e.price, "")
+		case r.Type == NewMessage && in == messageInput:
+			if e.message == "" {
+				//so the same message sent again later is new
+				rs.seen = true
+				rs.last = ""
+				continue
+			}
+
+			e.change(rs, ts, e.message, 0, e.message)
+		}
+	}
+}
+
+//disconnected is whether the meter can not be read at ts and since when, either polls are failing or there has not
+//been a reading for StaleAfter
+func (e *Engine) disconnected(ts time.Time) (time.Time, bool) {
+	if !e.disconnectedSince.IsZero() {
+		return e.disconnectedSince, true
+	}
+
+	if e.StaleAfter > 0 && !e.lastSample.IsZero() && ts.Sub(e.lastSample) > e.StaleAfter {
+		return e.lastSample, true
+	}
+
+	return time.Time{}, false
+}
+
+//threshold fires once breached has held for the rule's For duration, and resolves when cleared
+func (e *Engine) threshold(rs *ruleState, ts time.Time, value float64, breached bool, cleared bool) {
+	if rs.firing {
+		if cleared {
+			rs.firing = false
+			e.notify(rs, Resolved, ts, rs.pendingSince, value, "")
+			rs.pendingSince = time.Time{}
+		}
+
+		return
+	}
+
+	if !breached {
+		rs.pendingSince = time.Time{}
+		return
+	}
+
+	if rs.pendingSince.IsZero() {
+		rs.pendingSince = ts
+	}
+
+	if ts.Sub(rs.pendingSince) >= time.Duration(rs.rule.For) {
+		rs.firing = true
+		e.notify(rs, Firing, ts, rs.pendingSince, value, "")
+	}
+}
+
+//change fires when key differs from the last key seen, the first key is the baseline
+func (e *Engine) change(rs *ruleState, ts time.Time, key string, value float64, text string) {
+	if !rs.seen {
+		rs.seen = true
+		rs.last = key
+		return
+	}
+
+	if key == rs.last {
+		return
+	}
+
+	rs.last = key
+	e.notify(rs, Firing, ts, ts, value, text)
+}
+
+func (e *Engine) notify(rs *ruleState, status string, ts time.Time, since time.Time, value float64, text string) {
+	n := Notification{
+		Rule:      rs.rule.Name,
+		Type:      rs.rule.Type,
+		Status:    status,
+		Time:      ts,
+		Since:     since,
+		Value:     value,
+		Threshold: rs.rule.Threshold,
+		Text:      text,
+	}
+
+	n.Summary = e.summary(n)
+
+	for _, w := range e.config.Webhooks {
+		if wants(rs.rule, w) {
+			e.send(w, n)
+		}
+	}
+}
+
+func wants(r Rule, w Webhook) bool {
+	if len(r.Webhooks) == 0 {
+		return true
+	}
+
+	for _, name := range r.Webhooks {
+		if name == w.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *Engine) summary(n Notification) string {
+	since := n.Since.In(e.loc).Format("15:04")
+	resolved := n.Status == Resolved
+
+	switch n.Type {
+	case DemandAbove:
+		if resolved {
+			return fmt.Sprintf("%s: demand is back down to %.3f kW", n.Rule, n.Value)
+		}
+
+		return fmt.Sprintf("%s: demand %.3f kW has been above %v kW since %s", n.Rule, n.Value, n.Threshold, since)
+	case DailyUsageAbove:
+		if resolved {
+			return fmt.Sprintf("%s: daily usage is back under budget at %.3f kWh", n.Rule, n.Value)
+		}
+
+		return fmt.Sprintf("%s: %.3f kWh used today is above the %v kWh budget", n.Rule, n.Value, n.Threshold)
+	case MeterDisconnected:
+		if resolved {
+			return fmt.Sprintf("%s: meter is readable again", n.Rule)
+		}
+
+		return fmt.Sprintf("%s: meter has not been readable since %s", n.Rule, since)
+	case PriceChanged:
+		return fmt.Sprintf("%s: price changed to %v %s/kWh", n.Rule, n.Value, e.currency)
+	case NewMessage:
+		return fmt.Sprintf("%s: new message from the utility: %s", n.Rule, n.Text)
+	default:
+		return fmt.Sprintf("%s %s", n.Rule, n.Status)
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
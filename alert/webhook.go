@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//ErrQueueFull is passed to the dispatcher's Result when a notification is dropped because the queue is full
+var ErrQueueFull = errors.New("notification queue is full")
+
+type (
+	//Dispatcher delivers notifications to webhooks in the background, retrying failures.  Each webhook has its own queue
+	//so one that is down does not hold up the others
+	Dispatcher struct {
+		client *http.Client
+		queues map[string]chan delivery
+
+		//Retries is how many times a failed delivery is retried, doubling Backoff between each
+		Retries int
+		Backoff time.Duration
+
+		//Result, if set, is called with the outcome of every notification
+		Result func(webhook string, err error)
+	}
+
+	delivery struct {
+		webhook      Webhook
+		notification Notification
+	}
+)
+
+//NewDispatcher creates a Dispatcher that queues up to size notifications for each of the webhooks, nothing is sent
+//until Run is called
+func NewDispatcher(client *http.Client, webhooks []Webhook, size int) *Dispatcher {
+	queues := make(map[string]chan delivery)
+	for _, w := range webhooks {
+		queues[w.Name] = make(chan delivery, size)
+	}
+
+	return &Dispatcher{client: client, queues: queues, Retries: 3, Backoff: time.Second}
+}
+
+//Send queues the notification, it is a Sender
+func (d *Dispatcher) Send(w Webhook, n Notification) {
+	queue, ok := d.queues[w.Name]
+	if !ok {
+		d.result(w.Name, fmt.Errorf("unknown webhook %s", w.Name))
+		return
+	}
+
+	select {
+	case queue <- delivery{webhook: w, notification: n}:
+	default:
+		d.result(w.Name, ErrQueueFull)
+	}
+}
+
+//Run delivers queued notifications until the context is done, returning once every webhook's delivery has stopped
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, queue := range d.queues {
+		wg.Add(1)
+		go func(queue chan delivery) {
+			defer wg.Done()
+			d.run(ctx, queue)
+		}(queue)
+	}
+
+	wg.Wait()
+}
+
+func (d *Dispatcher) run(ctx context.Context, queue chan delivery) {
+	for {
+		select {
+		case next := <-queue:
+			d.result(next.webhook.Name, d.deliver(ctx, next))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, next delivery) error {
+	body, err := next.webhook.Body(next.notification)
+	if err != nil {
+		return err
+	}
+
+	backoff := d.Backoff
+	for attempt := 0; ; attempt++ {
+		err = post(ctx, d.client, next.webhook, body)
+		if err == nil || attempt >= d.Retries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *Dispatcher) result(webhook string, err error) {
+	if d.Result != nil {
+		d.Result(webhook, err)
+	}
+}
+
+//Body is the request body the webhook is sent for the notification
+func (w Webhook) Body(n Notification) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(n)
+	}
+
+	buf := &bytes.Buffer{}
+	err := w.template.Execute(buf, n)
+	return buf.Bytes(), err
+}
+
+func post(ctx context.Context, client *http.Client, w Webhook, body []byte) error {
+	timeout, clean := context.WithTimeout(ctx, time.Second*10)
+	defer clean()
+
+	req, err := http.NewRequestWithContext(timeout, w.Method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	//drain so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", w.Name, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Package auth authenticates requests to reagled's http and grpc apis and checks they are allowed what they ask for.
+
+Methods
+
+A request authenticates with a bearer token, with basic auth against a user's bcrypt hash, or with a client
+certificate.  Client certificates are only seen when reagled serves tls and verifies them, a certificate is matched on
+its subject's common name.  Browsers can't send bearer tokens from an EventSource, so people using the dashboard want
+basic auth.
+
+bcrypt is slow on purpose, so a user's verified password is remembered for a minute rather than hashed on every
+request, and an address that fails basic auth too often is refused without checking for a while.
+
+Scopes
+
+Every credential has a scope.  Read can use everything that only reads, control can also use anything that changes
+something.  Which scope a request needs is up to the Middleware's Policy, None leaves the request open.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+//Scope is what a credential is allowed to do
+type Scope string
+
+const (
+	//None is the scope needed for requests that are not authenticated
+	None Scope = ""
+	//Read allows requests that only read
+	Read Scope = "read"
+	//Control allows everything
+	Control Scope = "control"
+)
+
+var (
+	//ErrNoCredentials is returned by a Method when the request has none of its kind of credentials
+	ErrNoCredentials = errors.New("no credentials")
+	//ErrInvalidCredentials is returned by a Method when the request's credentials are not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	//ErrForbidden is when the credentials are valid but their scope does not allow the request
+	ErrForbidden = errors.New("forbidden")
+	//ErrTooManyFailures is returned by a Method when the request's address has failed too often to be checked again yet
+	ErrTooManyFailures = errors.New("too many failed attempts")
+)
+
+//Allows is true if a credential with this scope can make requests needing the required scope
+func (s Scope) Allows(required Scope) bool {
+	switch required {
+	case None:
+		return true
+	case Read:
+		return s == Read || s == Control
+	default:
+		return s == required
+	}
+}
+
+type (
+	//Identity is who authenticated and what they are allowed
+	Identity struct {
+		Name   string `json:"name"`
+		Method string `json:"method"`
+		Scope  Scope  `json:"scope"`
+	}
+
+	//Method is a way of authenticating a request
+	Method interface {
+		//Name is how the method is known in the Identity, logs and metrics
+		Name() string
+
+		//Authenticate is ErrNoCredentials if the request does not have this method's kind of credentials, or
+		//ErrInvalidCredentials if they are not valid
+		Authenticate(r *http.Request) (Identity, error)
+
+		//Challenge is the WWW-Authenticate header asking for this method's credentials, empty if there is none
+		Challenge() string
+	}
+
+	//Middleware authenticates requests with the first Method they have credentials for
+	Middleware struct {
+		Methods []Method
+
+		//Policy is the scope a request needs, ByMethod if nil
+		Policy func(r *http.Request) Scope
+
+		//Result is told every request's outcome, the Identity only has the Method if the credentials were invalid
+		//and is empty if there were none
+		Result func(r *http.Request, id Identity, err error)
+	}
+)
+
+//ByMethod is the Policy that requests which can't change anything, GET, HEAD and OPTIONS, need Read and everything else
+//needs Control
+func ByMethod(r *http.Request) Scope {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return Read
+	default:
+		return Control
+	}
+}
+
+//Handler enforces the policy on every request before h sees it, h can get the Identity with FromContext
+func (m Middleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := m.Policy
+		if policy == nil {
+			policy = ByMethod
+		}
+
+		required := policy(r)
+		id, err := m.Check(r, required)
+
+		switch {
+		case err == nil && required == None:
+			h.ServeHTTP(w, r)
+		case err == nil:
+			h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		case err == ErrForbidden:
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			for _, method := range m.Methods {
+				if challenge := method.Challenge(); challenge != "" {
+					w.Header().Add("WWW-Authenticate", challenge)
+				}
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	})
+}
+
+//Check authenticates the request and checks it is allowed the required scope, telling Result the outcome.  It is what
+//Handler enforces, for requests that do not go through it such as grpc calls
+func (m Middleware) Check(r *http.Request, required Scope) (Identity, error) {
+	if required == None {
+		m.result(r, Identity{}, nil)
+		return Identity{}, nil
+	}
+
+	id, err := m.Authenticate(r)
+	if err == nil && !id.Scope.Allows(required) {
+		err = ErrForbidden
+	}
+
+	m.result(r, id, err)
+	return id, err
+}
+
+//Authenticate tries the methods in order, the first that finds credentials decides
+func (m Middleware) Authenticate(r *http.Request) (Identity, error) {
+	for _, method := range m.Methods {
+		id, err := method.Authenticate(r)
+		switch err {
+		case nil:
+			return id, nil
+		case ErrNoCredentials:
+			continue
+		default:
+			return Identity{Method: method.Name()}, err
+		}
+	}
+
+	return Identity{}, ErrNoCredentials
+}
+
+func (m Middleware) result(r *http.Request, id Identity, err error) {
+	if m.Result != nil {
+		m.Result(r, id, err)
+	}
+}
+
+type identityKey struct{}
+
+//NewContext is the context carrying the authenticated Identity
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+//FromContext is the Identity the Middleware authenticated, false if the request was not authenticated
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParse(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	config, err := Parse([]byte(`{
+		"tokens": [{"name": "grafana", "token": "abc"}, {"name": "hass", "token": "def", "scope": "control"}],
+		"users": [{"name": "family", "bcrypt_hash": "` + string(hash) + `"}],
+		"client_certs": [{"common_name": "proxy", "scope": "control"}]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, Read, config.Tokens[0].Scope, "read only unless given a scope")
+	assert.Equal(t, Control, config.Tokens[1].Scope)
+	assert.Equal(t, Read, config.Users[0].Scope)
+	assert.Len(t, config.Methods(), 3)
+
+	for _, invalid := range []string{
+		`{}`,
+		`{"tokens": [{"name": "grafana"}]}`,
+		`{"tokens": [{"name": "grafana", "token": "abc"}, {"name": "other", "token": "abc"}]}`,
+		`{"tokens": [{"name": "grafana", "token": "abc", "scope": "admin"}]}`,
+		`{"users": [{"name": "family", "bcrypt_hash": "secret"}]}`,
+		`{"client_certs": [{"scope": "read"}]}`,
+	} {
+		_, err := Parse([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	assert.True(t, Read.Allows(None))
+	assert.True(t, Read.Allows(Read))
+	assert.False(t, Read.Allows(Control))
+	assert.True(t, Control.Allows(Read))
+	assert.True(t, Control.Allows(Control))
+}
+
+func TestMiddleware(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	config := Config{
+		Tokens:      []Token{{Name: "grafana", Token: "readtoken", Scope: Read}, {Name: "hass", Token: "controltoken", Scope: Control}},
+		Users:       []User{{Name: "family", BcryptHash: string(hash), Scope: Read}},
+		ClientCerts: []ClientCert{{CommonName: "proxy", Scope: Control}},
+	}
+
+	var results []error
+	m := Middleware{
+		Methods: config.Methods(),
+		Policy: func(r *http.Request) Scope {
+			if r.URL.Path == "/metrics" {
+				return None
+			}
+			return ByMethod(r)
+		},
+		Result: func(r *http.Request, id Identity, err error) { results = append(results, err) },
+	}
+
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		w.Write([]byte(id.Method + ":" + id.Name))
+	}))
+
+	cert := func(name string) *tls.ConnectionState {
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: name}}}}}
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		auth   func(r *http.Request)
+		code   int
+		body   string
+	}{
+		{name: "open", method: "GET", path: "/metrics", code: http.StatusOK, body: ":"},
+		{name: "none", method: "GET", path: "/local/devicelist", code: http.StatusUnauthorized},
+		{name: "bearer", method: "GET", path: "/local/devicelist", auth: bearer("readtoken"), code: http.StatusOK, body: "bearer:grafana"},
+		{name: "lowercase bearer", method: "GET", path: "/local/devicelist", auth: func(r *http.Request) { r.Header.Set("Authorization", "bearer readtoken") }, code: http.StatusOK, body: "bearer:grafana"},
+		{name: "wrong token", method: "GET", path: "/local/devicelist", auth: bearer("readtoke"), code: http.StatusUnauthorized},
+		{name: "read can't control", method: "POST", path: "/control", auth: bearer("readtoken"), code: http.StatusForbidden},
+		{name: "control", method: "POST", path: "/control", auth: bearer("controltoken"), code: http.StatusOK, body: "bearer:hass"},
+		{name: "basic", method: "GET", path: "/dashboard", auth: basic("family", "secret"), code: http.StatusOK, body: "basic:family"},
+		{name: "wrong password", method: "GET", path: "/dashboard", auth: basic("family", "wrong"), code: http.StatusUnauthorized},
+		{name: "unknown user", method: "GET", path: "/dashboard", auth: basic("stranger", "secret"), code: http.StatusUnauthorized},
+		{name: "client cert", method: "POST", path: "/control", auth: func(r *http.Request) { r.TLS = cert("proxy") }, code: http.StatusOK, body: "client_cert:proxy"},
+		{name: "unknown client cert", method: "GET", path: "/local/wifi", auth: func(r *http.Request) { r.TLS = cert("laptop") }, code: http.StatusUnauthorized},
+		{name: "unverified client cert", method: "GET", path: "/local/wifi", auth: func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, code: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != nil {
+				tc.auth(r)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.code, w.Code)
+			if tc.code == http.StatusOK {
+				assert.Equal(t, tc.body, w.Body.String())
+			}
+
+			if tc.code == http.StatusUnauthorized {
+				assert.Equal(t, []string{`Bearer realm="reagled"`, `Basic realm="reagled", charset="UTF-8"`}, w.Header()["Www-Authenticate"])
+			}
+		})
+	}
+
+	require.Len(t, results, len(cases))
+	assert.Nil(t, results[0])
+	assert.Equal(t, ErrNoCredentials, results[1])
+	assert.Equal(t, ErrInvalidCredentials, results[4])
+	assert.Equal(t, ErrForbidden, results[5])
+}
+
+func TestUsersLimitBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	users := NewUsers([]User{{Name: "family", BcryptHash: string(hash), Scope: Read}})
+	users.MaxFailures = 2
+
+	request := func(address string, user string, password string) error {
+		r := httptest.NewRequest("GET", "/dashboard", nil)
+		r.RemoteAddr = address
+		r.SetBasicAuth(user, password)
+		_, err := users.Authenticate(r)
+		return err
+	}
+
+	require.NoError(t, request("10.0.0.1:1000", "family", "secret"))
+	assert.Len(t, users.verified, 1, "the verified password is remembered")
+	require.NoError(t, request("10.0.0.1:1001", "family", "secret"))
+
+	assert.Equal(t, ErrInvalidCredentials, request("10.0.0.2:1000", "family", "wrong"))
+	assert.Equal(t, ErrInvalidCredentials, request("10.0.0.2:1001", "stranger", "secret"))
+	assert.Equal(t, ErrTooManyFailures, request("10.0.0.2:1002", "family", "secret"), "any port of the address")
+	require.NoError(t, request("10.0.0.1:1002", "family", "secret"), "other addresses are not affected")
+
+	users.FailureWindow = 0
+	require.NoError(t, request("10.0.0.2:1003", "family", "secret"), "the window has passed")
+
+	users.VerifiedTTL = 0
+	assert.Equal(t, ErrInvalidCredentials, request("10.0.0.3:1000", "family", "wrong"), "a wrong password is never cached")
+}
+
+func bearer(token string) func(r *http.Request) {
+	return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+}
+
+func basic(user string, password string) func(r *http.Request) {
+	return func(r *http.Request) { r.SetBasicAuth(user, password) }
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type (
+	//Config is every credential that can use the api
+	Config struct {
+		Tokens      []Token      `json:"tokens,omitempty"`
+		Users       []User       `json:"users,omitempty"`
+		ClientCerts []ClientCert `json:"client_certs,omitempty"`
+	}
+
+	//Token is a static bearer token
+	Token struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+		Scope Scope  `json:"scope"`
+	}
+
+	//User authenticates with basic auth, the password is checked against a bcrypt hash such as htpasswd -nB makes
+	User struct {
+		Name       string `json:"name"`
+		BcryptHash string `json:"bcrypt_hash"`
+		Scope      Scope  `json:"scope"`
+	}
+
+	//ClientCert is a verified client certificate with this subject common name
+	ClientCert struct {
+		CommonName string `json:"common_name"`
+		Scope      Scope  `json:"scope"`
+	}
+)
+
+//Load reads and validates a json Config from the file
+func Load(path string) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Parse(b)
+}
+
+//Parse unmarshals and validates a json Config
+func Parse(b []byte) (Config, error) {
+	c := Config{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+
+	return c, c.validate()
+}
+
+//Methods are the methods there are credentials for, in the order they are tried
+func (c Config) Methods() []Method {
+	var methods []Method
+	if len(c.Tokens) > 0 {
+		methods = append(methods, Tokens(c.Tokens))
+	}
+
+	if len(c.Users) > 0 {
+		methods = append(methods, NewUsers(c.Users))
+	}
+
+	if len(c.ClientCerts) > 0 {
+		methods = append(methods, ClientCerts(c.ClientCerts))
+	}
+
+	return methods
+}
+
+//validate requires a credential, as otherwise nobody could use the api, and that they have a valid scope.  A token
+//or user without a scope is read only
+func (c *Config) validate() error {
+	if len(c.Tokens) == 0 && len(c.Users) == 0 && len(c.ClientCerts) == 0 {
+		return fmt.Errorf("no tokens, users or client_certs")
+	}
+
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for i := range c.Tokens {
+		t := &c.Tokens[i]
+		if t.Name == "" || t.Token == "" {
+			return fmt.Errorf("tokens need a name and token: %s", t.Name)
+		}
+
+		if names[t.Name] || tokens[t.Token] {
+			return fmt.Errorf("duplicate token: %s", t.Name)
+		}
+
+		names[t.Name] = true
+		tokens[t.Token] = true
+
+		if err := defaultScope(&t.Scope); err != nil {
+			return fmt.Errorf("token %s: %v", t.Name, err)
+		}
+	}
+
+	users := make(map[string]bool)
+	for i := range c.Users {
+		u := &c.Users[i]
+		if u.Name == "" {
+			return fmt.Errorf("users need a name")
+		}
+
+		if users[u.Name] {
+			return fmt.Errorf("duplicate user: %s", u.Name)
+		}
+
+		users[u.Name] = true
+
+		if _, err := bcrypt.Cost([]byte(u.BcryptHash)); err != nil {
+			return fmt.Errorf("user %s: invalid bcrypt_hash: %v", u.Name, err)
+		}
+
+		if err := defaultScope(&u.Scope); err != nil {
+			return fmt.Errorf("user %s: %v", u.Name, err)
+		}
+	}
+
+	certs := make(map[string]bool)
+	for i := range c.ClientCerts {
+		cert := &c.ClientCerts[i]
+		if cert.CommonName == "" {
+			return fmt.Errorf("client_certs need a common_name")
+		}
+
+		if certs[cert.CommonName] {
+			return fmt.Errorf("duplicate client_cert: %s", cert.CommonName)
+		}
+
+		certs[cert.CommonName] = true
+
+		if err := defaultScope(&cert.Scope); err != nil {
+			return fmt.Errorf("client_cert %s: %v", cert.CommonName, err)
+		}
+	}
+
+	return nil
+}
+
+func defaultScope(s *Scope) error {
+	switch *s {
+	case None:
+		*s = Read
+	case Read, Control:
+	default:
+		return fmt.Errorf("unknown scope %s", *s)
+	}
+
+	return nil
+}
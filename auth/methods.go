@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	//DefaultVerifiedTTL is how long Users remembers a verified password
+	DefaultVerifiedTTL = time.Minute
+	//DefaultMaxFailures is how many failed attempts Users allows an address in DefaultFailureWindow
+	DefaultMaxFailures = 10
+	//DefaultFailureWindow is how long an address' failed attempts are counted
+	DefaultFailureWindow = time.Minute
+
+	//maxTracked is how many verified passwords or failing addresses are kept before the expired ones are pruned
+	maxTracked = 1000
+)
+
+type (
+	//Tokens authenticates an Authorization: Bearer header
+	Tokens []Token
+
+	//Users authenticates basic auth.  bcrypt is slow on purpose, so to keep it from being a way to tie up reagled's cpu
+	//a verified password is remembered for VerifiedTTL, and an address with MaxFailures failed attempts is refused
+	//without checking until FailureWindow after the first of them
+	Users struct {
+		users []User
+
+		VerifiedTTL   time.Duration
+		MaxFailures   int
+		FailureWindow time.Duration
+
+		mu       sync.Mutex
+		verified map[[sha256.Size]byte]time.Time
+		failures map[string]*failures
+	}
+
+	failures struct {
+		since time.Time
+		count int
+	}
+
+	//ClientCerts authenticates the verified client certificate of a tls connection
+	ClientCerts []ClientCert
+)
+
+//Name implements Method
+func (t Tokens) Name() string { return "bearer" }
+
+//Challenge implements Method
+func (t Tokens) Challenge() string { return `Bearer realm="reagled"` }
+
+//Authenticate implements Method, every token is compared so the time taken does not say which was close
+func (t Tokens) Authenticate(r *http.Request) (Identity, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return Identity{}, ErrNoCredentials
+	}
+
+	presented := []byte(strings.TrimSpace(header[len("Bearer "):]))
+
+	id, err := Identity{}, ErrInvalidCredentials
+	for _, token := range t {
+		if subtle.ConstantTimeCompare([]byte(token.Token), presented) == 1 {
+			id, err = Identity{Name: token.Name, Method: t.Name(), Scope: token.Scope}, nil
+		}
+	}
+
+	return id, err
+}
+
+//NewUsers creates Users for the users with the default limits
+func NewUsers(users []User) *Users {
+	return &Users{
+		users:         users,
+		VerifiedTTL:   DefaultVerifiedTTL,
+		MaxFailures:   DefaultMaxFailures,
+		FailureWindow: DefaultFailureWindow,
+		verified:      make(map[[sha256.Size]byte]time.Time),
+		failures:      make(map[string]*failures),
+	}
+}
+
+//Name implements Method
+func (u *Users) Name() string { return "basic" }
+
+//Challenge implements Method
+func (u *Users) Challenge() string { return `Basic realm="reagled", charset="UTF-8"` }
+
+//Authenticate implements Method, unknown users are checked against a made up hash so they take as long as known ones
+func (u *Users) Authenticate(r *http.Request) (Identity, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return Identity{}, ErrNoCredentials
+	}
+
+	now := time.Now()
+	address := remoteHost(r)
+	if u.throttled(address, now) {
+		return Identity{}, ErrTooManyFailures
+	}
+
+	for _, user := range u.users {
+		if user.Name != name {
+			continue
+		}
+
+		key := sha256.Sum256([]byte(user.Name + "\x00" + user.BcryptHash + "\x00" + password))
+		if !u.cached(key, now) {
+			if bcrypt.CompareHashAndPassword([]byte(user.BcryptHash), []byte(password)) != nil {
+				u.failed(address, now)
+				return Identity{}, ErrInvalidCredentials
+			}
+
+			u.remember(key, address, now)
+		}
+
+		return Identity{Name: user.Name, Method: u.Name(), Scope: user.Scope}, nil
+	}
+
+	bcrypt.CompareHashAndPassword(unknownUser(), []byte(password))
+	u.failed(address, now)
+	return Identity{}, ErrInvalidCredentials
+}
+
+//throttled is true if the address has used up its failed attempts
+func (u *Users) throttled(address string, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	f, ok := u.failures[address]
+	if !ok {
+		return false
+	}
+
+	if now.Sub(f.since) >= u.FailureWindow {
+		delete(u.failures, address)
+		return false
+	}
+
+	return u.MaxFailures > 0 && f.count >= u.MaxFailures
+}
+
+func (u *Users) failed(address string, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.failures) >= maxTracked {
+		for a, f := range u.failures {
+			if now.Sub(f.since) >= u.FailureWindow {
+				delete(u.failures, a)
+			}
+		}
+	}
+
+	f, ok := u.failures[address]
+	if !ok {
+		f = &failures{since: now}
+		u.failures[address] = f
+	}
+
+	f.count++
+}
+
+//cached is true if the password was verified less than VerifiedTTL ago
+func (u *Users) cached(key [sha256.Size]byte, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	verified, ok := u.verified[key]
+	return ok && now.Sub(verified) < u.VerifiedTTL
+}
+
+//remember caches a verified password and forgets the address' failed attempts
+func (u *Users) remember(key [sha256.Size]byte, address string, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.failures, address)
+	if u.VerifiedTTL <= 0 {
+		return
+	}
+
+	if len(u.verified) >= maxTracked {
+		for k, verified := range u.verified {
+			if now.Sub(verified) >= u.VerifiedTTL {
+				delete(u.verified, k)
+			}
+		}
+	}
+
+	u.verified[key] = now
+}
+
+//remoteHost is the address without the port, so every connection from a client counts together
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+var (
+	unknownUserOnce sync.Once
+	unknownUserHash []byte
+)
+
+func unknownUser() []byte {
+	unknownUserOnce.Do(func() {
+		unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+	})
+
+	return unknownUserHash
+}
+
+//Name implements Method
+func (c ClientCerts) Name() string { return "client_cert" }
+
+//Challenge implements Method, there is no header asking for a certificate
+func (c ClientCerts) Challenge() string { return "" }
+
+//Authenticate implements Method, only certificates the tls server verified count
+func (c ClientCerts) Authenticate(r *http.Request) (Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, ErrNoCredentials
+	}
+
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, cert := range c {
+		if cert.CommonName == name {
+			return Identity{Name: name, Method: c.Name(), Scope: cert.Scope}, nil
+		}
+	}
+
+	return Identity{}, ErrInvalidCredentials
+}
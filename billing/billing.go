@@ -0,0 +1,276 @@
+/*
+Package billing summarizes the stored reading history by utility billing cycle.
+
+A cycle starts at midnight on the cycle day of each month.  Reports cover the cycle so far, a projection to the end of
+the cycle, the same point in the previous cycle and the whole of the previous cycle.  Costs come from a tariff when one
+is configured, otherwise from the price the meter reported with each reading.
+*/
+package billing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+)
+
+const (
+	//TariffCost is the cost source when costs are calculated from a tariff
+	TariffCost = "tariff"
+	//MeterCost is the cost source when costs are calculated from the meter's price
+	MeterCost = "meter"
+
+	//DefaultDemandInterval is the demand interval most utilities bill on
+	DefaultDemandInterval = time.Minute * 15
+)
+
+type (
+	//Source is the reading history reports are built from, a *storage.Store
+	Source interface {
+		Query(r storage.Resolution, start time.Time, end time.Time) ([]storage.Aggregate, error)
+	}
+
+	//Usage is the energy and cost of [Start, End).  Cost includes DemandCost
+	Usage struct {
+		Start      time.Time `json:"start"`
+		End        time.Time `json:"end"`
+		Delivered  float64   `json:"delivered_kwh"`
+		Received   float64   `json:"received_kwh"`
+		Cost       float64   `json:"cost"`
+		DemandCost float64   `json:"demand_cost"`
+	}
+
+	//Projection is the expected usage of the whole cycle if the rest of it is like the cycle so far
+	Projection struct {
+		Delivered float64 `json:"delivered_kwh"`
+		Cost      float64 `json:"cost"`
+	}
+
+	//PeakDemand is the demand interval of the cycle with the highest average demand
+	PeakDemand struct {
+		Start  time.Time `json:"start"`
+		End    time.Time `json:"end"`
+		Demand float64   `json:"demand_kw"`
+	}
+
+	//Report is the billing cycle summary as of a point in time
+	Report struct {
+		AsOf       time.Time `json:"as_of"`
+		CycleDay   int       `json:"cycle_day"`
+		CycleStart time.Time `json:"cycle_start"`
+		CycleEnd   time.Time `json:"cycle_end"`
+		Currency   string    `json:"currency"`
+		CostSource string    `json:"cost_source"`
+
+		SoFar               Usage       `json:"so_far"`
+		Projected           Projection  `json:"projected"`
+		SamePeriodLastCycle Usage       `json:"same_period_last_cycle"`
+		LastCycle           Usage       `json:"last_cycle"`
+		PeakDemand          *PeakDemand `json:"peak_demand,omitempty"`
+	}
+
+	//Tracker builds Reports from a Source
+	Tracker struct {
+		source   Source
+		cycleDay int
+		loc      *time.Location
+		tariff   *tariff.Tariff
+
+		//DemandInterval is the length of the intervals the peak demand is averaged over
+		DemandInterval time.Duration
+	}
+)
+
+//NewTracker creates a Tracker for cycles starting on cycleDay (1-28) in loc.  t may be nil to use the meter's price.
+func NewTracker(source Source, cycleDay int, loc *time.Location, t *tariff.Tariff) (*Tracker, error) {
+	if cycleDay < 1 || cycleDay > 28 {
+		return nil, fmt.Errorf("cycle day must be between 1 and 28: %v", cycleDay)
+	}
+
+	if t != nil {
+		//the tariff's own billing day would reset its demand and tier totals mid cycle
+		copied := *t
+		copied.BillingDay = cycleDay
+		t = &copied
+	}
+
+	return &Tracker{source: source, cycleDay: cycleDay, loc: loc, tariff: t, DemandInterval: DefaultDemandInterval}, nil
+}
+
+//CycleStart returns the start of the cycle ts is in
+func (t *Tracker) CycleStart(ts time.Time) time.Time {
+	return tariff.BillingPeriodStart(ts, t.cycleDay, t.loc)
+}
+
+//Report summarizes the cycle asOf is in
+func (t *Tracker) Report(asOf time.Time) (Report, error) {
+	start := t.CycleStart(asOf)
+	end := start.AddDate(0, 1, 0)
+	lastStart := start.AddDate(0, -1, 0)
+
+	report := Report{
+		AsOf:       asOf,
+		CycleDay:   t.cycleDay,
+		CycleStart: start,
+		CycleEnd:   end,
+		CostSource: MeterCost,
+	}
+
+	if t.tariff != nil {
+		report.CostSource = TariffCost
+		report.Currency = t.tariff.Currency
+	}
+
+	hours, err := t.source.Query(storage.Hour, lastStart, asOf)
+	if err != nil {
+		return report, err
+	}
+
+	var current []storage.Aggregate
+	var samePeriod []storage.Aggregate
+	var last []storage.Aggregate
+
+	//the same period last cycle is as far into the cycle as asOf, but no longer than the last cycle
+	samePeriodEnd := lastStart.Add(asOf.Sub(start))
+	if samePeriodEnd.After(start) {
+		samePeriodEnd = start
+	}
+
+	for _, h := range hours {
+		if report.Currency == "" {
+			report.Currency = h.Currency
+		}
+
+		if !h.Start.Before(start) {
+			current = append(current, h)
+			continue
+		}
+
+		last = append(last, h)
+		if h.Start.Before(samePeriodEnd) {
+			samePeriod = append(samePeriod, h)
+		}
+	}
+
+	report.SoFar, err = t.usage(start, asOf, current)
+	if err != nil {
+		return report, err
+	}
+
+	report.SamePeriodLastCycle, err = t.usage(lastStart, samePeriodEnd, samePeriod)
+	if err != nil {
+		return report, err
+	}
+
+	report.LastCycle, err = t.usage(lastStart, start, last)
+	if err != nil {
+		return report, err
+	}
+
+	report.Projected = t.project(report.SoFar, start, end)
+
+	report.PeakDemand, err = t.peakDemand(start, asOf)
+	return report, err
+}
+
+func (t *Tracker) usage(start time.Time, end time.Time, hours []storage.Aggregate) (Usage, error) {
+	usage := Usage{Start: start, End: end}
+	for _, h := range hours {
+		usage.Delivered += h.DeliveredDelta
+		usage.Received += h.ReceivedDelta
+	}
+
+	err := t.cost(&usage, hours)
+	return usage, err
+}
+
+//cost fills in the energy cost of the hours, and the demand cost if the tariff has a demand charge.  The demand is
+//charged on the same interval averages as PeakDemand, not the instantaneous maximum the meter reported
+func (t *Tracker) cost(usage *Usage, hours []storage.Aggregate) error {
+	if t.tariff == nil {
+		for _, h := range hours {
+			usage.Cost += h.DeliveredDelta * h.Price
+		}
+
+		return nil
+	}
+
+	calc := tariff.NewCalculator(*t.tariff)
+	for _, h := range hours {
+		calc.AddEnergy(h.Start, h.DeliveredDelta, 0)
+	}
+
+	intervals, _, err := t.demandIntervals(usage.Start, usage.End)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range intervals {
+		calc.AddPeakDemand(a.Start, a.DemandAvg())
+	}
+
+	totals := calc.Totals()
+	usage.Cost = totals.PeriodCost()
+	usage.DemandCost = totals.PeriodDemandCost
+	return nil
+}
+
+//project scales the energy so far to the length of the cycle.  The demand charge is already for the peak so far and is
+//not scaled.
+func (t *Tracker) project(soFar Usage, start time.Time, end time.Time) Projection {
+	elapsed := soFar.End.Sub(start)
+	if elapsed <= 0 {
+		return Projection{}
+	}
+
+	scale := float64(end.Sub(start)) / float64(elapsed)
+	energyCost := soFar.Cost - soFar.DemandCost
+	return Projection{Delivered: soFar.Delivered * scale, Cost: energyCost*scale + soFar.DemandCost}
+}
+
+//peakDemand is the demand interval with the highest average demand
+func (t *Tracker) peakDemand(start time.Time, end time.Time) (*PeakDemand, error) {
+	intervals, interval, err := t.demandIntervals(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var peak *PeakDemand
+	for _, a := range intervals {
+		if peak != nil && a.DemandAvg() <= peak.Demand {
+			continue
+		}
+
+		peak = &PeakDemand{Start: a.Start, End: a.Start.Add(interval), Demand: a.DemandAvg()}
+	}
+
+	return peak, nil
+}
+
+//demandIntervals returns the demand intervals of [start, end) that have readings and how long they are.  Minute buckets
+//are used when they have been retained, otherwise hours
+func (t *Tracker) demandIntervals(start time.Time, end time.Time) ([]storage.Aggregate, time.Duration, error) {
+	interval := t.DemandInterval
+	aggregates, err := t.source.Query(storage.Minute, start, end)
+	if err != nil {
+		return nil, interval, err
+	}
+
+	if len(aggregates) == 0 {
+		interval = time.Hour
+		aggregates, err = t.source.Query(storage.Hour, start, end)
+		if err != nil {
+			return nil, interval, err
+		}
+	}
+
+	var intervals []storage.Aggregate
+	for _, a := range storage.Rebucket(aggregates, storage.Step(interval)) {
+		if a.Count > 0 {
+			intervals = append(intervals, a)
+		}
+	}
+
+	return intervals, interval, nil
+}
@@ -0,0 +1,163 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource map[storage.Resolution][]storage.Aggregate
+
+func (f fakeSource) Query(r storage.Resolution, start time.Time, end time.Time) ([]storage.Aggregate, error) {
+	var results []storage.Aggregate
+	for _, a := range f[r] {
+		if !a.Start.Before(start) && a.Start.Before(end) {
+			results = append(results, a)
+		}
+	}
+
+	return results, nil
+}
+
+func aggregate(start time.Time, delivered float64, demand float64) storage.Aggregate {
+	return storage.Aggregate{
+		Start:          start,
+		Count:          1,
+		DemandMin:      demand,
+		DemandMax:      demand,
+		DemandSum:      demand,
+		DeliveredDelta: delivered,
+		Price:          0.1,
+		Currency:       "USD",
+	}
+}
+
+//hours of 2 kWh at 1 kW starting at start
+func hours(start time.Time, count int) []storage.Aggregate {
+	var results []storage.Aggregate
+	for i := 0; i < count; i++ {
+		results = append(results, aggregate(start.Add(time.Hour*time.Duration(i)), 2, 1))
+	}
+
+	return results
+}
+
+func testSource() fakeSource {
+	lastCycle := time.Date(2018, 10, 15, 0, 0, 0, 0, time.UTC)
+	cycle := time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC)
+	peak := time.Date(2018, 11, 16, 12, 0, 0, 0, time.UTC)
+
+	source := fakeSource{}
+	source[storage.Hour] = append(source[storage.Hour], hours(lastCycle, 10)...)
+	//after the same point in the last cycle
+	source[storage.Hour] = append(source[storage.Hour], aggregate(lastCycle.AddDate(0, 0, 5), 10, 4))
+	source[storage.Hour] = append(source[storage.Hour], hours(cycle, 10)...)
+
+	for i := 0; i < 20; i++ {
+		demand := 1.0
+		if i >= 15 {
+			demand = 6
+		}
+
+		source[storage.Minute] = append(source[storage.Minute], aggregate(peak.Add(time.Minute*time.Duration(i)), 0, demand))
+	}
+
+	return source
+}
+
+func TestReport(t *testing.T) {
+	tracker, err := NewTracker(testSource(), 15, time.UTC, nil)
+	require.NoError(t, err)
+
+	asOf := time.Date(2018, 11, 20, 0, 0, 0, 0, time.UTC)
+	report, err := tracker.Report(asOf)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), report.CycleStart)
+	assert.Equal(t, time.Date(2018, 12, 15, 0, 0, 0, 0, time.UTC), report.CycleEnd)
+	assert.Equal(t, MeterCost, report.CostSource)
+	assert.Equal(t, "USD", report.Currency)
+
+	assert.InDelta(t, 20.0, report.SoFar.Delivered, 0.0001)
+	assert.InDelta(t, 2.0, report.SoFar.Cost, 0.0001)
+
+	//5 days into a 30 day cycle
+	assert.InDelta(t, 120.0, report.Projected.Delivered, 0.0001)
+	assert.InDelta(t, 12.0, report.Projected.Cost, 0.0001)
+
+	assert.Equal(t, asOf.AddDate(0, -1, 0), report.SamePeriodLastCycle.End)
+	assert.InDelta(t, 20.0, report.SamePeriodLastCycle.Delivered, 0.0001)
+	assert.InDelta(t, 30.0, report.LastCycle.Delivered, 0.0001)
+
+	require.NotNil(t, report.PeakDemand)
+	assert.Equal(t, time.Date(2018, 11, 16, 12, 15, 0, 0, time.UTC), report.PeakDemand.Start)
+	assert.Equal(t, 6.0, report.PeakDemand.Demand)
+
+	t.Run("markdown", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteMarkdown(buf, report))
+		assert.Contains(t, buf.String(), "| So far | Nov 15 - Nov 19 | 20.00 | 0.00 | 2.00 |")
+		assert.Contains(t, buf.String(), "Peak demand was 6.000 kW")
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteCSV(buf, report))
+
+		rows, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 6)
+		assert.Equal(t, []string{"projected", "2018-11-15T00:00:00Z", "2018-12-15T00:00:00Z", "120", "", "12", "USD"}, rows[2])
+	})
+}
+
+func TestReportTariff(t *testing.T) {
+	rates, err := tariff.Parse([]byte(`{
+		"currency": "CAD",
+		"timezone": "UTC",
+		"billing_day": 1,
+		"seasons": [{"energy": {"type": "flat", "rate": 0.2}, "demand": {"rate_per_kw": 10}}]
+	}`))
+	require.NoError(t, err)
+
+	tracker, err := NewTracker(testSource(), 15, time.UTC, &rates)
+	require.NoError(t, err)
+
+	report, err := tracker.Report(time.Date(2018, 11, 20, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, TariffCost, report.CostSource)
+	assert.Equal(t, "CAD", report.Currency)
+	assert.InDelta(t, 4.0+60, report.SoFar.Cost, 0.0001)
+	assert.InDelta(t, 60.0, report.SoFar.DemandCost, 0.0001, "charged on the peak demand interval")
+	assert.InDelta(t, 24.0+60, report.Projected.Cost, 0.0001, "demand charge is not projected")
+	assert.InDelta(t, 6.0+40, report.LastCycle.Cost, 0.0001)
+
+	t.Run("spike", func(t *testing.T) {
+		//a brief spike raises the maximum but not the interval average the utility bills on
+		source := testSource()
+		spike := aggregate(time.Date(2018, 11, 17, 9, 0, 0, 0, time.UTC), 0, 1)
+		spike.DemandMax = 50
+		source[storage.Minute] = append(source[storage.Minute], spike)
+		source[storage.Hour][len(source[storage.Hour])-1].DemandMax = 50
+
+		tracker, err := NewTracker(source, 15, time.UTC, &rates)
+		require.NoError(t, err)
+
+		report, err := tracker.Report(time.Date(2018, 11, 20, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.InDelta(t, 60.0, report.SoFar.DemandCost, 0.0001)
+		assert.Equal(t, report.PeakDemand.Demand*10, report.SoFar.DemandCost, "the demand cost is for the reported peak")
+	})
+}
+
+func TestNewTrackerCycleDay(t *testing.T) {
+	_, err := NewTracker(testSource(), 29, time.UTC, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package billing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+const dateFormat = "Jan 2 2006"
+
+//WriteMarkdown writes the report as a markdown summary for people
+func WriteMarkdown(w io.Writer, r Report) error {
+	lines := []string{
+		fmt.Sprintf("# Billing cycle %s - %s", r.CycleStart.Format(dateFormat), r.CycleEnd.Format(dateFormat)),
+		"",
+		fmt.Sprintf("As of %s, costs from the %s.", r.AsOf.Format(time.RFC1123), costSourceName(r.CostSource)),
+		"",
+		fmt.Sprintf("| | Period | Delivered (kWh) | Received (kWh) | Cost (%s) |", r.Currency),
+		"|---|---|---:|---:|---:|",
+		usageRow("So far", r.SoFar),
+		fmt.Sprintf("| Projected | %s | %.2f | | %.2f |", period(r.CycleStart, r.CycleEnd), r.Projected.Delivered, r.Projected.Cost),
+		usageRow("Same period last cycle", r.SamePeriodLastCycle),
+		usageRow("Last cycle", r.LastCycle),
+		"",
+	}
+
+	if r.PeakDemand != nil {
+		lines = append(lines, fmt.Sprintf("Peak demand was %.3f kW from %s to %s.", r.PeakDemand.Demand,
+			r.PeakDemand.Start.Format("Jan 2 15:04"), r.PeakDemand.End.Format("15:04 MST")), "")
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func usageRow(name string, u Usage) string {
+	return fmt.Sprintf("| %s | %s | %.2f | %.2f | %.2f |", name, period(u.Start, u.End), u.Delivered, u.Received, u.Cost)
+}
+
+//period formats [start, end) as the inclusive dates
+func period(start time.Time, end time.Time) string {
+	last := end.Add(-time.Nanosecond)
+	if last.Before(start) {
+		last = start
+	}
+
+	return fmt.Sprintf("%s - %s", start.Format("Jan 2"), last.Format("Jan 2"))
+}
+
+func costSourceName(source string) string {
+	if source == TariffCost {
+		return "configured tariff"
+	}
+
+	return "meter's price"
+}
+
+//WriteCSV writes a row per period of the report, for spreadsheets
+func WriteCSV(w io.Writer, r Report) error {
+	rows := [][]string{
+		{"period", "start", "end", "delivered_kwh", "received_kwh", "cost", "currency"},
+		csvRow("so_far", r.SoFar, r.Currency),
+		{"projected", r.CycleStart.Format(time.RFC3339), r.CycleEnd.Format(time.RFC3339), formatFloat(r.Projected.Delivered, 3), "", formatFloat(r.Projected.Cost, 2), r.Currency},
+		csvRow("same_period_last_cycle", r.SamePeriodLastCycle, r.Currency),
+		csvRow("last_cycle", r.LastCycle, r.Currency),
+	}
+
+	if r.PeakDemand != nil {
+		rows = append(rows, []string{"peak_demand_kw", r.PeakDemand.Start.Format(time.RFC3339), r.PeakDemand.End.Format(time.RFC3339), formatFloat(r.PeakDemand.Demand, 3), "", "", ""})
+	}
+
+	writer := csv.NewWriter(w)
+	return writer.WriteAll(rows)
+}
+
+func csvRow(name string, u Usage, currency string) []string {
+	return []string{name, u.Start.Format(time.RFC3339), u.End.Format(time.RFC3339), formatFloat(u.Delivered, 3), formatFloat(u.Received, 3), formatFloat(u.Cost, 2), currency}
+}
+
+//formatFloat rounds away the noise of summing floats, costs to the cent and energy to the Wh
+func formatFloat(f float64, decimals int) string {
+	scale := math.Pow(10, float64(decimals))
+	return strconv.FormatFloat(math.Round(f*scale)/scale, 'f', -1, 64)
+}
@@ -2,12 +2,17 @@
 
 TEST_FLAG=$1
 
-docker build \
+#the integration tests read the password from a build secret so it does not end up in an image layer
+PASSWORD_FILE=$(mktemp)
+trap 'rm -f "$PASSWORD_FILE"' EXIT
+printf '%s' "$REAGLE_LOCAL_PASSWORD" > "$PASSWORD_FILE"
+
+DOCKER_BUILDKIT=1 docker build \
 	-t kklipsch/reagled \
 	--build-arg TEST_FLAG=$TEST_FLAG \
 	--build-arg REAGLE_LOCAL_LOCATION=$REAGLE_LOCAL_LOCATION \
 	--build-arg REAGLE_LOCAL_USER=$REAGLE_LOCAL_USER \
-	--build-arg REAGLE_LOCAL_PASSWORD=$REAGLE_LOCAL_PASSWORD \
+	--secret id=reagle_local_password,src="$PASSWORD_FILE" \
 	--build-arg REAGLE_IMPROVED_FIRMWARE=$REAGLE_IMPROVED_FIRMWARE \
 	--build-arg REAGLE_MODEL_ID_NAME=$REAGLE_MODEL_ID_NAME \
 	--build-arg REAGLE_DEBUG_REQUEST=$REAGLE_DEBUG_REQUEST \
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+)
+
+//Outcomes of a Cache Post
+const (
+	//CacheHit is a response that was cached or shared with a request already being forwarded
+	CacheHit = "hit"
+	//CacheMiss is a response the command was forwarded to the Eagle for
+	CacheMiss = "miss"
+	//CacheStale is an expired response used because forwarding failed
+	CacheStale = "stale"
+)
+
+type (
+	//Cache answers proxied commands from the Eagle's recent responses, so any number of clients asking the same thing
+	//cost the Eagle one request per TTL.  Commands are forwarded through the Local so they are rate limited along with
+	//everything else reagled asks.
+	Cache struct {
+		ctx context.Context
+		l   Local
+		ttl time.Duration
+
+		mu       sync.Mutex
+		entries  map[string]cacheEntry
+		inflight map[string]*cacheCall
+
+		//MaxStale is how long after it expires a response is still used when the Eagle can not be asked
+		MaxStale time.Duration
+
+		//Retry is how long to wait before forwarding again when rate limited, Timeout is how long to keep trying
+		Retry   time.Duration
+		Timeout time.Duration
+
+		now func() time.Time
+	}
+
+	cacheEntry struct {
+		body []byte
+		at   time.Time
+	}
+
+	//cacheCall is a command being forwarded, every request for it waits on done
+	cacheCall struct {
+		done chan struct{}
+		body []byte
+		err  error
+	}
+)
+
+//NewCache creates a Cache, forwarded commands are cancelled when the context is done
+func NewCache(ctx context.Context, l Local, ttl time.Duration) *Cache {
+	return &Cache{
+		ctx:      ctx,
+		l:        l,
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry),
+		inflight: make(map[string]*cacheCall),
+		MaxStale: time.Minute * 5,
+		Retry:    time.Second,
+		Timeout:  time.Second * 5,
+		now:      time.Now,
+	}
+}
+
+//Post returns the Eagle's response to the command and where it came from
+func (c *Cache) Post(ctx context.Context, command local.ProxiedCommand) ([]byte, string, error) {
+	key := command.Key()
+
+	c.mu.Lock()
+	entry, cached := c.entries[key]
+	age := c.now().Sub(entry.at)
+	if cached && age < c.ttl {
+		c.mu.Unlock()
+		return entry.body, CacheHit, nil
+	}
+
+	call, shared := c.inflight[key]
+	if !shared {
+		call = &cacheCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		go c.forward(key, command, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+
+	if call.err != nil {
+		if cached && age < c.ttl+c.MaxStale {
+			return entry.body, CacheStale, nil
+		}
+
+		return nil, "", call.err
+	}
+
+	if shared {
+		return call.body, CacheHit, nil
+	}
+
+	return call.body, CacheMiss, nil
+}
+
+//forward is not tied to any one request's context, the others waiting on it should not fail because one gave up
+func (c *Cache) forward(key string, command local.ProxiedCommand, call *cacheCall) {
+	call.body, call.err = c.request(command)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.entries[key] = cacheEntry{body: call.body, at: c.now()}
+		c.prune()
+	}
+
+	close(call.done)
+}
+
+func (c *Cache) request(command local.ProxiedCommand) ([]byte, error) {
+	timeout, clean := context.WithTimeout(c.ctx, c.Timeout)
+	defer clean()
+
+	for {
+		result, err := c.l.Request(timeout, RequestPost(command))
+		if err == nil {
+			return result.([]byte), nil
+		}
+
+		if !errors.Is(err, ErrRateLimited) {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(c.Retry):
+		case <-timeout.Done():
+			return nil, err
+		}
+	}
+}
+
+//prune drops responses too old to be used even as stale, must be called with the lock held
+func (c *Cache) prune() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.at) >= c.ttl+c.MaxStale {
+			delete(c.entries, key)
+		}
+	}
+}
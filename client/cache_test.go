@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//fakeEagle answers post requests with the next of its responses, counting them
+type fakeEagle struct {
+	responses chan interface{}
+	calls     chan local.ProxiedCommand
+}
+
+func newFakeEagle(ctx context.Context) (Local, *fakeEagle) {
+	requests := make(chan Request)
+	fake := &fakeEagle{responses: make(chan interface{}, 10), calls: make(chan local.ProxiedCommand, 10)}
+
+	go func() {
+		for {
+			select {
+			case req := <-requests:
+				fake.calls <- req.payload.(local.ProxiedCommand)
+				req.resultsPromise <- <-fake.responses
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return Local(requests), fake
+}
+
+func TestCache(t *testing.T) {
+	ctx, clean := context.WithTimeout(context.Background(), time.Second*5)
+	defer clean()
+
+	l, fake := newFakeEagle(ctx)
+	cache := NewCache(ctx, l, time.Minute)
+	cache.Retry = time.Millisecond
+
+	now := time.Date(2018, 10, 20, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	list := local.ProxiedCommand{Name: "device_list", Body: []byte("<Command><Name>device_list</Name></Command>")}
+
+	fake.responses <- []byte("<DeviceList/>")
+	body, outcome, err := cache.Post(ctx, list)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, outcome)
+	assert.Equal(t, "<DeviceList/>", string(body))
+
+	body, outcome, err = cache.Post(ctx, list)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, outcome)
+	assert.Equal(t, "<DeviceList/>", string(body))
+	assert.Len(t, fake.calls, 1)
+
+	//expired, and the eagle is rate limited once before answering
+	now = now.Add(time.Minute)
+	fake.responses <- ErrRateLimited
+	fake.responses <- []byte("<DeviceList></DeviceList>")
+	body, outcome, err = cache.Post(ctx, list)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, outcome)
+	assert.Equal(t, "<DeviceList></DeviceList>", string(body))
+	assert.Len(t, fake.calls, 3)
+
+	//expired and the eagle is down, so the old response is better than nothing
+	now = now.Add(time.Minute * 2)
+	fake.responses <- fmt.Errorf("eagle is down")
+	body, outcome, err = cache.Post(ctx, list)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStale, outcome)
+	assert.Equal(t, "<DeviceList></DeviceList>", string(body))
+
+	//until it is too old
+	now = now.Add(cache.MaxStale)
+	fake.responses <- fmt.Errorf("eagle is down")
+	_, _, err = cache.Post(ctx, list)
+	assert.Error(t, err)
+
+	//a different command is not answered with the device list
+	fake.responses <- fmt.Errorf("eagle is down")
+	_, _, err = cache.Post(ctx, local.ProxiedCommand{Name: "wifi_status"})
+	assert.Error(t, err)
+}
+
+func TestCacheShares(t *testing.T) {
+	ctx, clean := context.WithTimeout(context.Background(), time.Second*5)
+	defer clean()
+
+	l, fake := newFakeEagle(ctx)
+	cache := NewCache(ctx, l, time.Minute)
+
+	status := local.ProxiedCommand{Name: "wifi_status"}
+
+	type result struct {
+		outcome string
+		err     error
+	}
+
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, outcome, err := cache.Post(ctx, status)
+			results <- result{outcome, err}
+		}()
+	}
+
+	//whether the second arrives while the first is being forwarded or after, it is not forwarded again
+	<-fake.calls
+	time.Sleep(time.Millisecond * 10)
+	fake.responses <- []byte("<WiFiStatus/>")
+
+	outcomes := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		r := <-results
+		require.NoError(t, r.err)
+		outcomes[r.outcome]++
+	}
+
+	assert.Len(t, fake.calls, 0, "only one command is forwarded")
+	assert.Equal(t, 1, outcomes[CacheMiss])
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kklipsch/reagle/local"
+)
+
+//ErrorKind extends local.ErrorKind with the errors the client itself produces
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "context"
+	default:
+		return local.ErrorKind(err)
+	}
+}
+
+//ErrorKinds are all of the values ErrorKind can return for a non nil error
+var ErrorKinds = append([]string{"rate_limited", "context"}, local.ErrorKinds...)
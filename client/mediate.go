@@ -60,7 +60,7 @@ func (m *mediator) query(ctx context.Context, typ requestType, payload interface
 	switch typ {
 	case localSpecificVariable:
 		variable := payload.(string)
-		return m.api.DeviceQuery(ctx, address, variable)
+		return readings(m.api.DeviceQuery(ctx, address, variable))
 	case localAllVariables:
 		details, err := m.api.DeviceDetails(ctx, address)
 		if err != nil {
@@ -69,10 +69,10 @@ func (m *mediator) query(ctx context.Context, typ requestType, payload interface
 
 		variables := local.VariablesFromDetailsResponse(details)
 		if len(variables) < 1 {
-			return nil, fmt.Errorf("no variables defined")
+			return nil, &local.UnsupportedVariableError{Reason: "no variables defined"}
 		}
 
-		return m.api.DeviceQuery(ctx, address, variables...)
+		return readings(m.api.DeviceQuery(ctx, address, variables...))
 	case localMeterDetails:
 		return m.api.DeviceDetails(ctx, address)
 	case localBaseMetrics:
@@ -81,6 +81,9 @@ func (m *mediator) query(ctx context.Context, typ requestType, payload interface
 		return m.api.DeviceList(ctx)
 	case localWifiStatus:
 		return m.api.WifiStatus(ctx)
+	case localPost:
+		_, body, err := local.PostCommandBody(ctx, m.api.Client, m.api.Config, payload.(local.ProxiedCommand).Body)
+		return body, err
 	}
 
 	panic(fmt.Sprintf("unknown request type: %v", typ))
@@ -88,10 +91,18 @@ func (m *mediator) query(ctx context.Context, typ requestType, payload interface
 
 func (m *mediator) getAddress(ctx context.Context, typ requestType) (string, error) {
 	switch typ {
-	case localWifiStatus, localDeviceList:
+	case localWifiStatus, localDeviceList, localPost:
 		//these query types do not require an address so don't even bothe trying to get it
 		return "", nil
 	default:
 		return m.address(ctx)
 	}
 }
+
+func readings(response local.DeviceQueryResponse, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return local.ReadingsFromDetailsResponse(response)
+}
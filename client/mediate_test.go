@@ -13,6 +13,8 @@ import (
 func TestMediateQuery(t *testing.T) {
 	for _, tc := range []mediateTest{
 		wifiStatusCheck(),
+		baseMetricsCheck(),
+		postCheck(),
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx, clean := context.WithTimeout(context.Background(), time.Second)
@@ -44,13 +46,56 @@ func wifiStatusCheck() mediateTest {
 	return mediateTest{
 		name:       "wifi_status",
 		typ:        localWifiStatus,
-		testServer: local.ServeWifiStatus(local.WifiStatus{Enabled: "enabled", SSID: "ssid"}),
+		testServer: local.ServeWifiStatus(local.WifiStatus{Enabled: true, SSID: "ssid", Channel: 11}),
 		check: func(t *testing.T, result interface{}) {
 			status, ok := result.(local.WifiStatus)
 			require.True(t, ok)
 
-			assert.Equal(t, "enabled", status.Enabled)
+			assert.True(t, bool(status.Enabled))
 			assert.Equal(t, "ssid", status.SSID)
+			assert.Equal(t, 11, status.Channel)
+		},
+	}
+}
+
+func baseMetricsCheck() mediateTest {
+	return mediateTest{
+		name: "base_metrics",
+		typ:  localBaseMetrics,
+		testServer: local.TestServerPayload{
+			DeviceList: []local.Device{{DeviceData: local.DeviceData{HardwareAddress: "0x01", ModelID: "electric_meter"}}},
+			DeviceQuery: &local.DeviceQueryResponse{
+				Components: local.NewComponents(local.Component{
+					Name: "Main",
+					Variables: local.NewVariables(
+						local.Variable{Name: "zigbee:InstantaneousDemand", Value: "1.250"},
+						local.Variable{Name: "zigbee:CurrentSummationDelivered", Value: "100.5"},
+						local.Variable{Name: "zigbee:Price", Value: "undefined"},
+						local.Variable{Name: "zigbee:PriceCurrency", Value: "840"},
+					),
+				}),
+			},
+		},
+		check: func(t *testing.T, result interface{}) {
+			metrics, ok := result.(BaseMetrics)
+			require.True(t, ok)
+
+			assert.Equal(t, BaseMetrics{Demand: 1.25, Delivered: 100.5, Currency: "USD", Undefined: []string{"zigbee:Price"}}, metrics)
+		},
+	}
+}
+
+func postCheck() mediateTest {
+	return mediateTest{
+		name:       "post",
+		typ:        localPost,
+		payload:    local.ProxiedCommand{Name: "wifi_status", Body: []byte("<Command><Name>wifi_status</Name></Command>")},
+		testServer: local.ServeWifiStatus(local.WifiStatus{SSID: "ssid"}),
+		check: func(t *testing.T, result interface{}) {
+			body, ok := result.([]byte)
+			require.True(t, ok)
+
+			assert.Contains(t, string(body), "<SSID>ssid</SSID>", "the eagle's response is passed back as is")
 		},
 	}
 }
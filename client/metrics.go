@@ -3,7 +3,6 @@ package client
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"github.com/kklipsch/reagle/local"
 )
@@ -12,28 +11,54 @@ import (
 type BaseMetrics struct {
 	Demand    float64 `json:"demand"`
 	Delivered float64 `json:"delivered"`
+	Received  float64 `json:"received"`
 	Price     float64 `json:"price"`
 	Currency  string  `json:"currency"`
+
+	//Undefined are the variables the meter had not reported a value for, they are 0 above
+	Undefined []string `json:"undefined,omitempty"`
+}
+
+//SummationUndefined returns true if delivered or received is 0 because the meter had not reported it rather than because it
+//is 0.  Anything treating the summations as counters would see that 0 as the meter being reset
+func (m BaseMetrics) SummationUndefined() bool {
+	for _, name := range m.Undefined {
+		if name == "zigbee:CurrentSummationDelivered" || name == "zigbee:CurrentSummationReceived" {
+			return true
+		}
+	}
+
+	return false
 }
 
 func getBaseMetrics(ctx context.Context, localAPI local.API, hardwareAddress string) (BaseMetrics, error) {
 	values := BaseMetrics{}
 
-	response, err := localAPI.DeviceQuery(ctx, hardwareAddress, "zigbee:InstantaneousDemand", "zigbee:CurrentSummationDelivered", "zigbee:Price", "zigbee:PriceCurrency")
+	response, err := localAPI.DeviceQuery(ctx, hardwareAddress, "zigbee:InstantaneousDemand", "zigbee:CurrentSummationDelivered", "zigbee:CurrentSummationReceived", "zigbee:Price", "zigbee:PriceCurrency")
+	if err != nil {
+		return values, fmt.Errorf("call to api failed: %w", err)
+	}
+
+	readings, err := local.ReadingsFromDetailsResponse(response)
 	if err != nil {
-		return values, fmt.Errorf("call to api failed: %v", err)
+		return values, err
 	}
 
-	variables := local.ResultsFromDetailsResponse(response)
-	if len(variables) != 1 {
-		return values, fmt.Errorf("variables has more components than expected: %v", variables)
+	if len(readings) != 1 {
+		return values, fmt.Errorf("variables has more components than expected: %v", readings)
 	}
 
-	var component map[string]local.Variable
-	for _, component = range variables {
+	var component local.Readings
+	for _, component = range readings {
 		break
 	}
 
+	for _, name := range []string{"zigbee:InstantaneousDemand", "zigbee:CurrentSummationDelivered", "zigbee:CurrentSummationReceived", "zigbee:Price"} {
+		if reading, ok := component[name]; ok && !reading.Defined() {
+			values.Undefined = append(values.Undefined, name)
+		}
+	}
+
 	values.Demand, err = getValueFloat("zigbee:InstantaneousDemand", component)
 	if err != nil {
 		return values, err
@@ -44,6 +69,14 @@ func getBaseMetrics(ctx context.Context, localAPI local.API, hardwareAddress str
 		return values, err
 	}
 
+	//not every meter reports received, those that don't have no solar/generation so 0 is correct
+	if _, ok := component["zigbee:CurrentSummationReceived"]; ok {
+		values.Received, err = getValueFloat("zigbee:CurrentSummationReceived", component)
+		if err != nil {
+			return values, err
+		}
+	}
+
 	values.Price, err = getValueFloat("zigbee:Price", component)
 	if err != nil {
 		return values, err
@@ -53,28 +86,40 @@ func getBaseMetrics(ctx context.Context, localAPI local.API, hardwareAddress str
 	return values, err
 }
 
-func getValue(name string, v map[string]local.Variable) (string, error) {
-	variable, ok := v[name]
+func getReading(name string, readings local.Readings) (local.Reading, error) {
+	reading, ok := readings[name]
 	if !ok {
-		return "", fmt.Errorf("%v does not exist: %v", name, v)
+		return reading, &local.UnsupportedVariableError{Variables: []string{name}, Reason: "not present in device query response"}
 	}
 
-	return variable.Value, nil
+	return reading, nil
 }
 
-func getValueFloat(name string, v map[string]local.Variable) (float64, error) {
-	value, err := getValue(name, v)
-	if err != nil {
-		return 0, err
+func getValue(name string, readings local.Readings) (string, error) {
+	reading, err := getReading(name, readings)
+	if err != nil || !reading.Defined() {
+		return "", err
 	}
 
-	if value == "undefined" {
-		return 0, nil
+	value, ok := reading.Text()
+	if !ok {
+		return "", fmt.Errorf("%s:%v is not text", name, reading.Value)
 	}
 
-	result, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return 0, fmt.Errorf("%s:%s - %v", name, value, err)
+	return value, nil
+}
+
+//undefined values are reported as 0
+func getValueFloat(name string, readings local.Readings) (float64, error) {
+	reading, err := getReading(name, readings)
+	if err != nil || !reading.Defined() {
+		return 0, err
+	}
+
+	value, ok := reading.Float()
+	if !ok {
+		return 0, fmt.Errorf("%s:%v is not a number", name, reading.Value)
 	}
-	return result, nil
+
+	return value, nil
 }
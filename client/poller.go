@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//Sample is a BaseMetrics and the time it was read
+type Sample struct {
+	Time    time.Time   `json:"time"`
+	Metrics BaseMetrics `json:"metrics"`
+}
+
+//Sink receives every Sample the Poller publishes, sinks are called in order on the poller's go routine so should not block
+type Sink func(context.Context, Sample)
+
+//ErrorSink receives the time and error of every failed poll, like Sinks they should not block
+type ErrorSink func(context.Context, time.Time, error)
+
+//Poller requests BaseMetrics on a schedule and publishes each one to its sinks.  It is the single source of readings for
+//everything in reagled that wants a history rather than the value at scrape time.
+type Poller struct {
+	l        Local
+	interval time.Duration
+
+	mu         sync.RWMutex
+	sinks      []Sink
+	errorSinks []ErrorSink
+}
+
+//NewPoller creates a Poller, it does nothing until Run is called
+func NewPoller(l Local, interval time.Duration, sinks ...Sink) *Poller {
+	return &Poller{l: l, interval: interval, sinks: sinks}
+}
+
+//Add adds a sink to the poller, it will receive every Sample published after it is added
+func (p *Poller) Add(sink Sink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sinks = append(p.sinks, sink)
+}
+
+//AddErrorSink adds a sink for failed polls
+func (p *Poller) AddErrorSink(sink ErrorSink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.errorSinks = append(p.errorSinks, sink)
+}
+
+//Publish hands the Sample to every sink
+func (p *Poller) Publish(ctx context.Context, sample Sample) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, sink := range p.sinks {
+		sink(ctx, sample)
+	}
+}
+
+//Run polls until the context is done.  Errors (including being rate limited) are counted and handed to the error sinks
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Poller) poll(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, p.interval)
+	defer clean()
+
+	response, err := p.l.Request(timeout, RequestBaseMetrics())
+	if err != nil {
+		pollErrors.WithLabelValues(ErrorKind(err)).Inc()
+		p.publishError(ctx, time.Now(), err)
+		return
+	}
+
+	//an undefined summation would be published as 0, which every sink that takes deltas would see as the meter resetting
+	metrics := response.(BaseMetrics)
+	if metrics.SummationUndefined() {
+		pollSkipped.Inc()
+		return
+	}
+
+	p.Publish(ctx, Sample{Time: time.Now(), Metrics: metrics})
+}
+
+func (p *Poller) publishError(ctx context.Context, ts time.Time, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, sink := range p.errorSinks {
+		sink(ctx, ts, err)
+	}
+}
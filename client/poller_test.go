@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollerSkipsUndefinedSummations(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	//the meter glitches in the middle of the series
+	delivered := []string{"12345.000", local.UndefinedValue, "12346.000"}
+	polls := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		command, err := local.ParseProxiedCommand(body)
+		require.NoError(t, err)
+
+		var response interface{}
+		switch command.Name {
+		case "device_list":
+			response = local.DeviceList{Device: []local.Device{{DeviceData: local.DeviceData{HardwareAddress: "0x01", ModelID: "electric_meter"}}}}
+		case "device_query":
+			response = local.DeviceQueryResponse{Components: local.NewComponents(local.Component{
+				Name: "Main",
+				Variables: local.NewVariables(
+					local.Variable{Name: "zigbee:InstantaneousDemand", Value: "1.000"},
+					local.Variable{Name: "zigbee:CurrentSummationDelivered", Value: delivered[polls]},
+					local.Variable{Name: "zigbee:CurrentSummationReceived", Value: "0.000"},
+					local.Variable{Name: "zigbee:Price", Value: "0.1200"},
+					local.Variable{Name: "zigbee:PriceCurrency", Value: "840"},
+				),
+			})}
+			polls++
+		}
+
+		b, err := xml.Marshal(response)
+		require.NoError(t, err)
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	l := NewDangerous(ctx, local.New(local.Config{Location: u.Host}), time.Millisecond)
+
+	var published []float64
+	poller := NewPoller(l, time.Second, func(_ context.Context, sample Sample) {
+		published = append(published, sample.Metrics.Delivered)
+	})
+
+	for range delivered {
+		poller.poll(ctx)
+	}
+
+	assert.Equal(t, len(delivered), polls)
+	assert.Equal(t, []float64{12345, 12346}, published, "the undefined summation is not published as 0")
+}
+
+func TestSummationUndefined(t *testing.T) {
+	assert.False(t, BaseMetrics{}.SummationUndefined())
+	assert.False(t, BaseMetrics{Undefined: []string{"zigbee:Price"}}.SummationUndefined())
+	assert.True(t, BaseMetrics{Undefined: []string{"zigbee:CurrentSummationReceived"}}.SummationUndefined())
+}
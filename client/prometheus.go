@@ -20,11 +20,11 @@ var (
 		[]string{"type"},
 	)
 
-	errors = promauto.NewCounterVec(prometheus.CounterOpts{
+	cErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "client_errors",
 		Help: "Count of errors from the client",
 	},
-		[]string{"type"},
+		[]string{"type", "kind"},
 	)
 
 	sendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
@@ -55,6 +55,18 @@ var (
 		[]string{"type"},
 	)
 
+	pollErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_poll_errors",
+		Help: "Count of errors from the poller, by kind",
+	},
+		[]string{"kind"},
+	)
+
+	pollSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "client_poll_skipped",
+		Help: "Count of polls not published because the meter reported a summation as undefined",
+	})
+
 	limit = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "client_rate_limits",
 		Help: "Count of rate limit results from the client",
@@ -64,10 +76,16 @@ var (
 )
 
 func initMetricsForAllTypes() {
+	for _, kind := range ErrorKinds {
+		pollErrors.WithLabelValues(kind).Add(0)
+	}
+
 	for _, t := range allTypes {
 		cRequests.WithLabelValues(typeName(t)).Add(0)
 		replies.WithLabelValues(typeName(t)).Add(0)
-		errors.WithLabelValues(typeName(t)).Add(0)
+		for _, kind := range ErrorKinds {
+			cErrors.WithLabelValues(typeName(t), kind).Add(0)
+		}
 		sendErrors.WithLabelValues(typeName(t)).Add(0)
 		requestCancelled.WithLabelValues(typeName(t)).Add(0)
 		awaitErrors.WithLabelValues(typeName(t)).Add(0)
@@ -1,6 +1,10 @@
 package client
 
-import "context"
+import (
+	"context"
+
+	"github.com/kklipsch/reagle/local"
+)
 
 type (
 	requestType int
@@ -20,6 +24,7 @@ const (
 	localDeviceList
 	localWifiStatus
 	localBaseMetrics
+	localPost
 )
 
 var (
@@ -30,6 +35,7 @@ var (
 		localDeviceList,
 		localWifiStatus,
 		localBaseMetrics,
+		localPost,
 	}
 )
 
@@ -47,17 +53,19 @@ func typeName(t requestType) string {
 		return "wifi_status"
 	case localBaseMetrics:
 		return "base_metrics"
+	case localPost:
+		return "post"
 	default:
 		return "unknown"
 	}
 }
 
-//RequestSpecificVariable is a Request to do a device query for the provided variable name on the smart meter
+//RequestSpecificVariable is a Request to do a device query for the provided variable name on the smart meter, the result is a map of component name -> local.Readings
 func RequestSpecificVariable(variable string) Request {
 	return request(localSpecificVariable, variable)
 }
 
-//RequestAllVariables is a Request to do a device query for all available variables on the smart meter
+//RequestAllVariables is a Request to do a device query for all available variables on the smart meter, the result is a map of component name -> local.Readings
 func RequestAllVariables() Request {
 	return request(localAllVariables)
 }
@@ -82,6 +90,11 @@ func RequestBaseMetrics() Request {
 	return request(localBaseMetrics)
 }
 
+//RequestPost is a Request to forward a command from another client to the Eagle, the result is the Eagle's response body
+func RequestPost(command local.ProxiedCommand) Request {
+	return request(localPost, command)
+}
+
 func awaitResult(ctx context.Context, r Request) (interface{}, error) {
 	select {
 	case result, ok := <-r.resultsPromise:
@@ -109,7 +122,7 @@ func sendResult(r Request, result interface{}, err error) {
 	toSend := result
 	if err != nil {
 		toSend = err
-		errors.WithLabelValues(name).Inc()
+		cErrors.WithLabelValues(name, ErrorKind(err)).Inc()
 	} else {
 		replies.WithLabelValues(name).Inc()
 	}
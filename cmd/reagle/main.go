@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/billing"
+	"github.com/kklipsch/reagle/local"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	addressFlag = cli.StringFlag{
+		Name:   "address",
+		Usage:  "url of the reagled to query, or its unix socket such as unix:/run/reagled/reagled.sock which is spoken to in plain http",
+		EnvVar: "REAGLE_ADDRESS",
+		Value:  "http://localhost:9000",
+	}
+
+	tokenFlag = cli.StringFlag{
+		Name:   "token",
+		Usage:  "bearer token from the reagled's auth_file",
+		EnvVar: "REAGLE_TOKEN",
+	}
+
+	userFlag = cli.StringFlag{
+		Name:   "user",
+		Usage:  "user from the reagled's auth_file, authenticates with basic auth and password",
+		EnvVar: "REAGLE_USER",
+	}
+
+	passwordFlag = cli.StringFlag{
+		Name:   "password",
+		Usage:  "password of user",
+		EnvVar: "REAGLE_PASSWORD",
+	}
+
+	caFileFlag = cli.StringFlag{
+		Name:   "ca_file",
+		Usage:  "pem file of certificate authorities to trust when the reagled serves https",
+		EnvVar: "REAGLE_CA_FILE",
+	}
+
+	certFileFlag = cli.StringFlag{
+		Name:   "cert_file",
+		Usage:  "pem client certificate for a reagled that verifies them",
+		EnvVar: "REAGLE_CERT_FILE",
+	}
+
+	keyFileFlag = cli.StringFlag{
+		Name:   "key_file",
+		Usage:  "pem key of cert_file",
+		EnvVar: "REAGLE_KEY_FILE",
+	}
+
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "markdown, csv or json",
+		Value: "markdown",
+	}
+
+	atFlag = cli.StringFlag{
+		Name:  "at",
+		Usage: "RFC3339 time to report the billing cycle of, defaults to now",
+	}
+)
+
+//unixPrefix makes the address a unix domain socket, as it does for reagled
+const unixPrefix = "unix:"
+
+const (
+	usageErrorCode int = iota + 1
+	requestErrorCode
+	outputErrorCode
+	authErrorCode
+)
+
+//authError is reagled refusing the credentials, or there being none when it needs them
+type authError struct {
+	status string
+	hint   string
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("reagled returned %s, %s", e.status, e.hint)
+}
+
+//reagled is where to query and the credentials to query it with
+type reagled struct {
+	address  string
+	client   *http.Client
+	token    string
+	user     string
+	password string
+}
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "reagle"
+	app.Usage = "command line tool for a reagled bridging a Rainforest Automation Eagle 200"
+	app.Flags = []cli.Flag{addressFlag, tokenFlag, userFlag, passwordFlag, caFileFlag, certFileFlag, keyFileFlag}
+	app.Commands = []cli.Command{
+		{
+			Name:   "report",
+			Usage:  "summarize the current billing cycle",
+			Flags:  []cli.Flag{formatFlag, atFlag},
+			Action: report,
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func report(cliCtx *cli.Context) error {
+	format := cliCtx.String(formatFlag.Name)
+	switch format {
+	case "markdown", "csv", "json":
+	default:
+		return cli.NewExitError(fmt.Sprintf("unknown format: %s", format), usageErrorCode)
+	}
+
+	query := url.Values{}
+	if at := cliCtx.String(atFlag.Name); at != "" {
+		if _, err := time.Parse(time.RFC3339, at); err != nil {
+			return cli.NewExitError(fmt.Sprintf("invalid at: %v", err), usageErrorCode)
+		}
+
+		query.Set("at", at)
+	}
+
+	s, err := newReagled(cliCtx)
+	if err != nil {
+		return cli.NewExitError(err, usageErrorCode)
+	}
+
+	r, err := s.fetchReport(query)
+	if _, ok := err.(*authError); ok {
+		return cli.NewExitError(err, authErrorCode)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err, requestErrorCode)
+	}
+
+	switch format {
+	case "csv":
+		err = billing.WriteCSV(os.Stdout, r)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(r)
+	default:
+		err = billing.WriteMarkdown(os.Stdout, r)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err, outputErrorCode)
+	}
+
+	return nil
+}
+
+func newReagled(cliCtx *cli.Context) (reagled, error) {
+	s := reagled{
+		address:  cliCtx.GlobalString(addressFlag.Name),
+		token:    cliCtx.GlobalString(tokenFlag.Name),
+		user:     cliCtx.GlobalString(userFlag.Name),
+		password: cliCtx.GlobalString(passwordFlag.Name),
+	}
+
+	tlsConfig, err := clientTLS(cliCtx.GlobalString(caFileFlag.Name), cliCtx.GlobalString(certFileFlag.Name), cliCtx.GlobalString(keyFileFlag.Name))
+	if err != nil {
+		return s, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if strings.HasPrefix(s.address, unixPrefix) {
+		path := strings.TrimPrefix(s.address, unixPrefix)
+		transport.DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}
+
+		//the host is not used to connect but a url needs one
+		s.address = "http://reagled"
+	}
+
+	s.client = &http.Client{Timeout: time.Second * 30, Transport: transport}
+	return s, nil
+}
+
+//clientTLS trusts the certificate authorities in caFile as well as the system's, and presents the client certificate
+//if there is one
+func clientTLS(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	config, err := local.TLSConfig(caFile, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ca_file: %v", err)
+	}
+
+	if certFile == "" && keyFile == "" {
+		return config, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cert_file or key_file: %v", err)
+	}
+
+	config.Certificates = []tls.Certificate{cert}
+	return config, nil
+}
+
+func (s reagled) fetchReport(query url.Values) (billing.Report, error) {
+	r := billing.Report{}
+
+	resp, err := s.get("/reports/billing", query)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return r, fmt.Errorf("reagled returned %s, is storage_dir set?", resp.Status)
+	default:
+		return r, fmt.Errorf("reagled returned %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	return r, err
+}
+
+//get is an authenticated request, a 401 or 403 is an *authError
+func (s reagled) get(path string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(s.address, "/")+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.token != "":
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	case s.user != "":
+		req.SetBasicAuth(s.user, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && s.token == "" && s.user == "":
+		resp.Body.Close()
+		return nil, &authError{status: resp.Status, hint: "set --token or --user and --password"}
+	case resp.StatusCode == http.StatusUnauthorized:
+		resp.Body.Close()
+		return nil, &authError{status: resp.Status, hint: "check the credentials are in the reagled's auth_file"}
+	case resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, &authError{status: resp.Status, hint: "the credentials' scope does not allow this"}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kklipsch/reagle/billing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+func parseReagled(t *testing.T, args ...string) (reagled, error) {
+	var (
+		s   reagled
+		err error
+	)
+
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{addressFlag, tokenFlag, userFlag, passwordFlag, caFileFlag, certFileFlag, keyFileFlag}
+	app.Action = func(cliCtx *cli.Context) error {
+		s, err = newReagled(cliCtx)
+		return nil
+	}
+
+	require.NoError(t, app.Run(append([]string{"reagle"}, args...)))
+	return s, err
+}
+
+func serveReport(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(billing.Report{CycleDay: 15})
+}
+
+func TestFetchReport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		switch {
+		case r.Header.Get("Authorization") == "Bearer secret", user == "grafana" && password == "hunter2":
+		case r.Header.Get("Authorization") == "Bearer control":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/reports/billing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		serveReport(w, r)
+	}))
+	defer ts.Close()
+
+	s := reagled{address: ts.URL, client: ts.Client()}
+
+	_, err := s.fetchReport(url.Values{})
+	require.IsType(t, &authError{}, err)
+	assert.Contains(t, err.Error(), "--token", "no credentials were given")
+
+	s.token = "wrong"
+	_, err = s.fetchReport(url.Values{})
+	require.IsType(t, &authError{}, err)
+	assert.NotContains(t, err.Error(), "storage_dir")
+
+	s.token = "control"
+	_, err = s.fetchReport(url.Values{})
+	assert.IsType(t, &authError{}, err)
+
+	s.token = "secret"
+	r, err := s.fetchReport(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, 15, r.CycleDay)
+
+	s.token = ""
+	s.user, s.password = "grafana", "hunter2"
+	_, err = s.fetchReport(url.Values{})
+	assert.NoError(t, err)
+
+	s.address = ts.URL + "/missing"
+	_, err = s.fetchReport(url.Values{})
+	assert.Contains(t, err.Error(), "storage_dir")
+}
+
+func TestFetchReportTLS(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(serveReport))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "reagle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	//the server's own certificate is trusted and presented as the client's
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	key, err := x509.MarshalPKCS8PrivateKey(ts.TLS.Certificates[0].PrivateKey)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+
+	s, err := parseReagled(t, "--address", ts.URL, "--ca_file", certFile, "--cert_file", certFile, "--key_file", keyFile)
+	require.NoError(t, err)
+	r, err := s.fetchReport(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, 15, r.CycleDay)
+
+	s, err = parseReagled(t, "--address", ts.URL, "--ca_file", certFile)
+	require.NoError(t, err)
+	_, err = s.fetchReport(url.Values{})
+	assert.Error(t, err, "the client certificate is required")
+
+	_, err = parseReagled(t, "--address", ts.URL, "--ca_file", filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestFetchReportUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reagle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "reagled.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: http.HandlerFunc(serveReport)}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	s, err := parseReagled(t, "--address", unixPrefix+path)
+	require.NoError(t, err)
+	r, err := s.fetchReport(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, 15, r.CycleDay)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kklipsch/reagle/alert"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	messageVariable = "zigbee:Message"
+
+	//alertCheckInterval is how often rules that depend on time passing, such as meter_disconnected, are evaluated
+	alertCheckInterval = time.Second * 30
+)
+
+var (
+	alertFiring = prometheus.NewDesc("alert_firing", "1 if the alert rule is firing", []string{"rule"}, nil)
+
+	alertNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "alert_notifications_total",
+		Help: "Count of alert notifications by webhook and whether they were sent, failed or dropped",
+	},
+		[]string{"webhook", "result"},
+	)
+)
+
+//alertMonitor runs the rules engine over the poller's samples and delivers its notifications
+type alertMonitor struct {
+	c      client.Local
+	engine *alert.Engine
+}
+
+func newAlertMonitor(ctx context.Context, background *runners, reg prometheus.Registerer, c client.Local, config alert.Config, loc *time.Location, poller *client.Poller, pollInterval time.Duration, messageInterval time.Duration) (*alertMonitor, error) {
+	transport, err := instrumentClient("alert_webhooks", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher := alert.NewDispatcher(&http.Client{Transport: transport}, config.Webhooks, 100)
+	dispatcher.Result = alertResult
+
+	for _, w := range config.Webhooks {
+		for _, result := range []string{"sent", "failed", "dropped"} {
+			alertNotifications.WithLabelValues(w.Name, result).Add(0)
+		}
+	}
+
+	monitor := &alertMonitor{c: c, engine: alert.NewEngine(config, loc, logNotification(dispatcher.Send))}
+	monitor.engine.StaleAfter = alertStaleAfter(pollInterval)
+	err = reg.Register(monitor)
+	if err != nil {
+		return monitor, err
+	}
+
+	background.run(ctx, dispatcher.Run)
+
+	poller.Add(monitor.engine.Sample)
+	poller.AddErrorSink(monitor.engine.PollError)
+
+	go pollEvery(ctx, alertCheckInterval, func(ctx context.Context) {
+		monitor.engine.Check(time.Now())
+	})
+
+	if monitor.engine.WantsMessages() {
+		go pollEvery(ctx, messageInterval, monitor.pollMessage)
+	}
+
+	return monitor, nil
+}
+
+//alertStaleAfter is how long without a reading before the meter is disconnected, a couple of missed polls but no less
+//than the default so the eagle's pushes are not expected any more often
+func alertStaleAfter(pollInterval time.Duration) time.Duration {
+	if stale := pollInterval * 2; stale > alert.DefaultStaleAfter {
+		return stale
+	}
+
+	return alert.DefaultStaleAfter
+}
+
+//seedDayUsage sets the energy used so far today from storage, so a restart does not reset daily_usage_above
+func seedDayUsage(store *storage.Store, engine *alert.Engine, loc *time.Location, now time.Time) error {
+	stored, err := storedHistory(store, storage.Day.Start(now, loc), now)
+	if err != nil {
+		return err
+	}
+
+	engine.SeedDayUsage(now, stored.delivered())
+	return nil
+}
+
+func (m *alertMonitor) pollMessage(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	response, err := m.c.Request(timeout, client.RequestSpecificVariable(messageVariable))
+	if err != nil {
+		instrumentError(err, "unable to get the utility message for alerts")
+		return
+	}
+
+	message := ""
+	for _, readings := range response.(map[string]local.Readings) {
+		if text, ok := readings[messageVariable].Text(); ok {
+			message = text
+		}
+	}
+
+	m.engine.Message(time.Now(), message)
+}
+
+func logNotification(send alert.Sender) alert.Sender {
+	return func(w alert.Webhook, n alert.Notification) {
+		applicationLogger.WithFields(log.Fields{
+			"rule":    n.Rule,
+			"status":  n.Status,
+			"webhook": w.Name,
+		}).Infoln(n.Summary)
+
+		send(w, n)
+	}
+}
+
+func alertResult(webhook string, err error) {
+	switch {
+	case err == alert.ErrQueueFull:
+		alertNotifications.WithLabelValues(webhook, "dropped").Inc()
+	case err != nil:
+		alertNotifications.WithLabelValues(webhook, "failed").Inc()
+	default:
+		alertNotifications.WithLabelValues(webhook, "sent").Inc()
+		return
+	}
+
+	applicationLogger.WithFields(log.Fields{"webhook": webhook, "err": err}).Errorln("unable to deliver alert notification")
+}
+
+func (m *alertMonitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- alertFiring
+}
+
+func (m *alertMonitor) Collect(ch chan<- prometheus.Metric) {
+	for rule, firing := range m.engine.Firing() {
+		value := 0.0
+		if firing {
+			value = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(alertFiring, prometheus.GaugeValue, value, rule)
+	}
+}
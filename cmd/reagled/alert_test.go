@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/alert"
+	"github.com/kklipsch/reagle/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertStaleAfter(t *testing.T) {
+	assert.Equal(t, alert.DefaultStaleAfter, alertStaleAfter(0), "the eagle's pushes")
+	assert.Equal(t, alert.DefaultStaleAfter, alertStaleAfter(time.Second*10))
+	assert.Equal(t, time.Minute*20, alertStaleAfter(time.Minute*10))
+}
+
+func TestSeedDayUsage(t *testing.T) {
+	config, err := alert.Parse([]byte(`{
+		"webhooks": [{"name": "hook", "url": "http://localhost"}],
+		"rules": [{"name": "budget", "type": "daily_usage_above", "threshold": 1}]
+	}`))
+	require.NoError(t, err)
+
+	start := time.Date(2018, 10, 15, 10, 0, 0, 0, time.UTC)
+	store, clean := testStore(t, minuteReadings(start, repeat(6, 9)...)...)
+	defer clean()
+
+	var notifications []alert.Notification
+	engine := alert.NewEngine(config, time.UTC, func(w alert.Webhook, n alert.Notification) {
+		notifications = append(notifications, n)
+	})
+
+	now := start.Add(time.Minute * 9)
+	require.NoError(t, seedDayUsage(store, engine, time.UTC, now))
+
+	//0.9 kWh stored and 0.2 kWh after the restart
+	ctx := context.Background()
+	engine.Sample(ctx, client.Sample{Time: now, Metrics: client.BaseMetrics{Delivered: 50}})
+	engine.Sample(ctx, client.Sample{Time: now.Add(time.Minute), Metrics: client.BaseMetrics{Delivered: 50.2}})
+	require.Len(t, notifications, 1)
+	assert.InDelta(t, 1.1, notifications[0].Value, 0.0001)
+}
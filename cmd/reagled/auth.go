@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kklipsch/reagle/auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var authRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_requests_total",
+	Help: "Count of requests to the http api by authentication method and whether they were allowed, open, unauthenticated, invalid, throttled or forbidden",
+},
+	[]string{"method", "outcome"},
+)
+
+//authMiddleware requires every request to authenticate, except /metrics when openMetrics is set and the routes standing
+//in for the eagle, which check the eagle's credentials themselves
+func authMiddleware(config auth.Config, openMetrics bool) auth.Middleware {
+	methods := config.Methods()
+	for _, method := range append([]string{"none"}, methodNames(methods)...) {
+		for _, outcome := range []string{"allowed", "open", "unauthenticated", "invalid", "throttled", "forbidden"} {
+			authRequests.WithLabelValues(method, outcome).Add(0)
+		}
+	}
+
+	return auth.Middleware{
+		Methods: methods,
+		Policy:  authPolicy(openMetrics),
+		Result:  authResult,
+	}
+}
+
+func authPolicy(openMetrics bool) func(r *http.Request) auth.Scope {
+	return func(r *http.Request) auth.Scope {
+		switch {
+		case r.URL.Path == uploaderPath, r.URL.Path == postManagerPath:
+			return auth.None
+		case r.URL.Path == "/metrics" && openMetrics:
+			return auth.None
+		default:
+			return auth.ByMethod(r)
+		}
+	}
+}
+
+func authResult(r *http.Request, id auth.Identity, err error) {
+	method := id.Method
+	if method == "" {
+		method = "none"
+	}
+
+	switch err {
+	case nil:
+		outcome := "allowed"
+		if id.Method == "" {
+			outcome = "open"
+		}
+		authRequests.WithLabelValues(method, outcome).Inc()
+		return
+	case auth.ErrNoCredentials:
+		//browsers ask without credentials before prompting, that is not worth logging
+		authRequests.WithLabelValues(method, "unauthenticated").Inc()
+		return
+	case auth.ErrForbidden:
+		authRequests.WithLabelValues(method, "forbidden").Inc()
+	case auth.ErrTooManyFailures:
+		authRequests.WithLabelValues(method, "throttled").Inc()
+	default:
+		authRequests.WithLabelValues(method, "invalid").Inc()
+	}
+
+	applicationLogger.WithFields(log.Fields{
+		"err":         err,
+		"name":        id.Name,
+		"auth_method": method,
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"remote_addr": r.RemoteAddr,
+	}).Warnln("request refused")
+}
+
+func methodNames(methods []auth.Method) []string {
+	var names []string
+	for _, method := range methods {
+		names = append(names, method.Name())
+	}
+
+	return names
+}
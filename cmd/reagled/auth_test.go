@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kklipsch/reagle/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthPolicy(t *testing.T) {
+	closed, open := authPolicy(false), authPolicy(true)
+
+	metrics := httptest.NewRequest("GET", "/metrics", nil)
+	assert.Equal(t, auth.Read, closed(metrics))
+	assert.Equal(t, auth.None, open(metrics))
+
+	assert.Equal(t, auth.Read, closed(httptest.NewRequest("GET", "/local/devicelist", nil)))
+	assert.Equal(t, auth.Control, closed(httptest.NewRequest("DELETE", "/local/devicelist", nil)))
+
+	assert.Equal(t, auth.None, closed(httptest.NewRequest("POST", uploaderPath, nil)), "checks the uploader's credentials")
+	assert.Equal(t, auth.None, closed(httptest.NewRequest("POST", postManagerPath, nil)), "checks the eagle's credentials")
+}
@@ -6,13 +6,15 @@ import (
 	"time"
 
 	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
 	instantDemand    = prometheus.NewDesc("instantaneous_demand", "current demand", nil, nil)
 	currentDelivered = prometheus.NewDesc("current_summation_delivered", "total provided", nil, nil)
-	price            = prometheus.NewDesc("price", "price as provided by the meter", []string{"currency"}, nil)
+	currentReceived  = prometheus.NewDesc("current_summation_received", "total received from the premises (e.g. solar)", nil, nil)
+	price            = prometheus.NewDesc("price", "price as provided by the meter, currency is the ISO 4217 numeric code the meter reports", []string{"currency"}, nil)
 )
 
 type (
@@ -29,6 +31,12 @@ type (
 		//use the standard client that keeps things threadsafe and throttled
 		c client.Local
 	}
+
+	//sampleCollector collects the latest sample from the poller, with the time it was read, for the pushers
+	sampleCollector struct {
+		ctx     context.Context
+		samples atomic.Value
+	}
 )
 
 func newPrometheusBridge(ctx context.Context, reg prometheus.Registerer, c client.Local) (*rainForestBridge, error) {
@@ -43,6 +51,19 @@ func newPrometheusBridge(ctx context.Context, reg prometheus.Registerer, c clien
 	return bridge, err
 }
 
+//pushGatherer gathers what is registered with reg, the bridge is not, along with the poller's latest sample in its
+//place.  Pushed metrics carry the time they were read and pushing does not call the api the way a scrape does
+func pushGatherer(ctx context.Context, gatherer prometheus.Gatherer, poller *client.Poller) (prometheus.Gatherer, error) {
+	collector := &sampleCollector{ctx: ctx}
+	poller.Add(func(ctx context.Context, sample client.Sample) {
+		collector.samples.Store(sample)
+	})
+
+	samples := prometheus.NewRegistry()
+	err := samples.Register(collector)
+	return prometheus.Gatherers{gatherer, samples}, err
+}
+
 //we have constant metrics so this can delegate to library code
 func (bridge *rainForestBridge) Describe(ch chan<- *prometheus.Desc) {
 	prometheus.DescribeByCollect(bridge, ch)
@@ -61,32 +82,60 @@ func (bridge *rainForestBridge) Collect(ch chan<- prometheus.Metric) {
 		response = bridge.previousValues.Load()
 	}
 
-	collectValues(ctx, ch, response)
+	collectValues(ctx, ch, response, time.Time{})
 	bridge.previousValues.Store(response)
 }
 
-func collectValues(ctx context.Context, ch chan<- prometheus.Metric, response interface{}) {
+func (c *sampleCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *sampleCollector) Collect(ch chan<- prometheus.Metric) {
+	//nothing until the first poll
+	if sample, ok := c.samples.Load().(client.Sample); ok {
+		collectValues(c.ctx, ch, sample.Metrics, sample.Time)
+	}
+}
+
+//collectValues sends the metrics, with ts as their timestamp unless it is zero
+func collectValues(ctx context.Context, ch chan<- prometheus.Metric, response interface{}, ts time.Time) {
 	//go ahead and panic cause if this isnt a BaseMetrics it means something disastorous has happened
 	values := response.(client.BaseMetrics)
 
-	send(ctx, ch, prometheus.MustNewConstMetric(
+	stamp := func(metric prometheus.Metric) prometheus.Metric {
+		if ts.IsZero() {
+			return metric
+		}
+
+		return prometheus.NewMetricWithTimestamp(ts, metric)
+	}
+
+	send(ctx, ch, stamp(prometheus.MustNewConstMetric(
 		instantDemand,
 		prometheus.GaugeValue,
 		values.Demand,
-	))
+	)))
 
-	send(ctx, ch, prometheus.MustNewConstMetric(
+	send(ctx, ch, stamp(prometheus.MustNewConstMetric(
 		currentDelivered,
 		prometheus.CounterValue,
 		values.Delivered,
-	))
+	)))
+
+	send(ctx, ch, stamp(prometheus.MustNewConstMetric(
+		currentReceived,
+		prometheus.CounterValue,
+		values.Received,
+	)))
 
-	send(ctx, ch, prometheus.MustNewConstMetric(
+	send(ctx, ch, stamp(prometheus.MustNewConstMetric(
 		price,
 		prometheus.GaugeValue,
 		values.Price,
-		values.Currency,
-	))
+		//the rest of reagle uses the alphabetic code but this label has always been the number, changing it would break
+		//existing series
+		local.CurrencyNumber(values.Currency),
+	)))
 }
 
 func send(ctx context.Context, ch chan<- prometheus.Metric, metric prometheus.Metric) {
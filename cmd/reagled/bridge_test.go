@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushGatherer(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "other_total", Help: "registered with reg"})
+	require.NoError(t, reg.Register(counter))
+
+	poller := client.NewPoller(nil, 0)
+	pushed, err := pushGatherer(ctx, reg, poller)
+	require.NoError(t, err)
+
+	families, err := pushed.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1, "no meter metrics until the first sample")
+
+	read := time.Date(2018, 10, 15, 10, 0, 0, 0, time.UTC)
+	poller.Publish(ctx, client.Sample{Time: read, Metrics: client.BaseMetrics{Demand: 1.5, Currency: "USD"}})
+
+	families, err = pushed.Gather()
+	require.NoError(t, err)
+
+	var demand, price *dto.Metric
+	for _, family := range families {
+		switch family.GetName() {
+		case "instantaneous_demand":
+			demand = family.GetMetric()[0]
+		case "price":
+			price = family.GetMetric()[0]
+		}
+	}
+
+	require.NotNil(t, price)
+	assert.Equal(t, "840", price.GetLabel()[0].GetValue(), "the currency label is the numeric code")
+
+	require.NotNil(t, demand)
+	assert.Equal(t, 1.5, demand.GetGauge().GetValue())
+	assert.Equal(t, read.UnixNano()/int64(time.Millisecond), demand.GetTimestampMs(), "the time it was read")
+}
@@ -2,9 +2,20 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/kklipsch/reagle/influx"
 	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/mqtt"
+	"github.com/kklipsch/reagle/otlp"
+	"github.com/kklipsch/reagle/remotewrite"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/stream"
+	"github.com/kklipsch/reagle/tlsreload"
+	"github.com/kklipsch/reagle/uploader"
 	cli "gopkg.in/urfave/cli.v1"
 )
 
@@ -12,25 +23,232 @@ type Config struct {
 	Address     string        `json:"address"`
 	Wait        time.Duration `json:"wait"`
 	LocalConfig local.Config
+
+	PollInterval time.Duration   `json:"poll_interval"`
+	StorageDir   string          `json:"storage_dir"`
+	Storage      storage.Options `json:"storage"`
+	TariffFile   string          `json:"tariff_file"`
+	BillingDay   int             `json:"billing_day"`
+
+	DemandWindows       []time.Duration `json:"demand_windows"`
+	DemandPeakThreshold float64         `json:"demand_peak_threshold"`
+
+	AlertFile            string        `json:"alert_file"`
+	AlertMessageInterval time.Duration `json:"alert_message_interval"`
+
+	MQTT             mqtt.Config   `json:"mqtt"`
+	MQTTPollInterval time.Duration `json:"mqtt_poll_interval"`
+
+	Influx               influx.Config `json:"influx"`
+	InfluxDeviceInterval time.Duration `json:"influx_device_interval"`
+
+	RemoteWrite remotewrite.Config `json:"remote_write"`
+	OTLP        otlp.Config        `json:"otlp"`
+
+	Uploader            uploader.Config  `json:"uploader"`
+	PostManagerCacheTTL time.Duration    `json:"post_manager_cache_ttl"`
+	Stream              stream.Config    `json:"stream"`
+	GRPCAddress         string           `json:"grpc_address"`
+	Dashboard           bool             `json:"dashboard"`
+	AuthFile            string           `json:"auth_file"`
+	AuthOpenMetrics     bool             `json:"auth_open_metrics"`
+	TLS                 tlsreload.Config `json:"tls"`
+	TLSReloadInterval   time.Duration    `json:"tls_reload_interval"`
+	UnixSocketMode      os.FileMode      `json:"unix_socket_mode"`
+
+	DevicePollInterval time.Duration `json:"device_poll_interval"`
+	WifiPollInterval   time.Duration `json:"wifi_poll_interval"`
 }
 
 func configure(ctx context.Context, cliCtx *cli.Context) (Config, error) {
 	cfg := Config{
 		Address: cliCtx.String(addressFlag.Name),
 		Wait:    cliCtx.Duration(waitFlag.Name),
+
+		PollInterval: cliCtx.Duration(pollIntervalFlag.Name),
+		StorageDir:   cliCtx.String(storageDirFlag.Name),
+		TariffFile:   cliCtx.String(tariffFileFlag.Name),
+		BillingDay:   cliCtx.Int(billingDayFlag.Name),
+
+		DemandPeakThreshold: cliCtx.Float64(demandPeakThresholdFlag.Name),
+
+		AlertFile:            cliCtx.String(alertFileFlag.Name),
+		AlertMessageInterval: cliCtx.Duration(alertMessageIntervalFlag.Name),
+
+		MQTT: mqtt.Config{
+			Broker:          cliCtx.String(mqttBrokerFlag.Name),
+			ClientID:        cliCtx.String(mqttClientIDFlag.Name),
+			Username:        cliCtx.String(mqttUsernameFlag.Name),
+			Password:        cliCtx.String(mqttPasswordFlag.Name),
+			QoS:             byte(cliCtx.Int(mqttQoSFlag.Name)),
+			TopicPrefix:     cliCtx.String(mqttTopicPrefixFlag.Name),
+			DiscoveryPrefix: cliCtx.String(mqttDiscoveryPrefixFlag.Name),
+			NodeID:          cliCtx.String(mqttNodeIDFlag.Name),
+		},
+		MQTTPollInterval: cliCtx.Duration(mqttPollIntervalFlag.Name),
+
+		Influx: influx.Config{
+			URL:           cliCtx.String(influxURLFlag.Name),
+			Org:           cliCtx.String(influxOrgFlag.Name),
+			Bucket:        cliCtx.String(influxBucketFlag.Name),
+			Token:         cliCtx.String(influxTokenFlag.Name),
+			BatchSize:     cliCtx.Int(influxBatchSizeFlag.Name),
+			FlushInterval: cliCtx.Duration(influxFlushIntervalFlag.Name),
+			SpoolDir:      cliCtx.String(influxSpoolDirFlag.Name),
+			MaxSpoolBytes: cliCtx.Int64(influxMaxSpoolBytesFlag.Name),
+		},
+		InfluxDeviceInterval: cliCtx.Duration(influxDeviceIntervalFlag.Name),
+
+		RemoteWrite: remotewrite.Config{
+			URL:         cliCtx.String(remoteWriteURLFlag.Name),
+			Username:    cliCtx.String(remoteWriteUsernameFlag.Name),
+			Password:    cliCtx.String(remoteWritePasswordFlag.Name),
+			BearerToken: cliCtx.String(remoteWriteBearerTokenFlag.Name),
+			Interval:    cliCtx.Duration(remoteWriteIntervalFlag.Name),
+			MaxPending:  cliCtx.Int(remoteWriteMaxPendingFlag.Name),
+		},
+
+		OTLP: otlp.Config{
+			Endpoint: cliCtx.String(otlpEndpointFlag.Name),
+			Interval: cliCtx.Duration(otlpIntervalFlag.Name),
+		},
+
+		Uploader: uploader.Config{
+			Username: cliCtx.String(uploaderUsernameFlag.Name),
+			Password: cliCtx.String(uploaderPasswordFlag.Name),
+		},
+
+		Storage: storage.Options{
+			Retention: storage.Retention{
+				Raw:    cliCtx.Duration(storageRawRetentionFlag.Name),
+				Minute: cliCtx.Duration(storageMinuteRetentionFlag.Name),
+				Hour:   cliCtx.Duration(storageHourRetentionFlag.Name),
+			},
+		},
+
+		PostManagerCacheTTL: cliCtx.Duration(postManagerCacheTTLFlag.Name),
+
+		Stream: stream.Config{
+			MaxSubscribers: cliCtx.Int(streamMaxSubscribersFlag.Name),
+			History:        cliCtx.Int(streamHistoryFlag.Name),
+			Buffer:         cliCtx.Int(streamBufferFlag.Name),
+			Policy:         stream.Policy(cliCtx.String(streamBackpressureFlag.Name)),
+			Heartbeat:      cliCtx.Duration(streamHeartbeatFlag.Name),
+		},
+
+		GRPCAddress: cliCtx.String(grpcAddressFlag.Name),
+		Dashboard:   cliCtx.Bool(dashboardFlag.Name),
+
+		AuthFile:        cliCtx.String(authFileFlag.Name),
+		AuthOpenMetrics: cliCtx.Bool(authOpenMetricsFlag.Name),
+
+		TLS: tlsreload.Config{
+			CertFile:          cliCtx.String(tlsCertFileFlag.Name),
+			KeyFile:           cliCtx.String(tlsKeyFileFlag.Name),
+			ClientCAFile:      cliCtx.String(tlsClientCAFileFlag.Name),
+			RequireClientCert: cliCtx.Bool(tlsRequireClientCertFlag.Name),
+		},
+		TLSReloadInterval: cliCtx.Duration(tlsReloadIntervalFlag.Name),
+
+		DevicePollInterval: cliCtx.Duration(devicePollIntervalFlag.Name),
+		WifiPollInterval:   cliCtx.Duration(wifiPollIntervalFlag.Name),
+	}
+
+	windows, err := parseDemandWindows(cliCtx.String(demandWindowsFlag.Name))
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.DemandWindows = windows
+
+	tags, err := parseTags(cliCtx.String(influxTagsFlag.Name))
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.Influx.Tags = tags
+
+	labels, err := parseTags(cliCtx.String(remoteWriteLabelsFlag.Name))
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.RemoteWrite.Labels = remoteWriteLabels(labels)
+
+	headers, err := parseTags(cliCtx.String(otlpHeadersFlag.Name))
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.OTLP.Headers = headers
+
+	mode, err := strconv.ParseUint(cliCtx.String(unixSocketModeFlag.Name), 8, 32)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid unix_socket_mode: %v", err)
 	}
 
-	localCfg := local.Config{
+	cfg.UnixSocketMode = os.FileMode(mode)
+
+	cfg.PollInterval, err = pollInterval(cliCtx, cfg.Uploader.Password != "")
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.LocalConfig, err = localConfig(cliCtx)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = local.ValidateConfig(cfg.LocalConfig)
+	return cfg, err
+}
+
+//localConfig is the part of the configuration for talking to the eagle, it is not validated so doctor can report on it
+func localConfig(cliCtx *cli.Context) (local.Config, error) {
+	cfg := local.Config{
 		Location:         cliCtx.String(locationFlag.Name),
 		User:             cliCtx.String(userFlag.Name),
 		ModelIDForMeter:  cliCtx.String(modelIDFlag.Name),
 		ImprovedFirmware: cliCtx.Bool(improvedFirmwareFlag.Name),
 		DebugRequest:     cliCtx.Bool(debugRequestFlag.Name),
 		DebugResponse:    cliCtx.Bool(debugResponseFlag.Name),
+		HTTPS:            cliCtx.Bool(httpsFlag.Name),
+		MaxResponseBytes: cliCtx.Int64(maxResponseBytesFlag.Name),
+	}
+
+	//the same filter local.ConfigFromEnv applies, otherwise the flag only changes what is logged
+	if !cfg.ImprovedFirmware {
+		cfg.Filter = local.BadResponseVariables
 	}
 
-	cfg.LocalConfig = local.SetPassword(localCfg, cliCtx.String(passwordFlag.Name))
+	if cfg.HTTPS {
+		tlsCfg, err := local.TLSConfig(cliCtx.String(caFileFlag.Name), cliCtx.Bool(insecureSkipVerifyFlag.Name))
+		if err != nil {
+			return cfg, err
+		}
 
-	err := local.ValidateConfig(cfg.LocalConfig)
-	return cfg, err
+		cfg.TLS = tlsCfg
+	}
+
+	credentials, err := local.CredentialsFrom(cliCtx.String(passwordFlag.Name), cliCtx.String(passwordFileFlag.Name), cliCtx.String(passwordCommandFlag.Name))
+	if err != nil {
+		return cfg, err
+	}
+
+	return local.SetCredentials(cfg, credentials), nil
+}
+
+//pollInterval is 0 when the eagle pushes to the uploader, its pushes are published in place of polling and doing both
+//would publish every reading twice.  Asking for both is an error
+func pollInterval(cliCtx *cli.Context, uploading bool) (time.Duration, error) {
+	interval := cliCtx.Duration(pollIntervalFlag.Name)
+	if !uploading || interval <= 0 {
+		return interval, nil
+	}
+
+	if cliCtx.IsSet(pollIntervalFlag.Name) {
+		return 0, fmt.Errorf("%s can not be used with %s, the eagle's pushes are published instead of polling", pollIntervalFlag.Name, uploaderPasswordFlag.Name)
+	}
+
+	return 0, nil
 }
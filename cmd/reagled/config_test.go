@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+func parseLocalConfig(t *testing.T, args ...string) local.Config {
+	var cfg local.Config
+
+	app := cli.NewApp()
+	app.Flags = localFlags
+	app.Action = func(cliCtx *cli.Context) error {
+		var err error
+		cfg, err = localConfig(cliCtx)
+		return err
+	}
+
+	require.NoError(t, app.Run(append([]string{"reagled"}, args...)))
+	return cfg
+}
+
+func TestLocalConfigFilter(t *testing.T) {
+	//the docker build sets this for the integration tests
+	defer os.Setenv(local.ImprovedFirmwareEnv, os.Getenv(local.ImprovedFirmwareEnv))
+	os.Unsetenv(local.ImprovedFirmwareEnv)
+
+	cfg := parseLocalConfig(t)
+	assert.True(t, cfg.ImprovedFirmware)
+	assert.False(t, cfg.GetFilter().Exclude("zigbee:Divisor"))
+
+	cfg = parseLocalConfig(t, "--unimproved_firmware=false")
+	assert.False(t, cfg.ImprovedFirmware)
+	assert.True(t, cfg.GetFilter().Exclude("zigbee:Divisor"), "multiplier and divisor are not queried on this firmware")
+	assert.True(t, cfg.GetFilter().Exclude("zigbee:Multiplier"))
+	assert.False(t, cfg.GetFilter().Exclude("zigbee:InstantaneousDemand"))
+}
+
+func TestPollInterval(t *testing.T) {
+	parse := func(uploading bool, args ...string) (time.Duration, error) {
+		var (
+			interval time.Duration
+			err      error
+		)
+
+		app := cli.NewApp()
+		app.Flags = []cli.Flag{pollIntervalFlag}
+		app.Action = func(cliCtx *cli.Context) error {
+			interval, err = pollInterval(cliCtx, uploading)
+			return nil
+		}
+
+		require.NoError(t, app.Run(append([]string{"reagled"}, args...)))
+		return interval, err
+	}
+
+	interval, err := parse(false)
+	require.NoError(t, err)
+	assert.Equal(t, pollIntervalFlag.Value, interval)
+
+	interval, err = parse(true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval, "the uploader turns polling off")
+
+	interval, err = parse(true, "--poll_interval=0")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+
+	_, err = parse(true, "--poll_interval=5s")
+	assert.Error(t, err, "the eagle's pushes and polls would both be published")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/dashboard"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+)
+
+//dashboardRoutes serves the page at /dashboard, it uses the /local endpoints and /stream when it is enabled
+func dashboardRoutes(today *dashboard.Today) routes {
+	return func(router *httprouter.Router) {
+		router.Handler("GET", "/dashboard", instrumentHandler("dashboard", dashboard.Handler()))
+		router.Handler("GET", "/dashboard/today", instrumentHandler("dashboard_today", todayHandler(today)))
+	}
+}
+
+func todayHandler(today *dashboard.Today) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, today.Summary(time.Now()))
+	}
+}
+
+//seedToday sets today's summary from storage, so a restart does not start it over.  With a tariff the energy is costed
+//the way billing reports do, following on from the billing period before today, otherwise at the meter's price
+func seedToday(store *storage.Store, today *dashboard.Today, costs *tariff.Calculator, loc *time.Location, now time.Time) error {
+	midnight := storage.Day.Start(now, loc)
+	stored, err := storedHistory(store, midnight, now)
+	if err != nil {
+		return err
+	}
+
+	var received, cost float64
+	currency := ""
+	for _, a := range stored.all() {
+		received += a.ReceivedDelta
+		cost += a.DeliveredDelta * a.Price
+		currency = a.Currency
+	}
+
+	if costs != nil {
+		calc := tariff.NewCalculator(costs.Tariff())
+		before, err := storedHistory(store, calc.Tariff().BillingPeriodStart(now), midnight)
+		if err != nil {
+			return err
+		}
+
+		calc.Seed(before.all())
+		previous := calc.Totals()
+		calc.Seed(stored.all())
+
+		totals := calc.Totals()
+		cost, currency = totals.PeriodEnergyCost, totals.Currency
+		if totals.PeriodStart.Equal(previous.PeriodStart) {
+			cost -= previous.PeriodEnergyCost
+		}
+	}
+
+	today.Seed(now, stored.delivered(), received, cost, currency)
+	return nil
+}
+
+//todaySink costs today's energy with the tariff's rate when there is one, otherwise with the meter's price
+func todaySink(today *dashboard.Today, costs *tariff.Calculator) client.Sink {
+	return func(ctx context.Context, sample client.Sample) {
+		rate, currency := sample.Metrics.Price, sample.Metrics.Currency
+		if costs != nil {
+			totals := costs.Totals()
+			rate, currency = totals.CurrentRate, totals.Currency
+		}
+
+		today.Add(sample.Time, sample.Metrics.Delivered, sample.Metrics.Received, rate, currency)
+	}
+}
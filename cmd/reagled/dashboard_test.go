@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/dashboard"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardRoutes(t *testing.T) {
+	today := dashboard.NewToday(time.UTC)
+	sink := todaySink(today, nil)
+
+	start := time.Now().Add(-time.Minute)
+	sink(context.Background(), client.Sample{Time: start, Metrics: client.BaseMetrics{Delivered: 10, Price: 0.12, Currency: "USD"}})
+	sink(context.Background(), client.Sample{Time: start.Add(time.Second), Metrics: client.BaseMetrics{Delivered: 12, Price: 0.12, Currency: "USD"}})
+
+	router := httprouter.New()
+	dashboardRoutes(today)(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/dashboard/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/dashboard", resp.Request.URL.Path, "the page's urls are relative to /dashboard")
+
+	resp, err = http.Get(ts.URL + "/dashboard/today")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var summary dashboard.Summary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	assert.InDelta(t, 2, summary.Delivered, 0.0001)
+	assert.InDelta(t, 0.24, summary.Cost, 0.0001, "costed at the meter's price without a tariff")
+	assert.Equal(t, "USD", summary.Currency)
+}
+
+func TestSeedToday(t *testing.T) {
+	midnight := time.Date(2018, 10, 15, 0, 0, 0, 0, time.UTC)
+	store, clean := testStore(t,
+		storage.Point{Time: midnight.Add(-time.Hour), Delivered: 100, Price: 0.10, Currency: "USD"},
+		storage.Point{Time: midnight.Add(-time.Minute * 30), Delivered: 101, Price: 0.10, Currency: "USD"},
+		storage.Point{Time: midnight.Add(time.Minute * 10), Delivered: 101.5, Received: 2, Price: 0.12, Currency: "USD"},
+	)
+	defer clean()
+
+	now := midnight.Add(time.Minute * 20)
+	today := dashboard.NewToday(time.UTC)
+	require.NoError(t, seedToday(store, today, nil, time.UTC, now))
+
+	summary := today.Summary(now)
+	assert.Equal(t, midnight, summary.Since)
+	assert.InDelta(t, 0.5, summary.Delivered, 0.0001)
+	assert.InDelta(t, 0.06, summary.Cost, 0.0001, "at the meter's price")
+	assert.Equal(t, "USD", summary.Currency)
+
+	//yesterday used up the first tier
+	rates, err := tariff.Parse([]byte(`{"currency": "USD", "timezone": "UTC", "seasons": [{"energy": {"type": "tiered", "tiers": [{"up_to_kwh": 1, "rate": 0.10}, {"rate": 0.20}]}}]}`))
+	require.NoError(t, err)
+
+	today = dashboard.NewToday(time.UTC)
+	require.NoError(t, seedToday(store, today, tariff.NewCalculator(rates), time.UTC, now))
+	assert.InDelta(t, 0.1, today.Summary(now).Cost, 0.0001)
+}
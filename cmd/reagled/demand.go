@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/demand"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	peakLabels = []string{"window", "method", "period"}
+
+	rollingDemand  = prometheus.NewDesc("demand_rolling_average_kw", "average demand over the window ending at the last reading", []string{"window"}, nil)
+	blockDemand    = prometheus.NewDesc("demand_block_average_kw", "average demand of the clock aligned block in progress so far", []string{"window"}, nil)
+	peakDemand     = prometheus.NewDesc("demand_peak_kw", "highest average demand of the day or month", peakLabels, nil)
+	peakDemandTime = prometheus.NewDesc("demand_peak_timestamp_seconds", "start of the block or end of the rolling window of the peak demand", peakLabels, nil)
+
+	demandApproachingPeak = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "demand_approaching_peak_total",
+		Help: "Count of the rolling demand reaching the threshold fraction of the month's peak",
+	},
+		[]string{"window"},
+	)
+)
+
+//demandMonitor exports the demand calculators, which are fed by the poller
+type demandMonitor struct {
+	calcs []*demand.Calculator
+}
+
+func newDemandMonitor(reg prometheus.Registerer, calcs []*demand.Calculator) (*demandMonitor, error) {
+	monitor := &demandMonitor{calcs: calcs}
+	for _, calc := range calcs {
+		demandApproachingPeak.WithLabelValues(formatWindow(calc.Window())).Add(0)
+	}
+
+	return monitor, reg.Register(monitor)
+}
+
+//seedDemand raises the calculators' peaks from the month so far in storage, so a restart does not lose them
+func seedDemand(store *storage.Store, calcs []*demand.Calculator, loc *time.Location, now time.Time) error {
+	stored, err := storedHistory(store, storage.Month.Start(now, loc), now)
+	if err != nil {
+		return err
+	}
+
+	for _, calc := range calcs {
+		calc.Seed(stored.hours, time.Hour, stored.cutoff)
+		calc.Seed(stored.minutes, time.Minute, now)
+	}
+
+	return nil
+}
+
+//demandSink feeds every polled sample to the calculators, handing completed blocks to onBlock if it is not nil
+func demandSink(calcs []*demand.Calculator, onBlock func(demand.Event)) client.Sink {
+	return func(ctx context.Context, sample client.Sample) {
+		for _, calc := range calcs {
+			for _, event := range calc.Add(sample.Time, sample.Metrics.Delivered) {
+				switch event.Type {
+				case demand.ApproachingPeak:
+					logApproachingPeak(event)
+				case demand.BlockEnd:
+					if onBlock != nil {
+						onBlock(event)
+					}
+				}
+			}
+		}
+	}
+}
+
+func logApproachingPeak(event demand.Event) {
+	window := formatWindow(event.Window)
+	demandApproachingPeak.WithLabelValues(window).Inc()
+	applicationLogger.WithFields(log.Fields{
+		"window":    window,
+		"demand_kw": event.Demand,
+		"peak_kw":   event.Peak,
+	}).Warnln("demand approaching the month's peak")
+}
+
+func (m *demandMonitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rollingDemand
+	ch <- blockDemand
+	ch <- peakDemand
+	ch <- peakDemandTime
+}
+
+func (m *demandMonitor) Collect(ch chan<- prometheus.Metric) {
+	for _, calc := range m.calcs {
+		snapshot := calc.Snapshot()
+		window := formatWindow(snapshot.Window)
+
+		if snapshot.RollingValid {
+			ch <- prometheus.MustNewConstMetric(rollingDemand, prometheus.GaugeValue, snapshot.Rolling, window)
+		}
+
+		ch <- prometheus.MustNewConstMetric(blockDemand, prometheus.GaugeValue, snapshot.Block, window)
+
+		for _, method := range demand.Methods {
+			peaks := snapshot.Peaks[method]
+			for period, peak := range map[string]demand.Peak{"day": peaks.Day, "month": peaks.Month} {
+				if peak.Time.IsZero() {
+					continue
+				}
+
+				ch <- prometheus.MustNewConstMetric(peakDemand, prometheus.GaugeValue, peak.Demand, window, string(method), period)
+				ch <- prometheus.MustNewConstMetric(peakDemandTime, prometheus.GaugeValue, float64(peak.Time.Unix()), window, string(method), period)
+			}
+		}
+	}
+}
+
+//parseDemandWindows parses a comma separated list of durations, each of which has to divide a day into whole blocks
+func parseDemandWindows(raw string) ([]time.Duration, error) {
+	var windows []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		window, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if window <= 0 || (time.Hour*24)%window != 0 {
+			return nil, fmt.Errorf("demand window %v does not divide a day evenly", window)
+		}
+
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+//formatWindow drops the zero units time.Duration.String includes, 15m rather than 15m0s
+func formatWindow(window time.Duration) string {
+	s := window.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/demand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDemandWindows(t *testing.T) {
+	windows, err := parseDemandWindows("15m, 30m,1h")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Minute * 15, time.Minute * 30, time.Hour}, windows)
+
+	windows, err = parseDemandWindows("")
+	require.NoError(t, err)
+	assert.Empty(t, windows)
+
+	_, err = parseDemandWindows("7m")
+	assert.Error(t, err)
+
+	assert.Equal(t, "15m", formatWindow(time.Minute*15))
+	assert.Equal(t, "1h", formatWindow(time.Hour))
+	assert.Equal(t, "1h30m", formatWindow(time.Minute*90))
+}
+
+func TestSeedDemand(t *testing.T) {
+	start := time.Date(2018, 10, 15, 10, 0, 0, 0, time.UTC)
+	demands := append(repeat(4, 15), repeat(1, 20)...)
+	store, clean := testStore(t, minuteReadings(start, demands...)...)
+	defer clean()
+
+	calc := demand.NewCalculator(time.Minute*15, time.UTC, 0)
+	require.NoError(t, seedDemand(store, []*demand.Calculator{calc}, time.UTC, start.Add(time.Minute*35)))
+
+	peaks := calc.Snapshot().Peaks[demand.Block]
+	assert.InDelta(t, 4.0, peaks.Month.Demand, 0.0001)
+	assert.Equal(t, start, peaks.Month.Time)
+}
+
+func repeat(f float64, count int) []float64 {
+	var fs []float64
+	for i := 0; i < count; i++ {
+		fs = append(fs, f)
+	}
+
+	return fs
+}
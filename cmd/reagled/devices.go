@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	deviceLabels = []string{"hardware_address", "model_id", "manufacturer"}
+
+	deviceConnected   = prometheus.NewDesc("eagle_device_connected", "1 if the eagle reports the device as connected", deviceLabels, nil)
+	deviceLastContact = prometheus.NewDesc("eagle_device_last_contact_timestamp_seconds", "last time the eagle heard from the device", deviceLabels, nil)
+	deviceInfo        = prometheus.NewDesc("eagle_device_info", "information about the devices on the eagle's zigbee network",
+		append(deviceLabels, "protocol", "network_address"), nil)
+
+	deviceEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eagle_device_events_total",
+		Help: "Count of devices joining, leaving or changing connection status",
+	},
+		[]string{"event"},
+	)
+)
+
+const (
+	deviceJoined        = "joined"
+	deviceLeft          = "left"
+	deviceStatusChanged = "status_changed"
+)
+
+type (
+	//polls the device list on a schedule, unlike the bridge, so that join/leave events are seen even when nobody is scraping
+	deviceMonitor struct {
+		c client.Local
+
+		//listeners are told about every event as well as it being logged
+		listeners []func(deviceEvent)
+
+		//hardware address -> local.Device from the last successful poll, nothing is stored until the first one
+		devices atomic.Value
+	}
+
+	deviceEvent struct {
+		event    string
+		device   local.Device
+		previous local.ConnectionStatus
+	}
+)
+
+func newDeviceMonitor(ctx context.Context, reg prometheus.Registerer, c client.Local, interval time.Duration, listeners ...func(deviceEvent)) (*deviceMonitor, error) {
+	monitor := &deviceMonitor{c: c, listeners: listeners}
+
+	for _, event := range []string{deviceJoined, deviceLeft, deviceStatusChanged} {
+		deviceEvents.WithLabelValues(event).Add(0)
+	}
+
+	err := reg.Register(monitor)
+	if err != nil {
+		return monitor, err
+	}
+
+	go pollEvery(ctx, interval, monitor.poll)
+	return monitor, nil
+}
+
+func (m *deviceMonitor) poll(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	response, err := m.c.Request(timeout, client.RequestDeviceList())
+	if err != nil {
+		instrumentError(err, "unable to get device list for device monitor")
+		return
+	}
+
+	current := make(map[string]local.Device)
+	for _, device := range response.([]local.Device) {
+		current[device.HardwareAddress] = device
+	}
+
+	//the first list is what was already there, not devices joining
+	previous, polled := m.devices.Load().(map[string]local.Device)
+	if polled {
+		for _, event := range diffDevices(previous, current) {
+			logDeviceEvent(event)
+			for _, listener := range m.listeners {
+				listener(event)
+			}
+		}
+	}
+
+	m.devices.Store(current)
+}
+
+//diffDevices returns the events needed to get from previous to current
+func diffDevices(previous map[string]local.Device, current map[string]local.Device) []deviceEvent {
+	var events []deviceEvent
+	for address, device := range current {
+		before, ok := previous[address]
+		switch {
+		case !ok:
+			events = append(events, deviceEvent{event: deviceJoined, device: device})
+		case before.ConnectionStatus != device.ConnectionStatus:
+			events = append(events, deviceEvent{event: deviceStatusChanged, device: device, previous: before.ConnectionStatus})
+		}
+	}
+
+	for address, device := range previous {
+		if _, ok := current[address]; !ok {
+			events = append(events, deviceEvent{event: deviceLeft, device: device, previous: device.ConnectionStatus})
+		}
+	}
+
+	return events
+}
+
+func logDeviceEvent(event deviceEvent) {
+	deviceEvents.WithLabelValues(event.event).Inc()
+	applicationLogger.WithFields(log.Fields{
+		"event":             event.event,
+		"hardware_address":  event.device.HardwareAddress,
+		"model_id":          event.device.ModelID,
+		"manufacturer":      event.device.Manufacturer,
+		"connection_status": event.device.ConnectionStatus,
+		"previous_status":   event.previous,
+	}).Infoln("device event")
+}
+
+//devices come and go so the metrics are not constant
+func (m *deviceMonitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- deviceConnected
+	ch <- deviceLastContact
+	ch <- deviceInfo
+}
+
+//Collect reports the last poll rather than calling the eagle
+func (m *deviceMonitor) Collect(ch chan<- prometheus.Metric) {
+	devices, _ := m.devices.Load().(map[string]local.Device)
+	for _, device := range devices {
+		labels := []string{device.HardwareAddress, device.ModelID, device.Manufacturer}
+
+		connected := 0.0
+		if device.ConnectionStatus.IsConnected() {
+			connected = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(deviceConnected, prometheus.GaugeValue, connected, labels...)
+		ch <- prometheus.MustNewConstMetric(deviceInfo, prometheus.GaugeValue, 1,
+			append(labels, device.Protocol, device.NetworkAddress.String())...)
+
+		if !device.LastContact.IsZero() {
+			ch <- prometheus.MustNewConstMetric(deviceLastContact, prometheus.GaugeValue, float64(device.LastContact.Unix()), labels...)
+		}
+	}
+}
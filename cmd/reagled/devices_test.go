@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDevices(t *testing.T) {
+	device := func(address string, status local.ConnectionStatus) local.Device {
+		return local.Device{DeviceData: local.DeviceData{HardwareAddress: address, ConnectionStatus: status}}
+	}
+
+	previous := map[string]local.Device{
+		"meter": device("meter", local.Connected),
+		"plug":  device("plug", local.Connected),
+		"gone":  device("gone", local.Connected),
+	}
+
+	current := map[string]local.Device{
+		"meter": device("meter", local.Connected),
+		"plug":  device("plug", local.Disconnected),
+		"new":   device("new", local.Joining),
+	}
+
+	events := diffDevices(previous, current)
+	sort.Slice(events, func(i, j int) bool { return events[i].device.HardwareAddress < events[j].device.HardwareAddress })
+
+	assert.Equal(t, []deviceEvent{
+		{event: deviceLeft, device: device("gone", local.Connected), previous: local.Connected},
+		{event: deviceJoined, device: device("new", local.Joining)},
+		{event: deviceStatusChanged, device: device("plug", local.Disconnected), previous: local.Connected},
+	}, events)
+
+	assert.Empty(t, diffDevices(current, current))
+}
+
+func TestDeviceMonitorFirstPoll(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	devices := []local.Device{
+		{DeviceData: local.DeviceData{HardwareAddress: "meter", ConnectionStatus: local.Connected}},
+		{DeviceData: local.DeviceData{HardwareAddress: "plug", ConnectionStatus: local.Connected}},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(local.DeviceList{Device: devices})
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c := client.NewDangerous(ctx, local.New(local.Config{Location: u.Host}), time.Millisecond)
+
+	var events []deviceEvent
+	monitor := &deviceMonitor{c: c, listeners: []func(deviceEvent){func(e deviceEvent) { events = append(events, e) }}}
+
+	monitor.poll(ctx)
+	assert.Empty(t, events, "devices there at startup did not just join")
+
+	devices = devices[:1]
+	monitor.poll(ctx)
+	require.Len(t, events, 1)
+	assert.Equal(t, deviceLeft, events[0].event)
+	assert.Equal(t, "plug", events[0].device.HardwareAddress)
+}
+
+func TestDeviceMonitorCollect(t *testing.T) {
+	collect := func(status local.ConnectionStatus) map[string]prometheus.Labels {
+		monitor := &deviceMonitor{}
+		monitor.devices.Store(map[string]local.Device{
+			"meter": {DeviceData: local.DeviceData{HardwareAddress: "meter", Protocol: "Zigbee", ConnectionStatus: status}},
+		})
+
+		reg := prometheus.NewRegistry()
+		require.NoError(t, reg.Register(monitor))
+		families, err := reg.Gather()
+		require.NoError(t, err)
+
+		series := make(map[string]prometheus.Labels)
+		for _, family := range families {
+			labels := prometheus.Labels{"value": fmt.Sprint(family.GetMetric()[0].GetGauge().GetValue())}
+			for _, pair := range family.GetMetric()[0].GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			series[family.GetName()] = labels
+		}
+		return series
+	}
+
+	connected, disconnected := collect(local.Connected), collect(local.Disconnected)
+
+	assert.Equal(t, connected["eagle_device_info"], disconnected["eagle_device_info"], "a status change does not start a new info series")
+	assert.Equal(t, "1", connected["eagle_device_connected"]["value"])
+	assert.Equal(t, "0", disconnected["eagle_device_connected"]["value"])
+}
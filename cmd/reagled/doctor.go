@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+//doctorTimeout bounds each request so an eagle that accepts connections but never answers is reported rather than waited on
+const doctorTimeout = 10 * time.Second
+
+//doctorChecks are run in this order, a failure skips the checks that depend on it
+var doctorChecks = []string{"configuration", "reachable", "credentials", "meter", "model_id", "variables"}
+
+//checkResult is one line of the doctor report
+type checkResult struct {
+	Name   string
+	Status string
+	Detail string
+	Hint   string
+}
+
+//diagnosis is the results of the checks in the order they ran
+type diagnosis []checkResult
+
+//doctor runs the same checklist we go through every time a site doesn't work and prints what it finds
+func doctor(cliCtx *cli.Context) error {
+	ctx := setSignalCancel(context.Background(), os.Interrupt, os.Kill, syscall.SIGTERM)
+
+	config, err := localConfig(cliCtx)
+	if err != nil {
+		err = fmt.Errorf("error configuring: %v", err)
+		return cli.NewExitError(err, doctorErrorCode)
+	}
+
+	d := diagnose(ctx, config)
+	d.write(cliCtx.App.Writer)
+
+	if !d.ok() {
+		return cli.NewExitError("doctor found problems", doctorErrorCode)
+	}
+
+	return nil
+}
+
+func diagnose(ctx context.Context, config local.Config) diagnosis {
+	d := diagnosis{}
+	d.run(ctx, config)
+
+	//whatever did not run was skipped by a failure
+	for _, name := range doctorChecks[len(d):] {
+		d = append(d, checkResult{Name: name, Status: "skip", Detail: "an earlier check failed"})
+	}
+
+	return d
+}
+
+func (d *diagnosis) run(ctx context.Context, config local.Config) {
+	err := local.ValidateConfig(config)
+	if err != nil {
+		d.fail("configuration", err.Error(), "set --location, --user and one of --password, --password_file or --password_command, or their environment variables")
+		return
+	}
+
+	d.pass("configuration", fmt.Sprintf("eagle at %s as %s", config.Location, config.User), "")
+
+	api := local.New(config)
+	//every variable is queried so the ones this firmware answers with invalid xml can be found
+	api.Config.Filter = local.NoFilter
+
+	timeout, cancel := context.WithTimeout(ctx, doctorTimeout)
+	devices, err := api.DeviceList(timeout)
+	cancel()
+
+	if !d.reachable(config, err) || !d.credentials(err) {
+		return
+	}
+
+	if err != nil {
+		d.fail("meter", fmt.Sprintf("device list failed: %v", err), "run again with --debug_response to see what the eagle answered")
+		return
+	}
+
+	meter, ok := d.meter(config, devices)
+	if !ok {
+		return
+	}
+
+	d.variables(ctx, api, meter)
+}
+
+func (d *diagnosis) reachable(config local.Config, err error) bool {
+	var gateway *local.GatewayUnreachableError
+	if !errors.As(err, &gateway) {
+		d.pass("reachable", fmt.Sprintf("%s responded", local.PostManagerEndpoint(config)), "")
+		return true
+	}
+
+	hint := fmt.Sprintf("check that %s is the eagle's address and can be reached from here", config.Location)
+	if config.HTTPS && strings.Contains(err.Error(), "x509") {
+		hint = "the eagle's certificate is self signed, set --ca_file to it or set --insecure_skip_verify"
+	}
+
+	d.fail("reachable", err.Error(), hint)
+	return false
+}
+
+func (d *diagnosis) credentials(err error) bool {
+	var (
+		auth  *local.AuthenticationError
+		creds *local.CredentialsError
+	)
+
+	switch {
+	case errors.As(err, &creds):
+		d.fail("credentials", err.Error(), "check the password file or command, it is loaded again every time the eagle rejects the password")
+		return false
+	case errors.As(err, &auth):
+		d.fail("credentials", err.Error(), "the user is the eagle's cloud id and the password its install code, both are on the label on the bottom of the eagle")
+		return false
+	default:
+		d.pass("credentials", "accepted by the eagle", "")
+		return true
+	}
+}
+
+//meter checks both that the eagle knows of a meter and that it is the one configured, returning the meter to check the
+//variables of even when the model id is wrong so that check can still run
+func (d *diagnosis) meter(config local.Config, devices []local.Device) (local.Device, bool) {
+	want := config.GetModelIDForMeter()
+
+	var (
+		models     []string
+		candidates []local.Device
+		configured *local.Device
+	)
+
+	for i, device := range devices {
+		models = append(models, device.ModelID)
+
+		switch {
+		case device.ModelID == want:
+			configured = &devices[i]
+		case strings.Contains(strings.ToLower(device.ModelID), "meter"):
+			candidates = append(candidates, device)
+		}
+	}
+
+	switch {
+	case configured != nil:
+		d.pass("meter", fmt.Sprintf("found %s", configured.ModelID), "")
+		d.pass("model_id", fmt.Sprintf("%s is %s", want, configured.HardwareAddress), "")
+		return *configured, true
+	case len(devices) == 0:
+		d.fail("meter", "the eagle has no devices", "pair the meter with the eagle, it can take a few minutes to join after the utility approves it")
+	case len(candidates) == 0:
+		d.fail("meter", fmt.Sprintf("no meter among %s", strings.Join(models, ", ")), "pair the meter with the eagle, or set --model_id if one of these is the meter")
+	default:
+		d.pass("meter", fmt.Sprintf("found %s", candidates[0].ModelID), "")
+	}
+
+	if len(candidates) == 0 {
+		d.fail("model_id", fmt.Sprintf("no device has model id %s", want), "set --model_id to the meter's model id")
+		return local.Device{}, false
+	}
+
+	d.fail("model_id", fmt.Sprintf("no device has model id %s", want), fmt.Sprintf("set --model_id=%s", candidates[0].ModelID))
+	return candidates[0], true
+}
+
+//variables queries each variable on its own so one answered with invalid xml does not hide the others
+func (d *diagnosis) variables(ctx context.Context, api local.API, meter local.Device) {
+	timeout, cancel := context.WithTimeout(ctx, doctorTimeout)
+	details, err := api.DeviceDetails(timeout, meter.HardwareAddress)
+	cancel()
+
+	if err != nil {
+		d.fail("variables", fmt.Sprintf("device details failed: %v", err), "run again with --debug_response to see what the eagle answered")
+		return
+	}
+
+	names := local.VariablesFromDetailsResponse(details)
+	if len(names) == 0 {
+		d.fail("variables", fmt.Sprintf("the eagle lists no variables for %s", meter.HardwareAddress), "the meter may still be joining, try again in a few minutes")
+		return
+	}
+
+	var malformed, failed, firmware []string
+	for _, name := range names {
+		timeout, cancel := context.WithTimeout(ctx, doctorTimeout)
+		_, err := api.DeviceQuery(timeout, meter.HardwareAddress, name)
+		cancel()
+
+		var (
+			xmlErr   *local.MalformedXMLError
+			meterErr *local.MeterUnreachableError
+		)
+
+		switch {
+		case err == nil:
+		case errors.As(err, &meterErr):
+			d.fail("variables", err.Error(), "the eagle has lost the meter, check that it is in range and still joined")
+			return
+		case errors.As(err, &xmlErr) && local.BadResponseVariables.Exclude(name):
+			firmware = append(firmware, name)
+		case errors.As(err, &xmlErr):
+			malformed = append(malformed, name)
+		default:
+			failed = append(failed, fmt.Sprintf("%s (%v)", name, err))
+		}
+	}
+
+	improved := api.Config.ImprovedFirmware
+	switch {
+	case len(malformed) > 0 || len(failed) > 0:
+		problems := append(failed, malformed...)
+		d.fail("variables", fmt.Sprintf("%d of %d did not respond with valid xml: %s", len(problems), len(names), strings.Join(problems, ", ")), "check for an eagle firmware update, reagled can not read these")
+	case len(firmware) > 0 && improved:
+		d.fail("variables", fmt.Sprintf("invalid xml for %s", strings.Join(firmware, ", ")), fmt.Sprintf("this firmware is not improved, set --unimproved_firmware=false or %s=false so they are not queried", local.ImprovedFirmwareEnv))
+	case len(firmware) > 0:
+		d.pass("variables", fmt.Sprintf("%d of %d respond with valid xml, %s are not queried with this firmware", len(names)-len(firmware), len(names), strings.Join(firmware, ", ")), "")
+	case !improved:
+		d.pass("variables", fmt.Sprintf("all %d respond with valid xml", len(names)), fmt.Sprintf("this firmware is improved, %s=false is not needed", local.ImprovedFirmwareEnv))
+	default:
+		d.pass("variables", fmt.Sprintf("all %d respond with valid xml", len(names)), "")
+	}
+}
+
+func (d *diagnosis) pass(name string, detail string, hint string) {
+	*d = append(*d, checkResult{Name: name, Status: "pass", Detail: detail, Hint: hint})
+}
+
+func (d *diagnosis) fail(name string, detail string, hint string) {
+	*d = append(*d, checkResult{Name: name, Status: "fail", Detail: detail, Hint: hint})
+}
+
+func (d diagnosis) ok() bool {
+	for _, result := range d {
+		if result.Status == "fail" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (d diagnosis) write(w io.Writer) {
+	for _, result := range d {
+		fmt.Fprintf(w, "%-4s  %-13s  %s\n", strings.ToUpper(result.Status), result.Name, result.Detail)
+		if result.Hint != "" {
+			fmt.Fprintf(w, "%-4s  %-13s  hint: %s\n", "", "", result.Hint)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEagle struct {
+	devices   []local.Device
+	variables []string
+	malformed map[string]bool
+}
+
+func (e fakeEagle) start(t *testing.T) (*httptest.Server, local.Config) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "cloudid" || password != "installcode" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		command, err := local.ParseProxiedCommand(body)
+		require.NoError(t, err)
+
+		var response interface{}
+		switch command.Name {
+		case "device_list":
+			response = local.DeviceList{Device: e.devices}
+		case "device_details":
+			names := local.ComponentName{Name: "Main", Variables: local.VariableNames{Variable: e.variables}}
+			response = local.DeviceDetailsResponse{Components: local.ComponentNames{Component: []local.ComponentName{names}}}
+		case "device_query":
+			if e.malformed[command.Variables[0]] {
+				w.Write([]byte("<Device><Components>"))
+				return
+			}
+
+			response = local.DeviceQueryResponse{Components: local.NewComponents(local.NewComponent("Main", command.Variables...))}
+		}
+
+		b, err := xml.Marshal(response)
+		require.NoError(t, err)
+		w.Write(b)
+	}))
+
+	u, _ := url.Parse(ts.URL)
+	config := local.SetPassword(local.Config{Location: u.Host, User: "cloudid", ImprovedFirmware: true}, "installcode")
+	return ts, config
+}
+
+func meterDevice(modelID string) local.Device {
+	return local.Device{DeviceData: local.DeviceData{HardwareAddress: "0x0013500100f5ac41", ModelID: modelID}}
+}
+
+func statuses(d diagnosis) map[string]string {
+	statuses := make(map[string]string)
+	for _, result := range d {
+		statuses[result.Name] = result.Status
+	}
+
+	return statuses
+}
+
+func hint(d diagnosis, name string) string {
+	for _, result := range d {
+		if result.Name == name {
+			return result.Hint
+		}
+	}
+
+	return ""
+}
+
+func TestDoctor(t *testing.T) {
+	ctx := context.Background()
+	variables := []string{"zigbee:InstantaneousDemand", "zigbee:Multiplier", "zigbee:Divisor"}
+
+	t.Run("healthy", func(t *testing.T) {
+		ts, config := fakeEagle{devices: []local.Device{meterDevice("electric_meter")}, variables: variables}.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, config)
+		assert.True(t, d.ok())
+		assert.Len(t, d, len(doctorChecks))
+		for _, name := range doctorChecks {
+			assert.Equal(t, "pass", statuses(d)[name], name)
+		}
+
+		var out bytes.Buffer
+		d.write(&out)
+		assert.Contains(t, out.String(), "PASS  variables      all 3 respond with valid xml")
+	})
+
+	t.Run("not_configured", func(t *testing.T) {
+		d := diagnose(ctx, local.Config{Location: "127.0.0.1:1"})
+		assert.False(t, d.ok())
+		assert.Equal(t, "fail", statuses(d)["configuration"])
+		assert.Equal(t, "skip", statuses(d)["variables"])
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		d := diagnose(ctx, local.SetPassword(local.Config{Location: "127.0.0.1:1", User: "cloudid"}, "installcode"))
+		assert.Equal(t, "fail", statuses(d)["reachable"])
+		assert.Equal(t, "skip", statuses(d)["credentials"])
+	})
+
+	t.Run("wrong_password", func(t *testing.T) {
+		ts, config := fakeEagle{}.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, local.SetPassword(config, "wrong"))
+		assert.Equal(t, "pass", statuses(d)["reachable"])
+		assert.Equal(t, "fail", statuses(d)["credentials"])
+		assert.Equal(t, "skip", statuses(d)["meter"])
+	})
+
+	t.Run("no_meter", func(t *testing.T) {
+		ts, config := fakeEagle{devices: []local.Device{meterDevice("thermostat")}}.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, config)
+		assert.Equal(t, "fail", statuses(d)["meter"])
+		assert.Equal(t, "fail", statuses(d)["model_id"])
+		assert.Equal(t, "skip", statuses(d)["variables"])
+	})
+
+	t.Run("model_id", func(t *testing.T) {
+		ts, config := fakeEagle{devices: []local.Device{meterDevice("thermostat"), meterDevice("gas_meter")}, variables: variables}.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, config)
+		assert.Equal(t, "pass", statuses(d)["meter"])
+		assert.Equal(t, "fail", statuses(d)["model_id"])
+		assert.Equal(t, "set --model_id=gas_meter", hint(d, "model_id"))
+		assert.Equal(t, "pass", statuses(d)["variables"], "the suggested meter is still checked")
+
+		config.ModelIDForMeter = "thermostat"
+		d = diagnose(ctx, config)
+		assert.True(t, d.ok(), "any model id can be the meter")
+	})
+
+	t.Run("unimproved_firmware", func(t *testing.T) {
+		eagle := fakeEagle{
+			devices:   []local.Device{meterDevice("electric_meter")},
+			variables: variables,
+			malformed: map[string]bool{"zigbee:Multiplier": true, "zigbee:Divisor": true},
+		}
+		ts, config := eagle.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, config)
+		assert.Equal(t, "fail", statuses(d)["variables"])
+		assert.Contains(t, hint(d, "variables"), "--unimproved_firmware=false")
+
+		config.ImprovedFirmware = false
+		d = diagnose(ctx, config)
+		assert.True(t, d.ok())
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		eagle := fakeEagle{
+			devices:   []local.Device{meterDevice("electric_meter")},
+			variables: variables,
+			malformed: map[string]bool{"zigbee:InstantaneousDemand": true},
+		}
+		ts, config := eagle.start(t)
+		defer ts.Close()
+
+		d := diagnose(ctx, config)
+		assert.False(t, d.ok())
+		assert.Contains(t, d[len(d)-1].Detail, "zigbee:InstantaneousDemand")
+	})
+}
@@ -10,12 +10,20 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func endpoint(c client.Local) http.Handler {
+//routes registers optional handlers, like those that need storage, on the router
+type routes func(*httprouter.Router)
+
+//endpoint serves the gatherer's metrics at /metrics, the api at /local and the extra routes
+func endpoint(c client.Local, gatherer prometheus.Gatherer, extra ...routes) http.Handler {
+	metrics := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+
 	router := httprouter.New()
-	router.Handler("GET", "/metrics", instrumentHandler("metrics", promhttp.Handler()))
+	router.Handler("GET", "/metrics", instrumentHandler("metrics", metrics))
 	router.Handler("GET", "/local/wifi", instrumentHandler("local_wifi", clientHandler(c, wifiStatus)))
 	router.Handler("GET", "/local/devicelist", instrumentHandler("local_devicelist", clientHandler(c, deviceList)))
 	router.Handler("GET", "/local/meter", instrumentHandler("local_meter", clientHandler(c, meterDetails)))
@@ -23,6 +31,10 @@ func endpoint(c client.Local) http.Handler {
 	router.Handler("GET", "/local/variable/", instrumentHandler("variable", clientHandler(c, allVariables)))
 	router.Handler("GET", "/local/metrics/", instrumentHandler("variable", clientHandler(c, baseMetrics)))
 
+	for _, add := range extra {
+		add(router)
+	}
+
 	return router
 }
 
@@ -55,16 +67,33 @@ func clientHandler(c client.Local, req func(interface{}) client.Request, getPayl
 		r = r.WithContext(timeout)
 
 		response, err := c.Request(r.Context(), req(payload))
-		switch err {
-		case nil:
-			jsonResponse(w, response)
-		case client.ErrRateLimited:
-			writeError(w, err, http.StatusServiceUnavailable)
-		case context.DeadlineExceeded:
-			writeError(w, err, http.StatusServiceUnavailable)
-		default:
-			writeError(w, err, http.StatusInternalServerError)
+		if err != nil {
+			writeError(w, err, statusForError(err))
+			return
+		}
+
+		jsonResponse(w, response)
+	}
+}
+
+//statusForError maps the kind of error to the status code we respond with.  Problems talking to the eagle are the
+//eagle's problem not the caller's, so they are reported as gateway errors rather than 4xx codes
+func statusForError(err error) int {
+	switch client.ErrorKind(err) {
+	case "rate_limited", "context", "meter_unreachable":
+		return http.StatusServiceUnavailable
+	case "device_not_found":
+		return http.StatusNotFound
+	case "unsupported_variable":
+		//only asking for a variable that can not be queried is the caller's fault, the meter leaving one out is not
+		if local.InvalidVariable(err) {
+			return http.StatusBadRequest
 		}
+		return http.StatusBadGateway
+	case "authentication", "server_error", "response_too_large", "malformed_xml", "gateway_unreachable":
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
@@ -89,6 +118,7 @@ func jsonResponse(w http.ResponseWriter, response interface{}) {
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(b)
 	if err != nil {
 		writeError(w, err, http.StatusInternalServerError)
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusForError(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{&local.UnsupportedVariableError{Variables: []string{"zigbee:Divisor"}, Reason: "excluded by filter", Invalid: true}, http.StatusBadRequest},
+		{&local.UnsupportedVariableError{Variables: []string{"zigbee:Price"}, Reason: "not present in device query response"}, http.StatusBadGateway},
+		{&local.UnsupportedVariableError{Reason: "no variables defined"}, http.StatusBadGateway},
+		{&local.DeviceNotFoundError{ModelID: "electric_meter"}, http.StatusNotFound},
+		{&local.AuthenticationError{Code: http.StatusUnauthorized}, http.StatusBadGateway},
+		{client.ErrRateLimited, http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusServiceUnavailable},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.status, statusForError(c.err), c.err.Error())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/kklipsch/reagle/auth"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_requests_total",
+	Help: "Count of grpc calls by method and status code, streams are counted when they end",
+},
+	[]string{"method", "code"},
+)
+
+//startGRPC serves the rpc api on its own address rather than the http router's.  Calls are authenticated by access, with
+//the same credentials as the http api, unless it is nil.  It serves tls with the http api's reloaded certificate when
+//tlsConfig is set
+func startGRPC(ctx context.Context, address string, mode os.FileMode, c client.Local, poller *client.Poller, access *auth.Middleware, tlsConfig *tls.Config) (*grpc.Server, error) {
+	listener, err := listen(address, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(unaryInterceptor(access)), grpc.StreamInterceptor(streamInterceptor(access))}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(grpcTLS(tlsConfig))))
+	}
+
+	srv := grpc.NewServer(opts...)
+	rpc.RegisterReagleServer(srv, rpc.NewServer(ctx, c, poller))
+
+	go func() {
+		//Serve only returns nil once stopped
+		err := srv.Serve(listener)
+		if err != nil {
+			applicationLogger.WithFields(log.Fields{"err": err}).Fatalln("failed at serving grpc")
+		}
+	}()
+
+	return srv, nil
+}
+
+//grpcTLS negotiates http/2 on the reloaded certificate.  The reloader's config for each handshake comes from
+//GetConfigForClient, which credentials.NewTLS does not add h2 to
+func grpcTLS(config *tls.Config) *tls.Config {
+	grpcConfig := config.Clone()
+	if config.GetConfigForClient == nil {
+		return grpcConfig
+	}
+
+	grpcConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		loaded, err := config.GetConfigForClient(hello)
+		if err != nil || loaded == nil {
+			return loaded, err
+		}
+
+		loaded = loaded.Clone()
+		loaded.NextProtos = []string{"h2"}
+		return loaded, nil
+	}
+
+	return grpcConfig
+}
+
+func unaryInterceptor(access *auth.Middleware) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGRPC(ctx, access, info.FullMethod)
+		if err != nil {
+			countGRPC(info.FullMethod, err)
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+		countGRPC(info.FullMethod, err)
+		return resp, err
+	}
+}
+
+func streamInterceptor(access *auth.Middleware) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), access, info.FullMethod)
+		if err == nil {
+			err = handler(srv, authenticatedStream{ServerStream: ss, ctx: ctx})
+		}
+
+		countGRPC(info.FullMethod, err)
+		return err
+	}
+}
+
+//authenticateGRPC checks the call's credentials the way the http api checks a request's, every rpc only reads so Read is
+//required.  The context carries the Identity
+func authenticateGRPC(ctx context.Context, access *auth.Middleware, fullMethod string) (context.Context, error) {
+	if access == nil {
+		return ctx, nil
+	}
+
+	id, err := access.Check(grpcRequest(ctx, fullMethod), auth.Read)
+	switch err {
+	case nil:
+		return auth.NewContext(ctx, id), nil
+	case auth.ErrForbidden:
+		return ctx, status.Error(codes.PermissionDenied, "forbidden")
+	default:
+		return ctx, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+}
+
+//grpcRequest is the call as the http request the auth methods expect, its metadata as the headers and the peer's tls
+//state so client certificates are seen
+func grpcRequest(ctx context.Context, fullMethod string) *http.Request {
+	r := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: fullMethod},
+		Header: http.Header{},
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, values := range md {
+		for _, v := range values {
+			r.Header.Add(k, v)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+//authenticatedStream is the stream with the context carrying its Identity
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+//countGRPC labels by the method name without the /reagle.Reagle/ service prefix
+func countGRPC(fullMethod string, err error) {
+	grpcRequests.WithLabelValues(path.Base(fullMethod), status.Code(err).String()).Inc()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/kklipsch/reagle/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestGRPCAuth(t *testing.T) {
+	access := authMiddleware(auth.Config{Tokens: []auth.Token{
+		{Name: "grafana", Token: "secret", Scope: auth.Read},
+		{Name: "nobody", Token: "other", Scope: auth.Scope("none")},
+	}}, false)
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	unary := func(m *auth.Middleware, ctx context.Context) (auth.Identity, error) {
+		var id auth.Identity
+		info := &grpc.UnaryServerInfo{FullMethod: "/reagle.Reagle/GetBaseMetrics"}
+		_, err := unaryInterceptor(m)(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			id, _ = auth.FromContext(ctx)
+			return nil, nil
+		})
+
+		return id, err
+	}
+
+	id, err := unary(&access, withToken("secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "grafana", id.Name)
+
+	_, err = unary(&access, context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = unary(&access, withToken("wrong"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = unary(&access, withToken("other"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = unary(nil, context.Background())
+	assert.NoError(t, err, "open without an auth file")
+
+	stream := func(ctx context.Context) (bool, error) {
+		called := false
+		info := &grpc.StreamServerInfo{FullMethod: "/reagle.Reagle/WatchReadings"}
+		err := streamInterceptor(&access)(nil, fakeServerStream{ctx: ctx}, info, func(_ interface{}, ss grpc.ServerStream) error {
+			_, called = auth.FromContext(ss.Context())
+			return nil
+		})
+
+		return called, err
+	}
+
+	called, err := stream(withToken("secret"))
+	assert.NoError(t, err)
+	assert.True(t, called, "the stream's context has the identity")
+
+	called, err = stream(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestGRPCTLS(t *testing.T) {
+	reloaded := &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven}
+	config := grpcTLS(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return reloaded, nil },
+	})
+
+	handshake, err := config.GetConfigForClient(&tls.ClientHelloInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"h2"}, handshake.NextProtos)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, handshake.ClientAuth, "client certificates are still verified")
+	assert.Empty(t, reloaded.NextProtos, "the http api's config is not changed")
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/storage"
+)
+
+const (
+	defaultDemandRange = time.Hour * 24
+	defaultDemandStep  = time.Minute * 5
+	maxDemandBuckets   = 10000
+)
+
+type (
+	demandBucket struct {
+		Start time.Time `json:"start"`
+		Count int       `json:"count"`
+		Min   float64   `json:"min_kw"`
+		Max   float64   `json:"max_kw"`
+		Avg   float64   `json:"avg_kw"`
+	}
+
+	demandHistory struct {
+		Start      time.Time      `json:"start"`
+		End        time.Time      `json:"end"`
+		Step       string         `json:"step"`
+		Resolution string         `json:"resolution"`
+		Buckets    []demandBucket `json:"buckets"`
+	}
+
+	energyBucket struct {
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+		Delivered float64   `json:"delivered_kwh"`
+		Received  float64   `json:"received_kwh"`
+		Net       float64   `json:"net_kwh"`
+	}
+
+	energyHistory struct {
+		Period  storage.Period `json:"period"`
+		Start   time.Time      `json:"start"`
+		End     time.Time      `json:"end"`
+		Buckets []energyBucket `json:"buckets"`
+	}
+)
+
+//historyRoutes serves the stored history, energy periods are in loc unless the request asks for another tz
+func historyRoutes(store *storage.Store, loc *time.Location) routes {
+	return func(router *httprouter.Router) {
+		router.Handler("GET", "/history/demand", instrumentHandler("history_demand", demandHandler(store)))
+		router.Handler("GET", "/history/energy", instrumentHandler("history_energy", energyHandler(store, loc)))
+	}
+}
+
+func demandHandler(store *storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		end, err := parseTimeParam(query.Get("end"), time.Now())
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid end: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		start, err := parseTimeParam(query.Get("start"), end.Add(-defaultDemandRange))
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid start: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		step := defaultDemandStep
+		if raw := query.Get("step"); raw != "" {
+			step, err = time.ParseDuration(raw)
+			if err != nil || step <= 0 {
+				writeError(w, fmt.Errorf("invalid step: %s", raw), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if !start.Before(end) || end.Sub(start)/step > maxDemandBuckets {
+			writeError(w, fmt.Errorf("start must be before end with at most %v steps", maxDemandBuckets), http.StatusBadRequest)
+			return
+		}
+
+		resolution := storage.ResolutionFor(step)
+		aggregates, err := store.Query(resolution, start, end)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		history := demandHistory{Start: start, End: end, Step: step.String(), Resolution: resolution.String(), Buckets: []demandBucket{}}
+		for _, a := range storage.Rebucket(aggregates, storage.Step(step)) {
+			history.Buckets = append(history.Buckets, demandBucket{Start: a.Start, Count: a.Count, Min: a.DemandMin, Max: a.DemandMax, Avg: a.DemandAvg()})
+		}
+
+		if !wantsCSV(r) {
+			jsonResponse(w, history)
+			return
+		}
+
+		rows := [][]string{{"start", "count", "min_kw", "max_kw", "avg_kw"}}
+		for _, b := range history.Buckets {
+			rows = append(rows, []string{b.Start.Format(time.RFC3339), strconv.Itoa(b.Count), formatFloat(b.Min), formatFloat(b.Max), formatFloat(b.Avg)})
+		}
+
+		csvResponse(w, rows)
+	}
+}
+
+func energyHandler(store *storage.Store, defaultLoc *time.Location) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		period := storage.Day
+		if raw := query.Get("period"); raw != "" {
+			var err error
+			period, err = storage.ParsePeriod(raw)
+			if err != nil {
+				writeError(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+
+		loc := defaultLoc
+		if raw := query.Get("tz"); raw != "" {
+			var err error
+			loc, err = time.LoadLocation(raw)
+			if err != nil {
+				writeError(w, fmt.Errorf("invalid tz: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		end, err := parseTimeParam(query.Get("end"), time.Now())
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid end: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		start, err := parseTimeParam(query.Get("start"), defaultEnergyStart(period, end, loc))
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid start: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		start = period.Start(start, loc)
+		aggregates, err := energyAggregates(store, period, loc, start, end)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		history := energyHistory{Period: period, Start: start, End: end, Buckets: []energyBucket{}}
+		for _, a := range storage.Rebucket(aggregates, period.Bucketer(loc)) {
+			history.Buckets = append(history.Buckets, energyBucket{
+				Start:     a.Start,
+				End:       period.Next(a.Start),
+				Delivered: a.DeliveredDelta,
+				Received:  a.ReceivedDelta,
+				Net:       a.DeliveredDelta - a.ReceivedDelta,
+			})
+		}
+
+		if !wantsCSV(r) {
+			jsonResponse(w, history)
+			return
+		}
+
+		rows := [][]string{{"start", "end", "delivered_kwh", "received_kwh", "net_kwh"}}
+		for _, b := range history.Buckets {
+			rows = append(rows, []string{b.Start.Format(time.RFC3339), b.End.Format(time.RFC3339), formatFloat(b.Delivered), formatFloat(b.Received), formatFloat(b.Net)})
+		}
+
+		csvResponse(w, rows)
+	}
+}
+
+//energyAggregates uses the hours unless a period starts part way through an hour, such as midnight in a +05:30 zone.
+//Then it uses the minutes, hours older than the minutes retained still count in the period the hour starts in
+func energyAggregates(store *storage.Store, period storage.Period, loc *time.Location, start time.Time, end time.Time) ([]storage.Aggregate, error) {
+	if onTheHour(period, loc, start, end) {
+		return store.Query(storage.Hour, start, end)
+	}
+
+	history, err := storedHistory(store, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return history.all(), nil
+}
+
+//onTheHour is true if every period boundary from start to end falls on an hour
+func onTheHour(period storage.Period, loc *time.Location, start time.Time, end time.Time) bool {
+	for boundary := period.Start(start, loc); !boundary.After(end); boundary = period.Next(boundary) {
+		if !boundary.Equal(boundary.Truncate(time.Hour)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//by default show the last week of days, two months of weeks and a year of months
+func defaultEnergyStart(period storage.Period, end time.Time, loc *time.Location) time.Time {
+	switch period {
+	case storage.Week:
+		return end.In(loc).AddDate(0, 0, -7*8)
+	case storage.Month:
+		return end.In(loc).AddDate(-1, 0, 0)
+	default:
+		return end.In(loc).AddDate(0, 0, -7)
+	}
+}
+
+//parseTimeParam accepts RFC3339 or unix seconds, returning the default if raw is empty
+func parseTimeParam(raw string, defaultTime time.Time) (time.Time, error) {
+	if raw == "" {
+		return defaultTime, nil
+	}
+
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+}
+
+func wantsCSV(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func csvResponse(w http.ResponseWriter, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+
+	writer := csv.NewWriter(w)
+	err := writer.WriteAll(rows)
+	instrumentError(err, "unable to write csv")
+}
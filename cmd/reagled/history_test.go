@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyServer(t *testing.T, loc *time.Location, points ...storage.Point) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "reagled-history")
+	require.NoError(t, err)
+
+	store, err := storage.Open(dir, storage.DefaultOptions())
+	require.NoError(t, err)
+
+	for _, p := range points {
+		require.NoError(t, store.Append(p))
+	}
+
+	router := httprouter.New()
+	historyRoutes(store, loc)(router)
+	ts := httptest.NewServer(router)
+
+	return ts, func() {
+		ts.Close()
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2018, 10, 15, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour * 24).Format(time.RFC3339)
+	ts, clean := historyServer(t, time.UTC,
+		storage.Point{Time: start, Demand: 1, Delivered: 10},
+		storage.Point{Time: start.Add(time.Minute), Demand: 3, Delivered: 11},
+		storage.Point{Time: start.Add(time.Hour), Demand: 5, Delivered: 13, Received: 1},
+		storage.Point{Time: start.Add(time.Hour * 2), Demand: 7, Delivered: 2, Received: 1},
+	)
+	defer clean()
+
+	t.Run("demand", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/history/demand?step=1h&start=" + start.Format(time.RFC3339) + "&end=" + end)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		history := demandHistory{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+		require.Len(t, history.Buckets, 3)
+		assert.Equal(t, "1h", history.Resolution)
+		assert.Equal(t, 2.0, history.Buckets[0].Avg)
+		assert.Equal(t, 3.0, history.Buckets[0].Max)
+	})
+
+	t.Run("energy_csv", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/history/energy?period=month&format=csv&tz=UTC&end=" + end)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		require.Len(t, lines, 2, string(body))
+		//1 + 2 and then the counter reset to 2
+		assert.True(t, strings.HasSuffix(lines[1], ",5,1,4"), lines[1])
+	})
+
+	t.Run("bad_step", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/history/demand?step=soon")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestHistoryEnergyHalfHourZone(t *testing.T) {
+	loc := time.FixedZone("IST", int((time.Hour*5+time.Minute*30)/time.Second))
+	midnight := time.Date(2018, 10, 16, 0, 0, 0, 0, loc)
+	ts, clean := historyServer(t, loc,
+		storage.Point{Time: midnight.Add(-time.Minute * 30), Delivered: 10},
+		storage.Point{Time: midnight.Add(-time.Minute), Delivered: 11},
+		storage.Point{Time: midnight.Add(time.Minute), Delivered: 13},
+		storage.Point{Time: midnight.Add(time.Minute * 31), Delivered: 16},
+	)
+	defer clean()
+
+	//no tz so the days are in the configured location
+	resp, err := http.Get(ts.URL + "/history/energy?period=day&end=" + strconv.FormatInt(midnight.Add(time.Hour).Unix(), 10))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	history := energyHistory{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	require.Len(t, history.Buckets, 2)
+
+	assert.True(t, midnight.AddDate(0, 0, -1).Equal(history.Buckets[0].Start))
+	assert.Equal(t, 1.0, history.Buckets[0].Delivered, "the hour starting at 23:30 is split at midnight")
+
+	assert.True(t, midnight.Equal(history.Buckets[1].Start))
+	assert.Equal(t, 5.0, history.Buckets[1].Delivered)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/influx"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	influxSpoolBatches = prometheus.NewDesc("influx_spool_batches", "Number of batches waiting in the influx spool", nil, nil)
+	influxSpoolBytes   = prometheus.NewDesc("influx_spool_bytes", "Size of the batches waiting in the influx spool", nil, nil)
+
+	influxPoints = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "influx_points_total",
+		Help: "Count of points by whether they were written, spooled or dropped",
+	},
+		[]string{"outcome"},
+	)
+)
+
+//influxExporter writes the poller's samples, and the device list on its own interval, to influx
+type influxExporter struct {
+	c      client.Local
+	writer *influx.Writer
+}
+
+func newInfluxExporter(ctx context.Context, background *runners, reg prometheus.Registerer, c client.Local, config influx.Config, poller *client.Poller, deviceInterval time.Duration) (*influxExporter, error) {
+	transport, err := instrumentClient("influx", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := influx.NewWriter(&http.Client{Transport: transport}, config)
+	if err != nil {
+		return nil, err
+	}
+
+	writer.Result = influxResult
+	for _, outcome := range []string{influx.Written, influx.Spooled, influx.Dropped} {
+		influxPoints.WithLabelValues(outcome).Add(0)
+	}
+
+	exporter := &influxExporter{c: c, writer: writer}
+	err = reg.Register(exporter)
+	if err != nil {
+		return exporter, err
+	}
+
+	background.run(ctx, writer.Run)
+
+	poller.Add(exporter.sample)
+
+	if deviceInterval > 0 {
+		go pollEvery(ctx, deviceInterval, exporter.pollDevices)
+	}
+
+	return exporter, nil
+}
+
+func (e *influxExporter) sample(ctx context.Context, sample client.Sample) {
+	e.writer.Write(influx.SamplePoint(sample))
+}
+
+func (e *influxExporter) pollDevices(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	response, err := e.c.Request(timeout, client.RequestDeviceList())
+	if err != nil {
+		instrumentError(err, "unable to get device list for influx")
+		return
+	}
+
+	for _, p := range influx.DevicePoints(time.Now(), response.([]local.Device)) {
+		e.writer.Write(p)
+	}
+}
+
+func influxResult(outcome string, points int, err error) {
+	influxPoints.WithLabelValues(outcome).Add(float64(points))
+
+	if err != nil {
+		applicationLogger.WithFields(log.Fields{"outcome": outcome, "points": points, "err": err}).Errorln("unable to write to influx")
+	}
+}
+
+func (e *influxExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- influxSpoolBatches
+	ch <- influxSpoolBytes
+}
+
+func (e *influxExporter) Collect(ch chan<- prometheus.Metric) {
+	batches, bytes, err := e.writer.Spooled()
+	if err != nil {
+		instrumentError(err, "unable to read influx spool")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(influxSpoolBatches, prometheus.GaugeValue, float64(batches))
+	ch <- prometheus.MustNewConstMetric(influxSpoolBytes, prometheus.GaugeValue, float64(bytes))
+}
+
+//parseTags parses comma separated key=value pairs such as site=home,panel=main
+func parseTags(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", pair)
+		}
+
+		tags[kv[0]] = kv[1]
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags("site=home, panel=main=2,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"site": "home", "panel": "main=2"}, tags)
+
+	tags, err = parseTags("")
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	_, err = parseTags("site")
+	assert.Error(t, err)
+}
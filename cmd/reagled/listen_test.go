@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reagled")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "reagled.sock")
+
+	listener, err := listen(unixPrefix+path, 0600)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = listen(unixPrefix+path, 0600)
+	assert.Error(t, err, "in use")
+
+	//a socket left behind, as when reagled is killed
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	listener, err = listen(unixPrefix+path, 0660)
+	require.NoError(t, err)
+	listener.Close()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a socket"), 0600))
+	_, err = listen(unixPrefix+path, 0660)
+	assert.Error(t, err, "only sockets are removed")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,8 +10,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/kklipsch/reagle/alert"
+	"github.com/kklipsch/reagle/auth"
 	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/dashboard"
+	"github.com/kklipsch/reagle/demand"
 	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/stream"
+	"github.com/kklipsch/reagle/tariff"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	cli "gopkg.in/urfave/cli.v1"
@@ -21,7 +29,7 @@ var (
 
 	addressFlag = cli.StringFlag{
 		Name:   "address",
-		Usage:  "where to serve the endpoints",
+		Usage:  "where to serve the endpoints, such as :9000 or unix:/run/reagled/reagled.sock for a unix domain socket",
 		EnvVar: "REAGLED_ADDRESS",
 		Value:  ":9000",
 	}
@@ -33,6 +41,395 @@ var (
 		Value:  time.Second,
 	}
 
+	pollIntervalFlag = cli.DurationFlag{
+		Name:   "poll_interval",
+		Usage:  "how often to poll the meter for readings to hand to storage and the other exporters, 0 disables polling.  Polling is off when uploader_password is set",
+		EnvVar: "REAGLED_POLL_INTERVAL",
+		Value:  time.Second * 10,
+	}
+
+	storageDirFlag = cli.StringFlag{
+		Name:   "storage_dir",
+		Usage:  "directory to store reading history in, if not set history is not kept",
+		EnvVar: "REAGLED_STORAGE_DIR",
+	}
+
+	storageRawRetentionFlag = cli.DurationFlag{
+		Name:   "storage_raw_retention",
+		Usage:  "how long to keep every polled reading, at least 1h",
+		EnvVar: "REAGLED_STORAGE_RAW_RETENTION",
+		Value:  storage.DefaultOptions().Retention.Raw,
+	}
+
+	storageMinuteRetentionFlag = cli.DurationFlag{
+		Name:   "storage_minute_retention",
+		Usage:  "how long to keep 1 minute rollups of readings",
+		EnvVar: "REAGLED_STORAGE_MINUTE_RETENTION",
+		Value:  storage.DefaultOptions().Retention.Minute,
+	}
+
+	storageHourRetentionFlag = cli.DurationFlag{
+		Name:   "storage_hour_retention",
+		Usage:  "how long to keep 1 hour rollups of readings",
+		EnvVar: "REAGLED_STORAGE_HOUR_RETENTION",
+		Value:  storage.DefaultOptions().Retention.Hour,
+	}
+
+	tariffFileFlag = cli.StringFlag{
+		Name:   "tariff_file",
+		Usage:  "json tariff used to calculate energy cost, if not set cost is not calculated",
+		EnvVar: "REAGLED_TARIFF_FILE",
+	}
+
+	billingDayFlag = cli.IntFlag{
+		Name:   "billing_day",
+		Usage:  "day of the month (1-28) billing cycles start on for reports, defaults to the tariff's billing day or 1",
+		EnvVar: "REAGLED_BILLING_DAY",
+	}
+
+	demandWindowsFlag = cli.StringFlag{
+		Name:   "demand_windows",
+		Usage:  "comma separated windows to average demand over, e.g. 15m,30m.  If a tariff has a demand charge it is billed on the first window",
+		EnvVar: "REAGLED_DEMAND_WINDOWS",
+	}
+
+	demandPeakThresholdFlag = cli.Float64Flag{
+		Name:   "demand_peak_threshold",
+		Usage:  "fraction of the month's peak demand the rolling demand has to reach to log that it is approaching the peak, 0 disables",
+		EnvVar: "REAGLED_DEMAND_PEAK_THRESHOLD",
+		Value:  0.9,
+	}
+
+	alertFileFlag = cli.StringFlag{
+		Name:   "alert_file",
+		Usage:  "json alert rules and webhooks, if not set reagled does not alert",
+		EnvVar: "REAGLED_ALERT_FILE",
+	}
+
+	alertMessageIntervalFlag = cli.DurationFlag{
+		Name:   "alert_message_interval",
+		Usage:  "how often to check for a new utility message when there is a new_message alert rule",
+		EnvVar: "REAGLED_ALERT_MESSAGE_INTERVAL",
+		Value:  time.Minute,
+	}
+
+	mqttBrokerFlag = cli.StringFlag{
+		Name:   "mqtt_broker",
+		Usage:  "mqtt broker url such as tcp://localhost:1883, if not set reagled does not publish to mqtt",
+		EnvVar: "REAGLED_MQTT_BROKER",
+	}
+
+	mqttClientIDFlag = cli.StringFlag{
+		Name:   "mqtt_client_id",
+		Usage:  "mqtt client id",
+		EnvVar: "REAGLED_MQTT_CLIENT_ID",
+		Value:  "reagled",
+	}
+
+	mqttUsernameFlag = cli.StringFlag{
+		Name:   "mqtt_username",
+		Usage:  "mqtt broker username",
+		EnvVar: "REAGLED_MQTT_USERNAME",
+	}
+
+	mqttPasswordFlag = cli.StringFlag{
+		Name:   "mqtt_password",
+		Usage:  "mqtt broker password",
+		EnvVar: "REAGLED_MQTT_PASSWORD",
+	}
+
+	mqttQoSFlag = cli.IntFlag{
+		Name:   "mqtt_qos",
+		Usage:  "mqtt qos of published messages, 0, 1 or 2",
+		EnvVar: "REAGLED_MQTT_QOS",
+		Value:  1,
+	}
+
+	mqttTopicPrefixFlag = cli.StringFlag{
+		Name:   "mqtt_topic_prefix",
+		Usage:  "prefix of the topics readings are published to",
+		EnvVar: "REAGLED_MQTT_TOPIC_PREFIX",
+		Value:  "reagle",
+	}
+
+	mqttDiscoveryPrefixFlag = cli.StringFlag{
+		Name:   "mqtt_discovery_prefix",
+		Usage:  "Home Assistant discovery prefix, empty disables discovery",
+		EnvVar: "REAGLED_MQTT_DISCOVERY_PREFIX",
+		Value:  "homeassistant",
+	}
+
+	mqttNodeIDFlag = cli.StringFlag{
+		Name:   "mqtt_node_id",
+		Usage:  "identifies this eagle in Home Assistant, must be unique among the eagles publishing to the broker",
+		EnvVar: "REAGLED_MQTT_NODE_ID",
+		Value:  "eagle",
+	}
+
+	mqttPollIntervalFlag = cli.DurationFlag{
+		Name:   "mqtt_poll_interval",
+		Usage:  "how often to poll the meter variables and device list to publish to mqtt, 0 disables them",
+		EnvVar: "REAGLED_MQTT_POLL_INTERVAL",
+		Value:  time.Minute,
+	}
+
+	influxURLFlag = cli.StringFlag{
+		Name:   "influx_url",
+		Usage:  "InfluxDB v2 url such as http://localhost:8086, if not set reagled does not write to influx",
+		EnvVar: "REAGLED_INFLUX_URL",
+	}
+
+	influxOrgFlag = cli.StringFlag{
+		Name:   "influx_org",
+		Usage:  "InfluxDB organization",
+		EnvVar: "REAGLED_INFLUX_ORG",
+	}
+
+	influxBucketFlag = cli.StringFlag{
+		Name:   "influx_bucket",
+		Usage:  "InfluxDB bucket",
+		EnvVar: "REAGLED_INFLUX_BUCKET",
+		Value:  "reagle",
+	}
+
+	influxTokenFlag = cli.StringFlag{
+		Name:   "influx_token",
+		Usage:  "InfluxDB api token",
+		EnvVar: "REAGLED_INFLUX_TOKEN",
+	}
+
+	influxTagsFlag = cli.StringFlag{
+		Name:   "influx_tags",
+		Usage:  "comma separated key=value tags added to every point, such as site=home",
+		EnvVar: "REAGLED_INFLUX_TAGS",
+	}
+
+	influxBatchSizeFlag = cli.IntFlag{
+		Name:   "influx_batch_size",
+		Usage:  "number of points written to influx at a time",
+		EnvVar: "REAGLED_INFLUX_BATCH_SIZE",
+		Value:  100,
+	}
+
+	influxFlushIntervalFlag = cli.DurationFlag{
+		Name:   "influx_flush_interval",
+		Usage:  "longest a point waits for its batch to fill before it is written",
+		EnvVar: "REAGLED_INFLUX_FLUSH_INTERVAL",
+		Value:  time.Second * 10,
+	}
+
+	influxSpoolDirFlag = cli.StringFlag{
+		Name:   "influx_spool_dir",
+		Usage:  "directory to keep batches that could not be written until influx is back, if not set they are dropped",
+		EnvVar: "REAGLED_INFLUX_SPOOL_DIR",
+	}
+
+	influxMaxSpoolBytesFlag = cli.Int64Flag{
+		Name:   "influx_max_spool_bytes",
+		Usage:  "size past which the oldest spooled batches are dropped, 0 is unlimited",
+		EnvVar: "REAGLED_INFLUX_MAX_SPOOL_BYTES",
+		Value:  100 * 1024 * 1024,
+	}
+
+	influxDeviceIntervalFlag = cli.DurationFlag{
+		Name:   "influx_device_interval",
+		Usage:  "how often to write the device list to influx, 0 disables it",
+		EnvVar: "REAGLED_INFLUX_DEVICE_INTERVAL",
+		Value:  time.Minute,
+	}
+
+	remoteWriteURLFlag = cli.StringFlag{
+		Name:   "remote_write_url",
+		Usage:  "Prometheus remote_write url to push metrics to, for when reagled cannot be scraped.  If not set reagled does not push",
+		EnvVar: "REAGLED_REMOTE_WRITE_URL",
+	}
+
+	remoteWriteUsernameFlag = cli.StringFlag{
+		Name:   "remote_write_username",
+		Usage:  "remote_write basic auth username",
+		EnvVar: "REAGLED_REMOTE_WRITE_USERNAME",
+	}
+
+	remoteWritePasswordFlag = cli.StringFlag{
+		Name:   "remote_write_password",
+		Usage:  "remote_write basic auth password",
+		EnvVar: "REAGLED_REMOTE_WRITE_PASSWORD",
+	}
+
+	remoteWriteBearerTokenFlag = cli.StringFlag{
+		Name:   "remote_write_bearer_token",
+		Usage:  "remote_write bearer token, used instead of basic auth",
+		EnvVar: "REAGLED_REMOTE_WRITE_BEARER_TOKEN",
+	}
+
+	remoteWriteIntervalFlag = cli.DurationFlag{
+		Name:   "remote_write_interval",
+		Usage:  "how often to push metrics",
+		EnvVar: "REAGLED_REMOTE_WRITE_INTERVAL",
+		Value:  time.Second * 30,
+	}
+
+	remoteWriteLabelsFlag = cli.StringFlag{
+		Name:   "remote_write_labels",
+		Usage:  "comma separated key=value labels added to every pushed series, job defaults to reagled and instance to the hostname",
+		EnvVar: "REAGLED_REMOTE_WRITE_LABELS",
+	}
+
+	remoteWriteMaxPendingFlag = cli.IntFlag{
+		Name:   "remote_write_max_pending",
+		Usage:  "how many pushes to buffer while the endpoint is unreachable",
+		EnvVar: "REAGLED_REMOTE_WRITE_MAX_PENDING",
+		Value:  1000,
+	}
+
+	otlpEndpointFlag = cli.StringFlag{
+		Name:   "otlp_endpoint",
+		Usage:  "OpenTelemetry collector OTLP/HTTP url such as http://localhost:4318, if not set reagled does not export to otlp",
+		EnvVar: "REAGLED_OTLP_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT",
+	}
+
+	otlpHeadersFlag = cli.StringFlag{
+		Name:   "otlp_headers",
+		Usage:  "comma separated key=value headers added to every otlp export, such as an api key",
+		EnvVar: "REAGLED_OTLP_HEADERS,OTEL_EXPORTER_OTLP_HEADERS",
+	}
+
+	otlpIntervalFlag = cli.DurationFlag{
+		Name:   "otlp_interval",
+		Usage:  "how often to export metrics to otlp",
+		EnvVar: "REAGLED_OTLP_INTERVAL",
+		Value:  time.Second * 30,
+	}
+
+	uploaderUsernameFlag = cli.StringFlag{
+		Name:   "uploader_username",
+		Usage:  "username the eagle's cloud uploader is configured with",
+		EnvVar: "REAGLED_UPLOADER_USERNAME",
+		Value:  "eagle",
+	}
+
+	uploaderPasswordFlag = cli.StringFlag{
+		Name:   "uploader_password",
+		Usage:  "password the eagle's cloud uploader is configured with, if not set reagled does not accept pushes at " + uploaderPath + ".  The pushes are published instead of polling",
+		EnvVar: "REAGLED_UPLOADER_PASSWORD",
+	}
+
+	postManagerCacheTTLFlag = cli.DurationFlag{
+		Name:   "post_manager_cache_ttl",
+		Usage:  "how long responses to proxied /cgi-bin/post_manager commands are reused, 0 disables the proxy.  Clients use the eagle's credentials",
+		EnvVar: "REAGLED_POST_MANAGER_CACHE_TTL",
+	}
+
+	streamMaxSubscribersFlag = cli.IntFlag{
+		Name:   "stream_max_subscribers",
+		Usage:  "how many clients can subscribe to the /stream of readings and device events at once, 0 disables /stream",
+		EnvVar: "REAGLED_STREAM_MAX_SUBSCRIBERS",
+	}
+
+	streamHistoryFlag = cli.IntFlag{
+		Name:   "stream_history",
+		Usage:  "how many recent events are kept for clients resuming with Last-Event-ID",
+		EnvVar: "REAGLED_STREAM_HISTORY",
+		Value:  100,
+	}
+
+	streamBufferFlag = cli.IntFlag{
+		Name:   "stream_buffer",
+		Usage:  "how many events can be waiting for a slow /stream client before the backpressure policy applies",
+		EnvVar: "REAGLED_STREAM_BUFFER",
+		Value:  16,
+	}
+
+	streamBackpressureFlag = cli.StringFlag{
+		Name:   "stream_backpressure",
+		Usage:  "what happens to a slow /stream client, drop_oldest drops its oldest waiting event and disconnect disconnects it to resume later",
+		EnvVar: "REAGLED_STREAM_BACKPRESSURE",
+		Value:  string(stream.DropOldest),
+	}
+
+	streamHeartbeatFlag = cli.DurationFlag{
+		Name:   "stream_heartbeat",
+		Usage:  "how often a heartbeat is sent to /stream clients so idle connections are not closed",
+		EnvVar: "REAGLED_STREAM_HEARTBEAT",
+		Value:  time.Second * 15,
+	}
+
+	grpcAddressFlag = cli.StringFlag{
+		Name:   "grpc_address",
+		Usage:  "where to serve the grpc api such as :9001 or unix:/run/reagled/grpc.sock, if not set reagled does not serve grpc.  Calls need the auth_file's credentials and are served with the tls certificate, as the http api is",
+		EnvVar: "REAGLED_GRPC_ADDRESS",
+	}
+
+	dashboardFlag = cli.BoolFlag{
+		Name:   "dashboard",
+		Usage:  "serve a dashboard of live demand, today's energy, the devices and wifi at /dashboard",
+		EnvVar: "REAGLED_DASHBOARD",
+	}
+
+	authFileFlag = cli.StringFlag{
+		Name:   "auth_file",
+		Usage:  "json file of the bearer tokens, users and client certificates allowed to use the http api, if not set the api is open",
+		EnvVar: "REAGLED_AUTH_FILE",
+	}
+
+	authOpenMetricsFlag = cli.BoolFlag{
+		Name:   "auth_open_metrics",
+		Usage:  "leave /metrics open when there is an auth_file, for scrapers that can't authenticate",
+		EnvVar: "REAGLED_AUTH_OPEN_METRICS",
+	}
+
+	tlsCertFileFlag = cli.StringFlag{
+		Name:   "tls_cert_file",
+		Usage:  "pem certificate to serve the endpoints with tls, reloaded when it changes, if not set the endpoints are plain http",
+		EnvVar: "REAGLED_TLS_CERT_FILE",
+	}
+
+	tlsKeyFileFlag = cli.StringFlag{
+		Name:   "tls_key_file",
+		Usage:  "pem key of the tls_cert_file, reloaded when it changes",
+		EnvVar: "REAGLED_TLS_KEY_FILE",
+	}
+
+	tlsClientCAFileFlag = cli.StringFlag{
+		Name:   "tls_client_ca_file",
+		Usage:  "pem certificates to verify client certificates with, needed for client_certs in the auth_file",
+		EnvVar: "REAGLED_TLS_CLIENT_CA_FILE",
+	}
+
+	tlsRequireClientCertFlag = cli.BoolFlag{
+		Name:   "tls_require_client_cert",
+		Usage:  "refuse connections without a client certificate verified by tls_client_ca_file",
+		EnvVar: "REAGLED_TLS_REQUIRE_CLIENT_CERT",
+	}
+
+	tlsReloadIntervalFlag = cli.DurationFlag{
+		Name:   "tls_reload_interval",
+		Usage:  "how often to check the tls files for changes, 0 disables reloading",
+		EnvVar: "REAGLED_TLS_RELOAD_INTERVAL",
+		Value:  time.Minute,
+	}
+
+	unixSocketModeFlag = cli.StringFlag{
+		Name:   "unix_socket_mode",
+		Usage:  "octal permissions of unix domain sockets reagled listens on",
+		EnvVar: "REAGLED_UNIX_SOCKET_MODE",
+		Value:  "0660",
+	}
+
+	devicePollIntervalFlag = cli.DurationFlag{
+		Name:   "device_poll_interval",
+		Usage:  "how often to poll the device list for the device metrics, 0 disables device monitoring",
+		EnvVar: "REAGLED_DEVICE_POLL_INTERVAL",
+		Value:  time.Minute,
+	}
+
+	wifiPollIntervalFlag = cli.DurationFlag{
+		Name:   "wifi_poll_interval",
+		Usage:  "how often to poll the wifi status for the wifi metrics, 0 disables wifi monitoring",
+		EnvVar: "REAGLED_WIFI_POLL_INTERVAL",
+		Value:  time.Minute,
+	}
+
 	locationFlag = cli.StringFlag{
 		Name:   "location",
 		Usage:  "eagle address",
@@ -51,6 +448,43 @@ var (
 		EnvVar: local.PasswordEnv,
 	}
 
+	passwordFileFlag = cli.StringFlag{
+		Name:   "password_file",
+		Usage:  "file holding the eagle password, such as a docker or kubernetes secret.  reread when the eagle rejects the password",
+		EnvVar: local.PasswordFileEnv,
+	}
+
+	passwordCommandFlag = cli.StringFlag{
+		Name:   "password_command",
+		Usage:  "command that outputs the eagle password, split on whitespace and not run by a shell.  rerun when the eagle rejects the password",
+		EnvVar: local.PasswordCommandEnv,
+	}
+
+	httpsFlag = cli.BoolFlag{
+		Name:   "https",
+		Usage:  "if set the eagle will be called over https",
+		EnvVar: local.HTTPSEnv,
+	}
+
+	caFileFlag = cli.StringFlag{
+		Name:   "ca_file",
+		Usage:  "pem file of certificate authorities to trust when calling the eagle over https",
+		EnvVar: local.CAFileEnv,
+	}
+
+	insecureSkipVerifyFlag = cli.BoolFlag{
+		Name:   "insecure_skip_verify",
+		Usage:  "if set the eagle's certificate will not be verified when calling it over https",
+		EnvVar: local.InsecureSkipVerifyEnv,
+	}
+
+	maxResponseBytesFlag = cli.Int64Flag{
+		Name:   "max_response_bytes",
+		Usage:  "largest response accepted from the eagle",
+		EnvVar: "REAGLED_MAX_RESPONSE_BYTES",
+		Value:  local.DefaultMaxResponseBytes,
+	}
+
 	modelIDFlag = cli.StringFlag{
 		Name:   "model_id",
 		Usage:  "what the eagle is reporting for your smart meter model id, can be found by hitting the device_list endpoint. Unlikely to need to be set",
@@ -77,12 +511,82 @@ var (
 		EnvVar: local.DebugResponseEnv,
 	}
 
-	flags = []cli.Flag{
+	flags = append([]cli.Flag{
 		addressFlag,
 		waitFlag,
+		pollIntervalFlag,
+		storageDirFlag,
+		storageRawRetentionFlag,
+		storageMinuteRetentionFlag,
+		storageHourRetentionFlag,
+		tariffFileFlag,
+		billingDayFlag,
+		demandWindowsFlag,
+		demandPeakThresholdFlag,
+		alertFileFlag,
+		alertMessageIntervalFlag,
+		mqttBrokerFlag,
+		mqttClientIDFlag,
+		mqttUsernameFlag,
+		mqttPasswordFlag,
+		mqttQoSFlag,
+		mqttTopicPrefixFlag,
+		mqttDiscoveryPrefixFlag,
+		mqttNodeIDFlag,
+		mqttPollIntervalFlag,
+		influxURLFlag,
+		influxOrgFlag,
+		influxBucketFlag,
+		influxTokenFlag,
+		influxTagsFlag,
+		influxBatchSizeFlag,
+		influxFlushIntervalFlag,
+		influxSpoolDirFlag,
+		influxMaxSpoolBytesFlag,
+		influxDeviceIntervalFlag,
+		remoteWriteURLFlag,
+		remoteWriteUsernameFlag,
+		remoteWritePasswordFlag,
+		remoteWriteBearerTokenFlag,
+		remoteWriteIntervalFlag,
+		remoteWriteLabelsFlag,
+		remoteWriteMaxPendingFlag,
+		otlpEndpointFlag,
+		otlpHeadersFlag,
+		otlpIntervalFlag,
+		uploaderUsernameFlag,
+		uploaderPasswordFlag,
+		postManagerCacheTTLFlag,
+		streamMaxSubscribersFlag,
+		streamHistoryFlag,
+		streamBufferFlag,
+		streamBackpressureFlag,
+		streamHeartbeatFlag,
+		grpcAddressFlag,
+		dashboardFlag,
+		authFileFlag,
+		authOpenMetricsFlag,
+		tlsCertFileFlag,
+		tlsKeyFileFlag,
+		tlsClientCAFileFlag,
+		tlsRequireClientCertFlag,
+		tlsReloadIntervalFlag,
+		unixSocketModeFlag,
+		devicePollIntervalFlag,
+		wifiPollIntervalFlag,
+	}, localFlags...)
+
+	//localFlags configure talking to the eagle, they are shared with the doctor command
+	localFlags = []cli.Flag{
 		locationFlag,
 		userFlag,
 		passwordFlag,
+		passwordFileFlag,
+		passwordCommandFlag,
+		httpsFlag,
+		caFileFlag,
+		insecureSkipVerifyFlag,
+		maxResponseBytesFlag,
 		modelIDFlag,
 		improvedFirmwareFlag,
 		debugRequestFlag,
@@ -100,6 +604,14 @@ func main() {
 	app.Usage = "bridge to Rainforest Automation Eagle 200"
 	app.Flags = flags
 	app.Action = start
+	app.Commands = []cli.Command{
+		{
+			Name:   "doctor",
+			Usage:  "check that the eagle can be reached and is configured correctly, printing hints for what is not",
+			Flags:  localFlags,
+			Action: doctor,
+		},
+	}
 
 	err := app.Run(os.Args)
 	if err != nil {
@@ -112,6 +624,20 @@ const (
 	apiErrorCode
 	bridgeErrorCode
 	shutdownErrorCode
+	storageErrorCode
+	tariffErrorCode
+	alertErrorCode
+	mqttErrorCode
+	influxErrorCode
+	remoteWriteErrorCode
+	otlpErrorCode
+	uploaderErrorCode
+	streamErrorCode
+	grpcErrorCode
+	authErrorCode
+	tlsErrorCode
+	listenErrorCode
+	doctorErrorCode
 )
 
 func start(cliCtx *cli.Context) error {
@@ -137,13 +663,309 @@ func start(cliCtx *cli.Context) error {
 
 	c := client.Get(ctx, api, config.Wait)
 
-	_, err = newPrometheusBridge(ctx, prometheus.DefaultRegisterer, c)
+	//the bridge asks the eagle at scrape time so it is only in the registry /metrics serves, not the one pushed from
+	scraped := prometheus.NewRegistry()
+	_, err = newPrometheusBridge(ctx, scraped, c)
 	if err != nil {
 		err = fmt.Errorf("error creating prometheus bridge: %v", err)
 		return cli.NewExitError(err, bridgeErrorCode)
 	}
 
-	srv := startServer(config, c)
+	var hub *stream.Hub
+	var deviceListeners []func(deviceEvent)
+	if config.Stream.MaxSubscribers > 0 {
+		hub, err = newStream(ctx, prometheus.DefaultRegisterer, config.Stream)
+		if err != nil {
+			err = fmt.Errorf("error creating stream: %v", err)
+			return cli.NewExitError(err, streamErrorCode)
+		}
+
+		deviceListeners = append(deviceListeners, streamDevices(hub))
+	}
+
+	if config.DevicePollInterval > 0 {
+		_, err = newDeviceMonitor(ctx, prometheus.DefaultRegisterer, c, config.DevicePollInterval, deviceListeners...)
+		if err != nil {
+			err = fmt.Errorf("error creating device monitor: %v", err)
+			return cli.NewExitError(err, bridgeErrorCode)
+		}
+	}
+
+	if config.WifiPollInterval > 0 {
+		_, err = newWifiMonitor(ctx, prometheus.DefaultRegisterer, c, config.WifiPollInterval)
+		if err != nil {
+			err = fmt.Errorf("error creating wifi monitor: %v", err)
+			return cli.NewExitError(err, bridgeErrorCode)
+		}
+	}
+
+	poller := client.NewPoller(c, config.PollInterval)
+	polling := false
+	background := &runners{}
+	var extraRoutes []routes
+
+	//the other sinks are seeded from storage so opening it comes first
+	var store *storage.Store
+	if config.StorageDir != "" {
+		store, err = storage.Open(config.StorageDir, config.Storage)
+		if err != nil {
+			err = fmt.Errorf("error opening storage: %v", err)
+			return cli.NewExitError(err, storageErrorCode)
+		}
+		defer store.Close()
+	}
+
+	var rates *tariff.Tariff
+	var costs *tariff.Calculator
+	if config.TariffFile != "" {
+		t, err := tariff.Load(config.TariffFile)
+		if err != nil {
+			err = fmt.Errorf("error loading tariff: %v", err)
+			return cli.NewExitError(err, tariffErrorCode)
+		}
+
+		rates = &t
+		costs = tariff.NewCalculator(t)
+		if store != nil {
+			var window time.Duration
+			if len(config.DemandWindows) > 0 {
+				window = config.DemandWindows[0]
+			}
+
+			err = seedCosts(store, costs, window, time.Now())
+			if err != nil {
+				err = fmt.Errorf("error reading billing period totals from storage: %v", err)
+				return cli.NewExitError(err, storageErrorCode)
+			}
+		}
+
+		_, err = newCostCollector(prometheus.DefaultRegisterer, costs)
+		if err != nil {
+			err = fmt.Errorf("error creating cost collector: %v", err)
+			return cli.NewExitError(err, bridgeErrorCode)
+		}
+
+		//the demand charge is on the demand window when there is one, otherwise on instantaneous demand
+		poller.Add(tariffSink(costs, len(config.DemandWindows) == 0))
+		polling = true
+	}
+
+	//days and months are in the utility's time zone
+	loc := time.Local
+	if rates != nil {
+		loc = rates.Location()
+	}
+
+	if len(config.DemandWindows) > 0 {
+		var calcs []*demand.Calculator
+		for _, window := range config.DemandWindows {
+			calcs = append(calcs, demand.NewCalculator(window, loc, config.DemandPeakThreshold))
+		}
+
+		if store != nil {
+			err = seedDemand(store, calcs, loc, time.Now())
+			if err != nil {
+				err = fmt.Errorf("error reading demand peaks from storage: %v", err)
+				return cli.NewExitError(err, storageErrorCode)
+			}
+		}
+
+		_, err = newDemandMonitor(prometheus.DefaultRegisterer, calcs)
+		if err != nil {
+			err = fmt.Errorf("error creating demand monitor: %v", err)
+			return cli.NewExitError(err, bridgeErrorCode)
+		}
+
+		poller.Add(demandSink(calcs, billedDemand(costs, config.DemandWindows[0])))
+		polling = true
+	}
+
+	if config.AlertFile != "" {
+		alerts, err := alert.Load(config.AlertFile)
+		if err != nil {
+			err = fmt.Errorf("error loading alert rules: %v", err)
+			return cli.NewExitError(err, alertErrorCode)
+		}
+
+		monitor, err := newAlertMonitor(ctx, background, prometheus.DefaultRegisterer, c, alerts, loc, poller, config.PollInterval, config.AlertMessageInterval)
+		if err != nil {
+			err = fmt.Errorf("error creating alert monitor: %v", err)
+			return cli.NewExitError(err, alertErrorCode)
+		}
+
+		if store != nil {
+			err = seedDayUsage(store, monitor.engine, loc, time.Now())
+			if err != nil {
+				err = fmt.Errorf("error reading today's usage from storage: %v", err)
+				return cli.NewExitError(err, storageErrorCode)
+			}
+		}
+
+		polling = true
+	}
+
+	if config.MQTT.Broker != "" {
+		bridge, err := newMQTTBridge(ctx, background, c, config.MQTT, poller, config.MQTTPollInterval)
+		if err != nil {
+			err = fmt.Errorf("error creating mqtt publisher: %v", err)
+			return cli.NewExitError(err, mqttErrorCode)
+		}
+		defer bridge.Close()
+
+		polling = true
+	}
+
+	if config.Influx.URL != "" {
+		_, err = newInfluxExporter(ctx, background, prometheus.DefaultRegisterer, c, config.Influx, poller, config.InfluxDeviceInterval)
+		if err != nil {
+			err = fmt.Errorf("error creating influx exporter: %v", err)
+			return cli.NewExitError(err, influxErrorCode)
+		}
+
+		polling = true
+	}
+
+	var pushed prometheus.Gatherer
+	if config.RemoteWrite.URL != "" || config.OTLP.Endpoint != "" {
+		pushed, err = pushGatherer(ctx, prometheus.DefaultGatherer, poller)
+		if err != nil {
+			err = fmt.Errorf("error creating pushed metrics: %v", err)
+			return cli.NewExitError(err, bridgeErrorCode)
+		}
+	}
+
+	if config.RemoteWrite.URL != "" {
+		_, err = newRemoteWriter(ctx, background, prometheus.DefaultRegisterer, pushed, config.RemoteWrite)
+		if err != nil {
+			err = fmt.Errorf("error creating remote writer: %v", err)
+			return cli.NewExitError(err, remoteWriteErrorCode)
+		}
+
+		polling = true
+	}
+
+	if config.OTLP.Endpoint != "" {
+		_, err = newOTLPExporter(ctx, c, pushed, config.OTLP, config.LocalConfig.Location)
+		if err != nil {
+			err = fmt.Errorf("error creating otlp exporter: %v", err)
+			return cli.NewExitError(err, otlpErrorCode)
+		}
+
+		polling = true
+	}
+
+	if config.Uploader.Password != "" {
+		push, err := uploaderRoutes(config.Uploader, poller)
+		if err != nil {
+			err = fmt.Errorf("error creating uploader receiver: %v", err)
+			return cli.NewExitError(err, uploaderErrorCode)
+		}
+
+		extraRoutes = append(extraRoutes, push)
+	}
+
+	if hub != nil {
+		poller.Add(streamSink(hub))
+		polling = true
+		extraRoutes = append(extraRoutes, streamRoutes(hub))
+	}
+
+	if config.PostManagerCacheTTL > 0 {
+		cache := client.NewCache(ctx, c, config.PostManagerCacheTTL)
+		cache.Retry = config.Wait
+
+		extraRoutes = append(extraRoutes, postManagerRoutes(config.LocalConfig, cache))
+	}
+
+	if config.Dashboard {
+		today := dashboard.NewToday(loc)
+		if store != nil {
+			err = seedToday(store, today, costs, loc, time.Now())
+			if err != nil {
+				err = fmt.Errorf("error reading today's energy from storage: %v", err)
+				return cli.NewExitError(err, storageErrorCode)
+			}
+		}
+
+		poller.Add(todaySink(today, costs))
+		polling = true
+		extraRoutes = append(extraRoutes, dashboardRoutes(today))
+	}
+
+	if store != nil {
+		poller.Add(storageSink(store))
+		polling = true
+		extraRoutes = append(extraRoutes, historyRoutes(store, loc))
+
+		tracker, err := newBillingTracker(store, config.BillingDay, rates)
+		if err != nil {
+			err = fmt.Errorf("error creating billing tracker: %v", err)
+			return cli.NewExitError(err, configureErrorCode)
+		}
+
+		extraRoutes = append(extraRoutes, reportRoutes(tracker))
+	}
+
+	handler := endpoint(c, prometheus.Gatherers{prometheus.DefaultGatherer, scraped}, extraRoutes...)
+	var access *auth.Middleware
+	if config.AuthFile != "" {
+		credentials, err := auth.Load(config.AuthFile)
+		if err != nil {
+			err = fmt.Errorf("error loading auth file: %v", err)
+			return cli.NewExitError(err, authErrorCode)
+		}
+
+		if len(credentials.ClientCerts) > 0 && config.TLS.ClientCAFile == "" {
+			err = fmt.Errorf("the auth file has client_certs but there is no tls_client_ca_file to verify them")
+			return cli.NewExitError(err, authErrorCode)
+		}
+
+		m := authMiddleware(credentials, config.AuthOpenMetrics)
+		access = &m
+		handler = m.Handler(handler)
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS.CertFile != "" || config.TLS.KeyFile != "" {
+		tlsConfig, err = newTLS(ctx, prometheus.DefaultRegisterer, config.TLS, config.TLSReloadInterval)
+		if err != nil {
+			err = fmt.Errorf("error loading tls certificate: %v", err)
+			return cli.NewExitError(err, tlsErrorCode)
+		}
+	}
+
+	if config.GRPCAddress != "" {
+		grpcSrv, err := startGRPC(ctx, config.GRPCAddress, config.UnixSocketMode, c, poller, access, tlsConfig)
+		if err != nil {
+			err = fmt.Errorf("error serving grpc: %v", err)
+			return cli.NewExitError(err, grpcErrorCode)
+		}
+		//watches end when the context is done so this does not wait on them
+		defer grpcSrv.GracefulStop()
+
+		polling = true
+	}
+
+	if polling && config.PollInterval > 0 {
+		//sinks such as storage are closed by earlier defers, so the poller has to be stopped before any of them run
+		pollCtx, stopPolling := context.WithCancel(ctx)
+		pollerDone := make(chan struct{})
+		go func() {
+			defer close(pollerDone)
+			poller.Run(pollCtx)
+		}()
+
+		defer func() {
+			stopPolling()
+			<-pollerDone
+		}()
+	}
+
+	srv, err := startServer(config, handler, tlsConfig)
+	if err != nil {
+		err = fmt.Errorf("error listening on %s: %v", config.Address, err)
+		return cli.NewExitError(err, listenErrorCode)
+	}
 
 	applicationLogger.Infoln("started")
 
@@ -153,6 +975,10 @@ func start(cliCtx *cli.Context) error {
 	defer clean()
 
 	err = srv.Shutdown(shutdownCtx)
+
+	//the exporters spool or send what they have once the context is done
+	background.wait()
+
 	if err != nil {
 		err = fmt.Errorf("error shutting down web server: %v", err)
 		return cli.NewExitError(err, shutdownErrorCode)
@@ -162,16 +988,28 @@ func start(cliCtx *cli.Context) error {
 	return nil
 }
 
-func startServer(config Config, c client.Local) *http.Server {
-	srv := &http.Server{Addr: config.Address, Handler: endpoint(c)}
+//startServer serves tls when there is a tls config, otherwise plain http
+func startServer(config Config, handler http.Handler, tlsConfig *tls.Config) (*http.Server, error) {
+	listener, err := listen(config.Address, config.UnixSocketMode)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Addr: config.Address, Handler: handler, TLSConfig: tlsConfig}
 	go func() {
-		err := srv.ListenAndServe()
+		var err error
+		if tlsConfig != nil {
+			err = srv.ServeTLS(listener, "", "")
+		} else {
+			err = srv.Serve(listener)
+		}
+
 		if err != http.ErrServerClosed {
 			applicationLogger.WithFields(log.Fields{"err": err}).Fatalln("failed at serving")
 		}
 	}()
 
-	return srv
+	return srv, nil
 }
 
 func setSignalCancel(ctx context.Context, sig ...os.Signal) context.Context {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/mqtt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var mqttMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "mqtt_messages_total",
+	Help: "Count of mqtt messages by whether they were published, dropped because the queue was full or failed",
+},
+	[]string{"outcome"},
+)
+
+//mqttBridge publishes the poller's samples, and polls the variables and device list on its own interval
+type mqttBridge struct {
+	c         client.Local
+	publisher *mqtt.Publisher
+}
+
+//newMQTTBridge starts publishing whether or not the broker can be reached, the publisher keeps trying to connect
+func newMQTTBridge(ctx context.Context, background *runners, c client.Local, config mqtt.Config, poller *client.Poller, interval time.Duration) (*mqttBridge, error) {
+	publisher, err := mqtt.NewPublisher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher.Result = mqttResult
+	for _, outcome := range []string{mqtt.Published, mqtt.Dropped, mqtt.Failed} {
+		mqttMessages.WithLabelValues(outcome).Add(0)
+	}
+
+	background.run(ctx, publisher.Run)
+
+	bridge := &mqttBridge{c: c, publisher: publisher}
+	poller.Add(bridge.sample)
+
+	if interval > 0 {
+		go pollEvery(ctx, interval, bridge.poll)
+	}
+
+	return bridge, nil
+}
+
+func mqttResult(outcome string, topic string, err error) {
+	if topic != "" {
+		mqttMessages.WithLabelValues(outcome).Inc()
+	}
+
+	//a dropped message is counted, it is not worth a log line per message while the broker is away
+	if err != nil && outcome == mqtt.Failed {
+		applicationLogger.WithFields(log.Fields{"topic": topic, "err": err}).Errorln("unable to publish to mqtt")
+	}
+}
+
+//Close is called once the publisher has stopped
+func (b *mqttBridge) Close() error {
+	return b.publisher.Close()
+}
+
+func (b *mqttBridge) sample(ctx context.Context, sample client.Sample) {
+	err := b.publisher.PublishSample(sample)
+	mqttError(err, "unable to publish sample to mqtt")
+}
+
+func (b *mqttBridge) poll(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	variables, err := b.c.Request(timeout, client.RequestAllVariables())
+	if err != nil {
+		instrumentError(err, "unable to get variables for mqtt")
+	} else {
+		err = b.publisher.PublishVariables(variables.(map[string]local.Readings))
+		mqttError(err, "unable to publish variables to mqtt")
+	}
+
+	devices, err := b.c.Request(timeout, client.RequestDeviceList())
+	if err != nil {
+		instrumentError(err, "unable to get device list for mqtt")
+		return
+	}
+
+	err = b.publisher.PublishDevices(devices.([]local.Device))
+	mqttError(err, "unable to publish devices to mqtt")
+}
+
+//mqttError is what could not be queued, a full queue is already counted by mqttResult
+func mqttError(err error, desc string) {
+	if err != mqtt.ErrQueueFull {
+		instrumentError(err, desc)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/otlp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var otlpExports = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "otlp_exports_total",
+	Help: "Count of otlp exports by whether they succeeded",
+},
+	[]string{"result"},
+)
+
+//otlpExporter exports everything on the registry, with the meter it is reading as the resource
+type otlpExporter struct {
+	c        client.Local
+	gatherer prometheus.Gatherer
+	exporter *otlp.Exporter
+
+	//the meter attributes are filled in by the first successful meter details request
+	resource otlp.Resource
+}
+
+func newOTLPExporter(ctx context.Context, c client.Local, gatherer prometheus.Gatherer, config otlp.Config, gateway string) (*otlpExporter, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("otlp interval must be positive, not %v", config.Interval)
+	}
+
+	transport, err := instrumentClient("otlp", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := otlp.NewExporter(&http.Client{Transport: transport}, config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range []string{"success", "failure"} {
+		otlpExports.WithLabelValues(result).Add(0)
+	}
+
+	e := &otlpExporter{
+		c:        c,
+		gatherer: gatherer,
+		exporter: exporter,
+		resource: otlp.Resource{
+			otlp.ServiceNameAttribute: "reagled",
+			otlp.GatewayAttribute:     gateway,
+		},
+	}
+
+	go pollEvery(ctx, config.Interval, e.export)
+	return e, nil
+}
+
+func (e *otlpExporter) export(ctx context.Context) {
+	//the resource identifies the series in the collector so nothing is exported until the meter is known
+	if e.resource[otlp.MeterAddressAttribute] == "" {
+		err := e.describeMeter(ctx)
+		if err != nil {
+			instrumentError(err, "unable to get meter details for otlp")
+			return
+		}
+	}
+
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		//a failing collector does not stop the others from being exported
+		instrumentError(err, "unable to gather metrics for otlp")
+	}
+
+	err = e.exporter.Export(ctx, e.resource, families, time.Now())
+	if err != nil {
+		otlpExports.WithLabelValues("failure").Inc()
+		instrumentError(err, "unable to export metrics to otlp")
+		return
+	}
+
+	otlpExports.WithLabelValues("success").Inc()
+}
+
+func (e *otlpExporter) describeMeter(ctx context.Context) error {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	response, err := e.c.Request(timeout, client.RequestMeterDetails())
+	if err != nil {
+		return err
+	}
+
+	details := response.(local.DeviceDetailsResponse).DeviceDetails
+	if details.HardwareAddress == "" {
+		return fmt.Errorf("meter details have no hardware address")
+	}
+
+	e.resource[otlp.MeterAddressAttribute] = details.HardwareAddress
+	e.resource[otlp.MeterModelAttribute] = details.ModelID
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//runners are the background goroutines that have something to finish once the context is done, such as spooling
+//the batch being written, that start waits for before exiting
+type runners struct {
+	wg sync.WaitGroup
+}
+
+//run calls f on its own goroutine
+func (r *runners) run(ctx context.Context, f func(context.Context)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		f(ctx)
+	}()
+}
+
+//wait returns once every f has returned
+func (r *runners) wait() {
+	r.wg.Wait()
+}
+
+//pollEvery calls poll immediately and then every interval until the context is done
+func pollEvery(ctx context.Context, interval time.Duration, poll func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		poll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnersWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	background := &runners{}
+
+	spooled := false
+	background.run(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		//as a writer spooling its batch
+		time.Sleep(10 * time.Millisecond)
+		spooled = true
+	})
+
+	cancel()
+	background.wait()
+	assert.True(t, spooled, "wait returns after the runner has finished")
+}
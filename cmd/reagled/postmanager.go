@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+//postManagerPath is the eagle's own path, so clients only need reagled's address in place of the eagle's
+const postManagerPath = "/cgi-bin/post_manager"
+
+//maxCommandBytes is far more than any command, it only stops a misbehaving client
+const maxCommandBytes = 64 * 1024
+
+var postManagerRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "post_manager_requests_total",
+	Help: "Count of proxied post_manager commands by command and whether they were cached, forwarded, stale or failed",
+},
+	[]string{"command", "outcome"},
+)
+
+//postManagerRoutes stands in for the eagle's post_manager so other clients can share reagled's access to it.  They
+//use the eagle's credentials and get its responses unchanged.
+func postManagerRoutes(config local.Config, cache *client.Cache) routes {
+	for _, command := range local.ProxiedCommands {
+		for _, outcome := range []string{client.CacheHit, client.CacheMiss, client.CacheStale, "error"} {
+			postManagerRequests.WithLabelValues(command, outcome).Add(0)
+		}
+	}
+
+	return func(router *httprouter.Router) {
+		router.Handler("POST", postManagerPath, instrumentHandler("post_manager", postManagerHandler(config, cache)))
+	}
+}
+
+func postManagerHandler(config local.Config, cache *client.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || !local.Authenticate(config, user, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="reagled"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxCommandBytes))
+		if err != nil {
+			writeError(w, fmt.Errorf("unable to read command: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		command, err := local.ParseProxiedCommand(body)
+		if err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		response, outcome, err := cache.Post(r.Context(), command)
+		if err != nil {
+			postManagerRequests.WithLabelValues(command.Name, "error").Inc()
+			writeError(w, err, statusForError(err))
+			return
+		}
+
+		postManagerRequests.WithLabelValues(command.Name, outcome).Inc()
+
+		contentType := "text/xml"
+		if command.Format == "json" {
+			contentType = "application/json"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Cache", outcome)
+		w.Write(response)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostManagerProxy(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	eagle, config := local.StartTestServer(local.ServeWifiStatus(local.WifiStatus{SSID: "ssid"}))
+	defer eagle.Close()
+
+	config.User = "cloudid"
+	config = local.SetPassword(config, "installcode")
+
+	router := httprouter.New()
+	postManagerRoutes(config, client.NewCache(ctx, client.NewDangerous(ctx, local.New(config), time.Millisecond), time.Minute))(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(user string, password string, command string) (*http.Response, string) {
+		req, err := http.NewRequest("POST", ts.URL+"/cgi-bin/post_manager", strings.NewReader(command))
+		require.NoError(t, err)
+		req.SetBasicAuth(user, password)
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, _ := post("cloudid", "wrong", "<Command><Name>wifi_status</Name></Command>")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, _ = post("cloudid", "installcode", "<Command><Name>set_setting</Name></Command>")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body := post("cloudid", "installcode", "<Command><Name>wifi_status</Name></Command>")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, client.CacheMiss, resp.Header.Get("X-Cache"))
+	assert.Contains(t, body, "<SSID>ssid</SSID>")
+
+	resp, cached := post("cloudid", "installcode", "<Command>\n  <Name>wifi_status</Name>\n</Command>")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, client.CacheHit, resp.Header.Get("X-Cache"))
+	assert.Equal(t, body, cached)
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/kklipsch/reagle/client"
 	"github.com/kklipsch/reagle/local"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -17,7 +17,7 @@ var (
 		Name: "errors",
 		Help: "Count of non-fatal errors",
 	},
-		[]string{"type"},
+		[]string{"kind"},
 	)
 
 	requestsInFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
@@ -62,8 +62,9 @@ var (
 )
 
 func initializeErrorCounts() {
-	errorsCount.WithLabelValues("context").Inc()
-	errorsCount.WithLabelValues("other").Inc()
+	for _, kind := range client.ErrorKinds {
+		errorsCount.WithLabelValues(kind).Add(0)
+	}
 }
 
 func instrumentError(err error, desc string) {
@@ -71,11 +72,10 @@ func instrumentError(err error, desc string) {
 		return
 	}
 
-	if err == context.DeadlineExceeded {
-		errorsCount.WithLabelValues("context").Inc()
-	} else {
-		errorsCount.WithLabelValues("other").Inc()
-		applicationLogger.WithFields(log.Fields{"err": err}).Errorln(desc)
+	kind := client.ErrorKind(err)
+	errorsCount.WithLabelValues(kind).Inc()
+	if kind != "context" {
+		applicationLogger.WithFields(log.Fields{"err": err, "kind": kind}).Errorln(desc)
 	}
 }
 
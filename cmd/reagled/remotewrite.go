@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/kklipsch/reagle/remotewrite"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	remoteWritePending = prometheus.NewDesc("remote_write_pending_batches", "Number of batches waiting to be pushed", nil, nil)
+
+	remoteWriteSamples = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "remote_write_samples_total",
+		Help: "Count of samples by whether they were sent or dropped, and of failed pushes that will be retried",
+	},
+		[]string{"outcome"},
+	)
+)
+
+//remoteWriter pushes everything on the registry, the same metrics a scrape of /metrics would get
+type remoteWriter struct {
+	pusher *remotewrite.Pusher
+}
+
+func newRemoteWriter(ctx context.Context, background *runners, reg prometheus.Registerer, gatherer prometheus.Gatherer, config remotewrite.Config) (*remoteWriter, error) {
+	transport, err := instrumentClient("remote_write", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pusher, err := remotewrite.NewPusher(&http.Client{Transport: transport}, gatherer, config)
+	if err != nil {
+		return nil, err
+	}
+
+	pusher.Result = remoteWriteResult
+	for _, outcome := range []string{remotewrite.Sent, remotewrite.Dropped, remotewrite.Failed} {
+		remoteWriteSamples.WithLabelValues(outcome).Add(0)
+	}
+
+	writer := &remoteWriter{pusher: pusher}
+	err = reg.Register(writer)
+	if err != nil {
+		return writer, err
+	}
+
+	background.run(ctx, pusher.Run)
+	return writer, nil
+}
+
+//remoteWriteLabels are the labels a scrape would have added, unless they are configured
+func remoteWriteLabels(labels map[string]string) map[string]string {
+	if _, ok := labels["job"]; !ok {
+		labels["job"] = "reagled"
+	}
+
+	if _, ok := labels["instance"]; !ok {
+		if hostname, err := os.Hostname(); err == nil {
+			labels["instance"] = hostname
+		}
+	}
+
+	return labels
+}
+
+func remoteWriteResult(outcome string, samples int, err error) {
+	remoteWriteSamples.WithLabelValues(outcome).Add(float64(samples))
+
+	if err != nil {
+		applicationLogger.WithFields(log.Fields{"outcome": outcome, "samples": samples, "err": err}).Errorln("unable to push metrics")
+	}
+}
+
+func (w *remoteWriter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- remoteWritePending
+}
+
+func (w *remoteWriter) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(remoteWritePending, prometheus.GaugeValue, float64(w.pusher.Pending()))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/billing"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+)
+
+//newBillingTracker uses the tariff's billing day and time zone unless billingDay overrides it
+func newBillingTracker(store *storage.Store, billingDay int, rates *tariff.Tariff) (*billing.Tracker, error) {
+	loc := time.Local
+	if rates != nil {
+		loc = rates.Location()
+		if billingDay == 0 {
+			billingDay = rates.BillingDay
+		}
+	}
+
+	if billingDay == 0 {
+		billingDay = 1
+	}
+
+	return billing.NewTracker(store, billingDay, loc, rates)
+}
+
+func reportRoutes(tracker *billing.Tracker) routes {
+	return func(router *httprouter.Router) {
+		router.Handler("GET", "/reports/billing", instrumentHandler("reports_billing", billingHandler(tracker)))
+	}
+}
+
+func billingHandler(tracker *billing.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		at, err := parseTimeParam(r.URL.Query().Get("at"), time.Now())
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid at: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		report, err := tracker.Report(at)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		switch {
+		case wantsCSV(r):
+			w.Header().Set("Content-Type", "text/csv")
+			err = billing.WriteCSV(w, report)
+		case wantsMarkdown(r):
+			w.Header().Set("Content-Type", "text/markdown")
+			err = billing.WriteMarkdown(w, report)
+		default:
+			jsonResponse(w, report)
+		}
+
+		instrumentError(err, "unable to write billing report")
+	}
+}
+
+func wantsMarkdown(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "markdown" || strings.Contains(r.Header.Get("Accept"), "text/markdown")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/storage"
+)
+
+//storageSink appends every polled sample to the store
+func storageSink(store *storage.Store) client.Sink {
+	return func(ctx context.Context, sample client.Sample) {
+		err := store.Append(pointFromSample(sample))
+		instrumentError(err, "unable to store sample")
+	}
+}
+
+func pointFromSample(sample client.Sample) storage.Point {
+	return storage.Point{
+		Time:      sample.Time,
+		Demand:    sample.Metrics.Demand,
+		Delivered: sample.Metrics.Delivered,
+		Received:  sample.Metrics.Received,
+		Price:     sample.Metrics.Price,
+		Currency:  sample.Metrics.Currency,
+	}
+}
+
+//history is the stored aggregates of a range at the finest resolution retained, hours until the hour the minutes
+//start in and minutes from then on
+type history struct {
+	hours   []storage.Aggregate
+	minutes []storage.Aggregate
+	//cutoff is where the hours end and minutes begin
+	cutoff time.Time
+}
+
+func storedHistory(store *storage.Store, start time.Time, end time.Time) (history, error) {
+	minutes, err := store.Query(storage.Minute, start, end)
+	if err != nil {
+		return history{}, err
+	}
+
+	cutoff := end
+	if len(minutes) > 0 {
+		cutoff = minutes[0].Start.Truncate(time.Hour)
+	}
+
+	hours, err := store.Query(storage.Hour, start, cutoff)
+	if err != nil {
+		return history{}, err
+	}
+
+	return history{hours: hours, minutes: minutes, cutoff: cutoff}, nil
+}
+
+//all is the hours followed by the minutes
+func (h history) all() []storage.Aggregate {
+	all := make([]storage.Aggregate, 0, len(h.hours)+len(h.minutes))
+	return append(append(all, h.hours...), h.minutes...)
+}
+
+//delivered is the energy delivered over the whole range
+func (h history) delivered() float64 {
+	kwh := 0.0
+	for _, a := range h.all() {
+		kwh += a.DeliveredDelta
+	}
+
+	return kwh
+}
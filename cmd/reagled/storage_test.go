@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//testStore opens a store in a temporary directory with the points appended, the returned func closes and removes it
+func testStore(t *testing.T, points ...storage.Point) (*storage.Store, func()) {
+	dir, err := ioutil.TempDir("", "reagled-storage")
+	require.NoError(t, err)
+
+	store, err := storage.Open(dir, storage.DefaultOptions())
+	require.NoError(t, err)
+
+	for _, p := range points {
+		require.NoError(t, store.Append(p))
+	}
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+//minuteReadings are points every minute from start at the demands (kW), each with the energy of the minute before it.
+//The first reading is a minute before start so the minute starting at start has the first demand's energy
+func minuteReadings(start time.Time, demands ...float64) []storage.Point {
+	points := []storage.Point{{Time: start.Add(-time.Minute)}}
+	delivered := 0.0
+	for i, demand := range demands {
+		delivered += demand / 60
+		points = append(points, storage.Point{Time: start.Add(time.Minute * time.Duration(i)), Demand: demand, Delivered: delivered})
+	}
+
+	return points
+}
+
+func TestStoredHistory(t *testing.T) {
+	start := time.Date(2018, 10, 15, 10, 30, 0, 0, time.UTC)
+	store, clean := testStore(t, minuteReadings(start, 1, 2)...)
+	defer clean()
+
+	stored, err := storedHistory(store, start.Add(-time.Hour*24), start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stored.hours, "the minutes cover the whole hour they start in")
+	assert.Len(t, stored.minutes, 3)
+	assert.Equal(t, start.Truncate(time.Hour), stored.cutoff)
+
+	stored, err = storedHistory(store, start.Add(time.Hour), start.Add(time.Hour*2))
+	require.NoError(t, err)
+	assert.Empty(t, stored.minutes)
+	assert.Equal(t, start.Add(time.Hour*2), stored.cutoff)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/kklipsch/reagle/stream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+//stream event types
+const (
+	streamReadingEvent = "reading"
+	streamDeviceEvent  = "device"
+)
+
+var (
+	streamEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stream_events_total",
+		Help: "Count of events published to /stream by type",
+	},
+		[]string{"type"},
+	)
+
+	streamBackpressure = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stream_backpressure_total",
+		Help: "Count of times a /stream subscriber fell behind by the policy applied, dropping its oldest event or disconnecting it",
+	},
+		[]string{"policy"},
+	)
+)
+
+//streamDevice is a device event as subscribers see it
+type streamDevice struct {
+	Event            string                 `json:"event"`
+	HardwareAddress  string                 `json:"hardware_address"`
+	ModelID          string                 `json:"model_id"`
+	Manufacturer     string                 `json:"manufacturer"`
+	ConnectionStatus local.ConnectionStatus `json:"connection_status"`
+	PreviousStatus   local.ConnectionStatus `json:"previous_status,omitempty"`
+}
+
+//newStream creates the hub behind /stream, subscribers are disconnected when the context is done so they do not hold
+//up shutdown
+func newStream(ctx context.Context, reg prometheus.Registerer, config stream.Config) (*stream.Hub, error) {
+	hub, err := stream.NewHub(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hub.Dropped = func(policy stream.Policy) {
+		streamBackpressure.WithLabelValues(string(policy)).Inc()
+	}
+
+	for _, typ := range []string{streamReadingEvent, streamDeviceEvent} {
+		streamEvents.WithLabelValues(typ).Add(0)
+	}
+
+	err = reg.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "stream_subscribers",
+		Help: "Number of clients subscribed to /stream",
+	}, func() float64 { return float64(hub.Subscribers()) }))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		hub.Close()
+	}()
+
+	return hub, nil
+}
+
+func streamRoutes(hub *stream.Hub) routes {
+	return func(router *httprouter.Router) {
+		router.Handler("GET", "/stream", instrumentHandler("stream", hub))
+	}
+}
+
+//streamSink publishes every reading the poller publishes
+func streamSink(hub *stream.Hub) client.Sink {
+	return func(ctx context.Context, sample client.Sample) {
+		publishStream(hub, streamReadingEvent, sample)
+	}
+}
+
+//streamDevices publishes device monitor events
+func streamDevices(hub *stream.Hub) func(deviceEvent) {
+	return func(event deviceEvent) {
+		publishStream(hub, streamDeviceEvent, streamDevice{
+			Event:            event.event,
+			HardwareAddress:  event.device.HardwareAddress,
+			ModelID:          event.device.ModelID,
+			Manufacturer:     event.device.Manufacturer,
+			ConnectionStatus: event.device.ConnectionStatus,
+			PreviousStatus:   event.previous,
+		})
+	}
+}
+
+func publishStream(hub *stream.Hub, typ string, data interface{}) {
+	err := hub.Publish(typ, data)
+	if err != nil {
+		//closed at shutdown is expected
+		if err != stream.ErrClosed {
+			instrumentError(err, "unable to publish to stream")
+		}
+		return
+	}
+
+	streamEvents.WithLabelValues(typ).Inc()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/demand"
+	"github.com/kklipsch/reagle/storage"
+	"github.com/kklipsch/reagle/tariff"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	energyCost  = prometheus.NewDesc("energy_cost_total", "cost of the energy delivered since startup, calculated from the tariff", []string{"currency"}, nil)
+	currentRate = prometheus.NewDesc("current_rate", "the tariff's per kWh rate for the next kWh delivered", []string{"currency"}, nil)
+
+	billingPeriodCost   = prometheus.NewDesc("billing_period_cost", "energy and demand cost of the current billing period so far", []string{"currency"}, nil)
+	billingPeriodEnergy = prometheus.NewDesc("billing_period_energy_kwh", "energy delivered in the current billing period", nil, nil)
+	billingPeriodPeak   = prometheus.NewDesc("billing_period_peak_demand_kw", "peak demand of the current billing period", nil, nil)
+)
+
+//costCollector exports the calculator's totals, the meter's own price is still exported by the bridge
+type costCollector struct {
+	calc *tariff.Calculator
+}
+
+func newCostCollector(reg prometheus.Registerer, calc *tariff.Calculator) (*costCollector, error) {
+	collector := &costCollector{calc: calc}
+	return collector, reg.Register(collector)
+}
+
+//tariffSink feeds every polled sample to the calculator, with the instantaneous demand for the demand charge if
+//instantaneous is set
+func tariffSink(calc *tariff.Calculator, instantaneous bool) client.Sink {
+	return func(ctx context.Context, sample client.Sample) {
+		kw := 0.0
+		if instantaneous {
+			kw = sample.Metrics.Demand
+		}
+
+		calc.Add(sample.Time, sample.Metrics.Delivered, kw)
+	}
+}
+
+//seedCosts sets the calculator's billing period totals from storage, so a restart does not lose them.  The demand charge
+//is seeded from the blocks of window, or the instantaneous demand the meter reported if window is 0
+func seedCosts(store *storage.Store, calc *tariff.Calculator, window time.Duration, now time.Time) error {
+	rates := calc.Tariff()
+	stored, err := storedHistory(store, rates.BillingPeriodStart(now), now)
+	if err != nil {
+		return err
+	}
+
+	calc.Seed(stored.all())
+
+	if window == 0 {
+		for _, a := range stored.all() {
+			calc.AddPeakDemand(a.Start, a.DemandMax)
+		}
+
+		return nil
+	}
+
+	loc := rates.Location()
+	blocks := demand.Blocks(stored.hours, time.Hour, window, loc, stored.cutoff)
+	blocks = append(blocks, demand.Blocks(stored.minutes, time.Minute, window, loc, now)...)
+	for _, block := range blocks {
+		calc.AddPeakDemand(block.Time, block.Demand)
+	}
+
+	return nil
+}
+
+//billedDemand hands the completed blocks of the window to the calculator's demand charge, nil if there is no calculator
+func billedDemand(calc *tariff.Calculator, window time.Duration) func(demand.Event) {
+	if calc == nil {
+		return nil
+	}
+
+	return func(event demand.Event) {
+		if event.Window == window {
+			calc.AddPeakDemand(event.Time, event.Demand)
+		}
+	}
+}
+
+func (c *costCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- energyCost
+	ch <- currentRate
+	ch <- billingPeriodCost
+	ch <- billingPeriodEnergy
+	ch <- billingPeriodPeak
+}
+
+func (c *costCollector) Collect(ch chan<- prometheus.Metric) {
+	totals := c.calc.Totals()
+
+	ch <- prometheus.MustNewConstMetric(energyCost, prometheus.CounterValue, totals.EnergyCost, totals.Currency)
+	ch <- prometheus.MustNewConstMetric(currentRate, prometheus.GaugeValue, totals.CurrentRate, totals.Currency)
+	ch <- prometheus.MustNewConstMetric(billingPeriodCost, prometheus.GaugeValue, totals.PeriodCost(), totals.Currency)
+	ch <- prometheus.MustNewConstMetric(billingPeriodEnergy, prometheus.GaugeValue, totals.PeriodEnergy)
+	ch <- prometheus.MustNewConstMetric(billingPeriodPeak, prometheus.GaugeValue, totals.PeriodPeakDemand)
+}
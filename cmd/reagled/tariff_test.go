@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/tariff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedCosts(t *testing.T) {
+	rates, err := tariff.Parse([]byte(`{"timezone": "UTC", "seasons": [{"energy": {"type": "flat", "rate": 0.10}, "demand": {"rate_per_kw": 10}}]}`))
+	require.NoError(t, err)
+
+	start := time.Date(2018, 10, 15, 10, 0, 0, 0, time.UTC)
+	store, clean := testStore(t, minuteReadings(start, append(repeat(4, 15), repeat(1, 20)...)...)...)
+	defer clean()
+
+	now := start.Add(time.Minute * 35)
+	block := tariff.NewCalculator(rates)
+	require.NoError(t, seedCosts(store, block, time.Minute*15, now))
+
+	totals := block.Totals()
+	assert.Equal(t, time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC), totals.PeriodStart)
+	assert.InDelta(t, 1+20/60.0, totals.PeriodEnergy, 0.0001)
+	assert.InDelta(t, 4.0, totals.PeriodPeakDemand, 0.0001)
+
+	instantaneous := tariff.NewCalculator(rates)
+	require.NoError(t, seedCosts(store, instantaneous, 0, now))
+	assert.InDelta(t, 4.0, instantaneous.Totals().PeriodPeakDemand, 0.0001)
+	assert.Equal(t, totals.PeriodEnergyCost, instantaneous.Totals().PeriodEnergyCost)
+}
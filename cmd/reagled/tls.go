@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/kklipsch/reagle/tlsreload"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var tlsReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "tls_reloads_total",
+	Help: "Count of times changed tls certificate files were reloaded or failed to load",
+},
+	[]string{"outcome"},
+)
+
+//newTLS serves the certificate files, reloading them when they change until the context is done
+func newTLS(ctx context.Context, reg prometheus.Registerer, config tlsreload.Config, interval time.Duration) (*tls.Config, error) {
+	reloader, err := tlsreload.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, outcome := range []string{"reloaded", "error"} {
+		tlsReloads.WithLabelValues(outcome).Add(0)
+	}
+
+	err = reg.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "tls_certificate_expiry_timestamp_seconds",
+		Help: "When the tls certificate being served expires",
+	}, func() float64 { return float64(reloader.NotAfter().Unix()) }))
+	if err != nil {
+		return nil, err
+	}
+
+	reloader.Reloaded = func(err error) {
+		if err != nil {
+			tlsReloads.WithLabelValues("error").Inc()
+			applicationLogger.WithFields(log.Fields{"err": err}).Errorln("unable to reload tls certificate, still serving the previous one")
+			return
+		}
+
+		tlsReloads.WithLabelValues("reloaded").Inc()
+		applicationLogger.WithFields(log.Fields{"not_after": reloader.NotAfter()}).Infoln("reloaded tls certificate")
+	}
+
+	if interval > 0 {
+		go reloader.Watch(ctx, interval)
+	}
+
+	return reloader.TLSConfig(), nil
+}
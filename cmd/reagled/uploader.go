@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/uploader"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+//uploaderPath is where the eagle's cloud uploader should be pointed, http://reagled:9000/uploader
+const uploaderPath = "/uploader"
+
+var uploaderPushes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "uploader_pushes_total",
+	Help: "Count of authenticated pushes from the eagle's uploader by whether they were published, ignored or invalid",
+},
+	[]string{"outcome"},
+)
+
+//uploaderRoutes receives the eagle's pushes and publishes them through the poller, so everything the poller feeds
+//works the same whether readings are polled or pushed
+func uploaderRoutes(config uploader.Config, poller *client.Poller) (routes, error) {
+	receiver, err := uploader.NewReceiver(config, poller.Publish)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, outcome := range []string{"published", "ignored", "invalid"} {
+		uploaderPushes.WithLabelValues(outcome).Add(0)
+	}
+
+	receiver.Result = func(u uploader.Update, published bool, err error) {
+		switch {
+		case err != nil:
+			uploaderPushes.WithLabelValues("invalid").Inc()
+			applicationLogger.WithFields(log.Fields{"error": err}).Warnln("invalid push from the uploader")
+		case published:
+			uploaderPushes.WithLabelValues("published").Inc()
+		default:
+			uploaderPushes.WithLabelValues("ignored").Inc()
+		}
+	}
+
+	return func(router *httprouter.Router) {
+		router.Handler("POST", uploaderPath, instrumentHandler("uploader", receiver))
+	}, nil
+}
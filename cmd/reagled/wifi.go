@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	wifiEnabled = prometheus.NewDesc("eagle_wifi_enabled", "1 if the eagle reports wifi as enabled", nil, nil)
+	wifiInfo    = prometheus.NewDesc("eagle_wifi_info", "information about the eagle's wifi connection", []string{"ssid", "encryption", "type", "channel"}, nil)
+
+	wifiIPAddressChanges = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eagle_wifi_ip_address_changes_total",
+		Help: "Count of the eagle's ip address changing",
+	})
+)
+
+type (
+	//polls the wifi status on a schedule so that changes can be logged as they happen
+	wifiMonitor struct {
+		c client.Local
+
+		//*local.WifiStatus from the last successful poll, nil until there is one
+		status atomic.Value
+	}
+
+	wifiChange struct {
+		fellBackToEthernet bool
+		channelChanged     bool
+		ipAddressChanged   bool
+	}
+)
+
+func newWifiMonitor(ctx context.Context, reg prometheus.Registerer, c client.Local, interval time.Duration) (*wifiMonitor, error) {
+	monitor := &wifiMonitor{c: c}
+	monitor.status.Store((*local.WifiStatus)(nil))
+
+	err := reg.Register(monitor)
+	if err != nil {
+		return monitor, err
+	}
+
+	go pollEvery(ctx, interval, monitor.poll)
+	return monitor, nil
+}
+
+func (m *wifiMonitor) poll(ctx context.Context) {
+	timeout, clean := context.WithTimeout(ctx, time.Second*5)
+	defer clean()
+
+	response, err := m.c.Request(timeout, client.RequestWifiStatus())
+	if err != nil {
+		instrumentError(err, "unable to get wifi status for wifi monitor")
+		return
+	}
+
+	current := response.(local.WifiStatus)
+	previous := m.status.Load().(*local.WifiStatus)
+	if previous != nil {
+		logWifiChange(*previous, current, diffWifi(*previous, current))
+	}
+
+	m.status.Store(&current)
+}
+
+func diffWifi(previous local.WifiStatus, current local.WifiStatus) wifiChange {
+	return wifiChange{
+		fellBackToEthernet: bool(previous.Enabled) && !bool(current.Enabled),
+		channelChanged:     bool(previous.Enabled) && bool(current.Enabled) && previous.Channel != current.Channel,
+		ipAddressChanged:   previous.IPAddress != current.IPAddress,
+	}
+}
+
+func logWifiChange(previous local.WifiStatus, current local.WifiStatus, change wifiChange) {
+	logger := applicationLogger.WithFields(log.Fields{"ssid": current.SSID, "previous_ssid": previous.SSID})
+
+	if change.fellBackToEthernet {
+		logger.Warnln("gateway fell back from wifi to ethernet")
+	}
+
+	if change.channelChanged {
+		logger.WithFields(log.Fields{"channel": current.Channel, "previous_channel": previous.Channel}).Warnln("gateway wifi channel changed")
+	}
+
+	if change.ipAddressChanged {
+		wifiIPAddressChanges.Inc()
+		logger.WithFields(log.Fields{"ip_address": current.IPAddress, "previous_ip_address": previous.IPAddress}).Infoln("gateway ip address changed")
+	}
+}
+
+//the info labels change with the wifi so the metrics are not constant
+func (m *wifiMonitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- wifiEnabled
+	ch <- wifiInfo
+}
+
+//Collect reports the last poll rather than calling the eagle
+func (m *wifiMonitor) Collect(ch chan<- prometheus.Metric) {
+	status := m.status.Load().(*local.WifiStatus)
+	if status == nil {
+		return
+	}
+
+	enabled := 0.0
+	if status.Enabled {
+		enabled = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(wifiEnabled, prometheus.GaugeValue, enabled)
+	ch <- prometheus.MustNewConstMetric(wifiInfo, prometheus.GaugeValue, 1, status.SSID, status.Encryption, status.Type, strconv.Itoa(status.Channel))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWifi(t *testing.T) {
+	wifi := local.WifiStatus{Enabled: true, Channel: 6, IPAddress: "192.168.1.10"}
+
+	fallback := wifi
+	fallback.Enabled = false
+	fallback.IPAddress = "192.168.1.11"
+	assert.Equal(t, wifiChange{fellBackToEthernet: true, ipAddressChanged: true}, diffWifi(wifi, fallback))
+
+	moved := wifi
+	moved.Channel = 11
+	assert.Equal(t, wifiChange{channelChanged: true}, diffWifi(wifi, moved))
+
+	assert.Equal(t, wifiChange{}, diffWifi(wifi, wifi))
+}
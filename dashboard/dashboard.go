@@ -0,0 +1,33 @@
+/*
+Package dashboard is a single page dashboard for people who don't use Grafana.  The page is compiled into the binary so
+it works without internet access, it gets everything else from reagled's own endpoints.
+
+index.html is the source, assets.go is generated from it with go generate.
+*/
+package dashboard
+
+//go:generate go run gen.go
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	etag    = fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(index)))
+	started = time.Now()
+)
+
+//Handler serves the page, it is unchanged for the life of the binary so clients revalidate with its etag
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		//ServeContent handles If-None-Match as well as HEAD and ranges
+		http.ServeContent(w, r, "index.html", started, strings.NewReader(index))
+	})
+}
@@ -0,0 +1,96 @@
+package dashboard
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetsAreGenerated(t *testing.T) {
+	html, err := ioutil.ReadFile("index.html")
+	require.NoError(t, err)
+	assert.Equal(t, string(html), index, "index.html has changed, run go generate")
+}
+
+func TestHandler(t *testing.T) {
+	ts := httptest.NewServer(Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+	assert.Equal(t, index, string(body))
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+
+	cached, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer cached.Body.Close()
+	assert.Equal(t, http.StatusNotModified, cached.StatusCode)
+}
+
+func TestToday(t *testing.T) {
+	loc := time.FixedZone("utility", -6*60*60)
+	start := time.Date(2019, 3, 1, 20, 0, 0, 0, loc)
+
+	today := NewToday(loc)
+	assert.Equal(t, Summary{Since: start}, today.Summary(start), "nothing added yet")
+
+	today.Add(start, 1000, 50, 0.10, "USD")
+	today.Add(start.Add(time.Hour), 1002, 50, 0.10, "USD")
+	today.Add(start.Add(2*time.Hour), 1003, 51, 0.20, "USD")
+	today.Add(start.Add(time.Hour), 900, 0, 0.10, "USD")
+
+	summary := today.Summary(start.Add(2 * time.Hour))
+	assert.Equal(t, start, summary.Since, "counted from startup")
+	assert.InDelta(t, 3, summary.Delivered, 0.0001)
+	assert.InDelta(t, 1, summary.Received, 0.0001)
+	assert.InDelta(t, 0.4, summary.Cost, 0.0001)
+	assert.Equal(t, "USD", summary.Currency)
+
+	tomorrow := time.Date(2019, 3, 2, 0, 0, 0, 0, loc)
+	assert.Equal(t, Summary{Since: tomorrow, Currency: "USD"}, today.Summary(tomorrow.Add(time.Minute)), "nothing added yet tomorrow")
+
+	today.Add(tomorrow.Add(time.Minute), 1004, 51, 0.10, "USD")
+	summary = today.Summary(tomorrow.Add(time.Minute))
+	assert.Equal(t, tomorrow, summary.Since)
+	assert.InDelta(t, 1, summary.Delivered, 0.0001)
+	assert.InDelta(t, 0.1, summary.Cost, 0.0001)
+
+	today.Add(tomorrow.Add(2*time.Minute), 2, 0, 0.10, "USD")
+	today.Add(tomorrow.Add(3*time.Minute), 3, 0, 0.10, "USD")
+	assert.InDelta(t, 4, today.Summary(tomorrow.Add(3*time.Minute)).Delivered, 0.0001, "a reset summation counts from 0")
+}
+
+func TestTodaySeed(t *testing.T) {
+	loc := time.FixedZone("utility", -6*60*60)
+	start := time.Date(2019, 3, 1, 20, 0, 0, 0, loc)
+	midnight := time.Date(2019, 3, 1, 0, 0, 0, 0, loc)
+
+	today := NewToday(loc)
+	today.Seed(start, 10, 1, 1.5, "USD")
+	assert.Equal(t, Summary{Since: midnight, Delivered: 10, Received: 1, Cost: 1.5, Currency: "USD"}, today.Summary(start))
+
+	//the first summation after a restart carries on from the seeded summary
+	today.Add(start, 1000, 50, 0.10, "USD")
+	today.Add(start.Add(time.Hour), 1002, 50, 0.10, "USD")
+	summary := today.Summary(start.Add(time.Hour))
+	assert.Equal(t, midnight, summary.Since)
+	assert.InDelta(t, 12, summary.Delivered, 0.0001)
+	assert.InDelta(t, 1.7, summary.Cost, 0.0001)
+
+	tomorrow := midnight.AddDate(0, 0, 1)
+	assert.Equal(t, Summary{Since: tomorrow, Currency: "USD"}, today.Summary(tomorrow.Add(time.Minute)))
+}
@@ -0,0 +1,35 @@
+//go:build ignore
+// +build ignore
+
+//gen writes index.html into assets.go as a constant so that builds don't need the file, run it with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+func main() {
+	html, err := ioutil.ReadFile("index.html")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if bytes.IndexByte(html, '`') >= 0 {
+		log.Fatal("index.html can't contain backquotes, it is written as a raw string")
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by gen.go from index.html. DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package dashboard")
+	fmt.Fprintln(&out)
+	fmt.Fprintf(&out, "const index = `%s`\n", html)
+
+	err = ioutil.WriteFile("assets.go", out.Bytes(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
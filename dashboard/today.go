@@ -0,0 +1,110 @@
+package dashboard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+)
+
+//Summary is the energy and its cost so far today
+type Summary struct {
+	//Since is midnight, or when reagled started if that was today
+	Since     time.Time `json:"since"`
+	Delivered float64   `json:"delivered_kwh"`
+	Received  float64   `json:"received_kwh"`
+	Cost      float64   `json:"cost"`
+	Currency  string    `json:"currency,omitempty"`
+}
+
+//Today keeps today's Summary from the meter's summations.  It is in memory, so it is seeded from storage when reagled
+//restarts or starts over otherwise
+type Today struct {
+	loc *time.Location
+
+	mu        sync.Mutex
+	summary   Summary
+	last      time.Time
+	delivered float64
+	received  float64
+}
+
+//NewToday creates a Today whose days start at midnight in loc
+func NewToday(loc *time.Location) *Today {
+	return &Today{loc: loc}
+}
+
+//Seed sets the summary of the day ts is in from stored history, the first summation added after it carries on from it
+func (t *Today) Seed(ts time.Time, delivered float64, received float64, cost float64, currency string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.summary = Summary{Since: midnight(ts.In(t.loc)), Delivered: delivered, Received: received, Cost: cost, Currency: currency}
+	t.last = time.Time{}
+}
+
+//Add adds the energy since the previous summations, costing what was delivered at rate.  Summations older than the
+//previous are ignored and a summation that went down, as when the meter is reset, counts from 0 the way storage does
+func (t *Today) Add(ts time.Time, delivered float64, received float64, rate float64, currency string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.last.IsZero() && !ts.After(t.last) {
+		return
+	}
+
+	switch {
+	case t.last.IsZero() && !t.summary.Since.IsZero() && sameDay(t.summary.Since, ts.In(t.loc)):
+		//seeded, the energy before this summation is already in the summary
+	case t.last.IsZero():
+		t.summary = Summary{Since: ts}
+	case !sameDay(t.last.In(t.loc), ts.In(t.loc)):
+		t.summary = Summary{Since: midnight(ts.In(t.loc))}
+		fallthrough
+	default:
+		kwh := storage.CounterDelta(t.delivered, delivered)
+		t.summary.Delivered += kwh
+		t.summary.Cost += kwh * rate
+		t.summary.Received += storage.CounterDelta(t.received, received)
+	}
+
+	if currency != "" {
+		t.summary.Currency = currency
+	}
+
+	t.last = ts
+	t.delivered = delivered
+	t.received = received
+}
+
+//Summary is today's summary as of at, which is empty if nothing has been added yet today
+func (t *Today) Summary(at time.Time) Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := t.last
+	if last.IsZero() {
+		last = t.summary.Since
+	}
+
+	if last.IsZero() {
+		return Summary{Since: at}
+	}
+
+	if at.After(last) && !sameDay(last.In(t.loc), at.In(t.loc)) {
+		return Summary{Since: midnight(at.In(t.loc)), Currency: t.summary.Currency}
+	}
+
+	return t.summary
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
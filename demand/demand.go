@@ -0,0 +1,344 @@
+/*
+Package demand calculates average demand over fixed length windows from the meter's summation counter.
+
+Utilities with demand charges bill on the highest average demand over a 15 or 30 minute interval rather than on the
+instantaneous demand the meter reports.  Some use clock aligned blocks (:00-:15, :15-:30, ...) and some a rolling window
+ending at every reading, so both are calculated along with their daily and monthly peaks.
+*/
+package demand
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+)
+
+//Method is how the window is positioned
+type Method string
+
+const (
+	//Rolling windows end at every reading
+	Rolling Method = "rolling"
+	//Block windows are aligned to the clock and do not overlap
+	Block Method = "block"
+)
+
+//Methods are all the methods a Calculator tracks
+var Methods = []Method{Rolling, Block}
+
+//EventType is the type of an Event returned by Calculator.Add
+type EventType string
+
+const (
+	//BlockEnd is returned when a block is complete, Demand is its average
+	BlockEnd EventType = "block_end"
+	//ApproachingPeak is returned when the rolling demand first reaches the threshold fraction of the month's block peak
+	ApproachingPeak EventType = "approaching_peak"
+)
+
+type (
+	//Peak is the highest average demand, in kW, of a period and the time of the window it was seen in
+	Peak struct {
+		Demand float64   `json:"demand_kw"`
+		Time   time.Time `json:"time"`
+	}
+
+	//Peaks are the daily and monthly peak of a Method
+	Peaks struct {
+		Day   Peak `json:"day"`
+		Month Peak `json:"month"`
+	}
+
+	//Event is something that happened while adding a reading
+	Event struct {
+		Type   EventType     `json:"type"`
+		Window time.Duration `json:"window"`
+		Time   time.Time     `json:"time"`
+		Demand float64       `json:"demand_kw"`
+		Peak   float64       `json:"peak_kw"`
+	}
+
+	//Snapshot is the current state of a Calculator
+	Snapshot struct {
+		Window time.Duration `json:"window"`
+
+		//Rolling is only valid once readings cover a whole window
+		Rolling      float64 `json:"rolling_kw"`
+		RollingValid bool    `json:"rolling_valid"`
+
+		//Block is the average demand of the block in progress so far
+		BlockStart time.Time `json:"block_start"`
+		Block      float64   `json:"block_kw"`
+
+		Peaks map[Method]Peaks `json:"peaks"`
+	}
+
+	//Calculator averages demand over a window, it is safe for concurrent use
+	Calculator struct {
+		window    time.Duration
+		loc       *time.Location
+		threshold float64
+
+		mu            sync.Mutex
+		last          time.Time
+		lastDelivered float64
+
+		//cumulative energy since the first reading, so counter resets do not affect the window
+		energy  float64
+		samples []sample
+		rolling float64
+		valid   bool
+
+		blockStart   time.Time
+		blockEnergy  float64
+		blockPartial bool
+
+		peaks       map[Method]*Peaks
+		approaching bool
+	}
+
+	sample struct {
+		time   time.Time
+		energy float64
+	}
+)
+
+//NewCalculator creates a Calculator for the window, with days and months in loc.  threshold is the fraction of the
+//month's block peak that raises an ApproachingPeak event, 0 disables it
+func NewCalculator(window time.Duration, loc *time.Location, threshold float64) *Calculator {
+	return &Calculator{
+		window:    window,
+		loc:       loc,
+		threshold: threshold,
+		peaks:     map[Method]*Peaks{Rolling: {}, Block: {}},
+	}
+}
+
+//Window is the length of the window the calculator averages over
+func (c *Calculator) Window() time.Duration {
+	return c.window
+}
+
+//Add accounts for a reading of the summation delivered counter in kWh.  Readings older than the last one are ignored
+func (c *Calculator) Add(ts time.Time, delivered float64) []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last.IsZero() {
+		c.last = ts
+		c.lastDelivered = delivered
+		c.samples = []sample{{time: ts}}
+		c.blockStart = truncate(ts, c.window, c.loc)
+		c.blockPartial = c.blockStart.Before(ts)
+		return nil
+	}
+
+	if !ts.After(c.last) {
+		return nil
+	}
+
+	delta := storage.CounterDelta(c.lastDelivered, delivered)
+	events := c.addBlocks(c.last, ts, delta)
+
+	c.last = ts
+	c.lastDelivered = delivered
+	c.energy += delta
+	c.samples = append(c.samples, sample{time: ts, energy: c.energy})
+
+	if c.updateRolling(ts) {
+		c.peaks[Rolling].add(ts, c.rolling, c.loc)
+		if event, ok := c.checkApproaching(ts); ok {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+//addBlocks spreads energy used between from and to evenly over time, completing any blocks that ended
+func (c *Calculator) addBlocks(from time.Time, to time.Time, energy float64) []Event {
+	var events []Event
+	for {
+		blockEnd := c.blockStart.Add(c.window)
+		if to.Before(blockEnd) {
+			c.blockEnergy += energy
+			return events
+		}
+
+		portion := energy * float64(blockEnd.Sub(from)) / float64(to.Sub(from))
+		c.blockEnergy += portion
+		energy -= portion
+
+		//the first block is missing the energy used before the first reading
+		if !c.blockPartial {
+			demand := c.blockEnergy / c.window.Hours()
+			c.peaks[Block].add(c.blockStart, demand, c.loc)
+			events = append(events, Event{Type: BlockEnd, Window: c.window, Time: c.blockStart, Demand: demand})
+		}
+
+		from = blockEnd
+		c.blockStart = blockEnd
+		c.blockEnergy = 0
+		c.blockPartial = false
+	}
+}
+
+//truncate returns the start of the block ts is in.  Blocks are aligned to the clock in loc rather than the UTC epoch,
+//they differ when the zone's offset is not a whole number of windows, e.g. hour blocks in a +05:30 zone
+func truncate(ts time.Time, window time.Duration, loc *time.Location) time.Time {
+	_, offset := ts.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	return ts.Add(shift).Truncate(window).Add(-shift)
+}
+
+//Blocks averages the demand of the clock aligned blocks of window in loc from stored history.  aggregates are oldest
+//first, each covering resolution, and when that is longer than the window the aggregates are the blocks.  Blocks that
+//end after end are not complete and are left out
+func Blocks(aggregates []storage.Aggregate, resolution time.Duration, window time.Duration, loc *time.Location, end time.Time) []Peak {
+	step := window
+	if resolution > step {
+		step = resolution
+	}
+
+	var blocks []Peak
+	var current *Peak
+	for _, a := range aggregates {
+		start := a.Start
+		if resolution <= window {
+			start = truncate(a.Start, window, loc)
+		}
+
+		if start.Add(step).After(end) {
+			break
+		}
+
+		if current == nil || !start.Equal(current.Time) {
+			blocks = append(blocks, Peak{Time: start})
+			current = &blocks[len(blocks)-1]
+		}
+
+		current.Demand += a.DeliveredDelta / step.Hours()
+	}
+
+	return blocks
+}
+
+//Seed raises the peaks from stored history so they survive a restart.  aggregates are oldest first, each covering
+//resolution.  Blocks are averaged as in Blocks and rolling windows end at every aggregate that ends by end, so the
+//seeded peaks are only as fine as the resolution allows.  The block in progress is left to the calculator to average
+func (c *Calculator) Seed(aggregates []storage.Aggregate, resolution time.Duration, end time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, block := range Blocks(aggregates, resolution, c.window, c.loc, end) {
+		c.peaks[Block].add(block.Time, block.Demand, c.loc)
+	}
+
+	for i, a := range aggregates {
+		if a.Start.Add(resolution).After(end) {
+			break
+		}
+
+		if resolution < c.window {
+			c.seedRolling(aggregates[:i+1], resolution)
+		} else {
+			c.peaks[Rolling].add(a.Start.Add(resolution), a.DeliveredDelta/resolution.Hours(), c.loc)
+		}
+	}
+}
+
+//seedRolling adds the rolling window ending at the end of the last of the aggregates
+func (c *Calculator) seedRolling(aggregates []storage.Aggregate, resolution time.Duration) {
+	last := aggregates[len(aggregates)-1]
+	windowEnd := last.Start.Add(resolution)
+	windowStart := windowEnd.Add(-c.window)
+
+	energy := 0.0
+	for i := len(aggregates) - 1; i >= 0 && !aggregates[i].Start.Before(windowStart); i-- {
+		energy += aggregates[i].DeliveredDelta
+	}
+
+	c.peaks[Rolling].add(windowEnd, energy/c.window.Hours(), c.loc)
+}
+
+//updateRolling recalculates the rolling average, returning false until the readings cover a whole window
+func (c *Calculator) updateRolling(ts time.Time) bool {
+	start := ts.Add(-c.window)
+
+	//keep one sample at or before the start of the window to interpolate from
+	for len(c.samples) > 1 && !c.samples[1].time.After(start) {
+		c.samples = c.samples[1:]
+	}
+
+	first := c.samples[0]
+	if first.time.After(start) {
+		return false
+	}
+
+	next := c.samples[1]
+	energyAtStart := first.energy + (next.energy-first.energy)*float64(start.Sub(first.time))/float64(next.time.Sub(first.time))
+
+	c.rolling = (c.energy - energyAtStart) / c.window.Hours()
+	c.valid = true
+	return true
+}
+
+//checkApproaching returns an event the first time the rolling demand reaches the threshold, and again only after it has
+//dropped back below it
+func (c *Calculator) checkApproaching(ts time.Time) (Event, bool) {
+	peak := c.peaks[Block].Month.Demand
+	if c.threshold <= 0 || peak <= 0 {
+		return Event{}, false
+	}
+
+	if c.rolling < peak*c.threshold {
+		c.approaching = false
+		return Event{}, false
+	}
+
+	if c.approaching {
+		return Event{}, false
+	}
+
+	c.approaching = true
+	return Event{Type: ApproachingPeak, Window: c.window, Time: ts, Demand: c.rolling, Peak: peak}, true
+}
+
+//Snapshot returns the current averages and peaks
+func (c *Calculator) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := Snapshot{
+		Window:       c.window,
+		Rolling:      c.rolling,
+		RollingValid: c.valid,
+		BlockStart:   c.blockStart,
+		Peaks:        make(map[Method]Peaks),
+	}
+
+	if elapsed := c.last.Sub(c.blockStart); elapsed > 0 {
+		snapshot.Block = c.blockEnergy / elapsed.Hours()
+	}
+
+	for method, peaks := range c.peaks {
+		snapshot.Peaks[method] = *peaks
+	}
+
+	return snapshot
+}
+
+//add replaces the peaks if demand is higher or ts is in a new day or month
+func (p *Peaks) add(ts time.Time, demand float64, loc *time.Location) {
+	local := ts.In(loc)
+	day := p.Day.Time.In(loc)
+	if p.Day.Time.IsZero() || demand > p.Day.Demand || day.YearDay() != local.YearDay() || day.Year() != local.Year() {
+		p.Day = Peak{Demand: demand, Time: ts}
+	}
+
+	month := p.Month.Time.In(loc)
+	if p.Month.Time.IsZero() || demand > p.Month.Demand || month.Month() != local.Month() || month.Year() != local.Year() {
+		p.Month = Peak{Demand: demand, Time: ts}
+	}
+}
@@ -0,0 +1,181 @@
+package demand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2018, 10, 31, 23, 0, 0, 0, time.UTC)
+
+//feed adds a reading every minute for the demands (kW), returning the events
+func feed(c *Calculator, from time.Time, delivered float64, demands ...float64) ([]Event, float64) {
+	var events []Event
+	for i, demand := range demands {
+		delivered += demand / 60
+		events = append(events, c.Add(from.Add(time.Minute*time.Duration(i+1)), delivered)...)
+	}
+
+	return events, delivered
+}
+
+func repeat(demand float64, count int) []float64 {
+	var demands []float64
+	for i := 0; i < count; i++ {
+		demands = append(demands, demand)
+	}
+
+	return demands
+}
+
+func TestBlocks(t *testing.T) {
+	c := NewCalculator(time.Minute*15, time.UTC, 0)
+
+	//starting mid block, that block is not complete so is not reported
+	begin := start.Add(time.Minute * 5)
+	assert.Empty(t, c.Add(begin, 100))
+
+	events, _ := feed(c, begin, 100, append(repeat(2, 10), repeat(4, 15)...)...)
+	require.Len(t, events, 1)
+	assert.Equal(t, BlockEnd, events[0].Type)
+	assert.Equal(t, start.Add(time.Minute*15), events[0].Time)
+	assert.InDelta(t, 4, events[0].Demand, 0.0001)
+
+	//meter reset mid block still counts the energy
+	c.Add(begin.Add(time.Minute*26), 1.0/60)
+	events, _ = feed(c, begin.Add(time.Minute*26), 1.0/60, repeat(1, 14)...)
+	require.Len(t, events, 1)
+	assert.InDelta(t, 1.0, events[0].Demand, 0.0001)
+
+	snapshot := c.Snapshot()
+	assert.Equal(t, 4.0, round(snapshot.Peaks[Block].Month.Demand))
+	assert.Equal(t, start.Add(time.Minute*15), snapshot.Peaks[Block].Month.Time)
+}
+
+func TestRollingAndPeaks(t *testing.T) {
+	c := NewCalculator(time.Minute*15, time.UTC, 0)
+	c.Add(start, 0)
+
+	_, delivered := feed(c, start, 0, repeat(2, 10)...)
+	assert.False(t, c.Snapshot().RollingValid, "window not covered yet")
+
+	_, delivered = feed(c, start.Add(time.Minute*10), delivered, repeat(6, 5)...)
+	snapshot := c.Snapshot()
+	require.True(t, snapshot.RollingValid)
+	assert.InDelta(t, (2*10+6*5)/15.0, snapshot.Rolling, 0.0001)
+
+	//the next day (and month) starts the peaks again
+	_, _ = feed(c, start.Add(time.Minute*15), delivered, repeat(1, 60)...)
+	snapshot = c.Snapshot()
+	assert.InDelta(t, 1.0, snapshot.Peaks[Rolling].Day.Demand, 0.0001)
+	assert.InDelta(t, 1.0, snapshot.Peaks[Rolling].Month.Demand, 0.0001)
+	assert.Equal(t, time.November, snapshot.Peaks[Rolling].Month.Time.Month())
+}
+
+func TestApproachingPeak(t *testing.T) {
+	c := NewCalculator(time.Minute*15, time.UTC, 0.9)
+	c.Add(start, 0)
+
+	//a 4kW block sets the month's peak
+	_, delivered := feed(c, start, 0, repeat(4, 15)...)
+	_, delivered = feed(c, start.Add(time.Minute*15), delivered, repeat(1, 15)...)
+
+	events, delivered := feed(c, start.Add(time.Minute*30), delivered, repeat(10, 5)...)
+	var approaching []Event
+	for _, e := range events {
+		if e.Type == ApproachingPeak {
+			approaching = append(approaching, e)
+		}
+	}
+
+	require.Len(t, approaching, 1, "only raised once until it drops below the threshold")
+	assert.InDelta(t, 4.0, approaching[0].Peak, 0.0001)
+	assert.True(t, approaching[0].Demand >= 3.6)
+
+	events, _ = feed(c, start.Add(time.Minute*35), delivered, repeat(0, 30)...)
+	for _, e := range events {
+		assert.NotEqual(t, ApproachingPeak, e.Type)
+	}
+}
+
+func TestBlocksAlignToLocation(t *testing.T) {
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	c := NewCalculator(time.Hour, india, 0)
+
+	c.Add(start, 0)
+	assert.Equal(t, time.Date(2018, 11, 1, 4, 0, 0, 0, india), c.Snapshot().BlockStart.In(india))
+}
+
+func TestBlocksFromHistory(t *testing.T) {
+	india := time.FixedZone("IST", 5*60*60+30*60)
+
+	//30 minutes at 2kW then 30 minutes at 4kW, straddling the hour on the clock in india
+	history := minutes(start, append(repeat(2, 30), repeat(4, 30)...)...)
+	blocks := Blocks(history, time.Minute, time.Hour, india, start.Add(time.Hour*2))
+	require.Len(t, blocks, 2)
+	assert.Equal(t, start.Add(-time.Minute*30), blocks[0].Time)
+	assert.InDelta(t, 1.0, blocks[0].Demand, 0.0001, "the first block is missing the energy before the history")
+	assert.InDelta(t, 2.0, blocks[1].Demand, 0.0001, "the second block is missing the energy after it")
+
+	assert.Len(t, Blocks(history, time.Minute, time.Hour, india, start.Add(time.Hour)), 1, "the second block is not complete")
+}
+
+//minutes returns minute aggregates from from for the demands (kW)
+func minutes(from time.Time, demands ...float64) []storage.Aggregate {
+	var aggregates []storage.Aggregate
+	for i, demand := range demands {
+		aggregates = append(aggregates, storage.Aggregate{Start: from.Add(time.Minute * time.Duration(i)), Count: 1, DeliveredDelta: demand / 60})
+	}
+
+	return aggregates
+}
+
+func TestSeed(t *testing.T) {
+	c := NewCalculator(time.Minute*15, time.UTC, 0)
+
+	//a 4kW block, 10 minutes of 8kW straddling two blocks and a block in progress at 20kW
+	demands := append(repeat(4, 15), repeat(1, 10)...)
+	demands = append(demands, repeat(8, 10)...)
+	demands = append(demands, repeat(0, 10)...)
+	demands = append(demands, repeat(20, 5)...)
+	c.Seed(minutes(start, demands...), time.Minute, start.Add(time.Minute*50))
+
+	snapshot := c.Snapshot()
+	assert.InDelta(t, 4.0, snapshot.Peaks[Block].Month.Demand, 0.0001)
+	assert.Equal(t, start, snapshot.Peaks[Block].Month.Time)
+	assert.InDelta(t, 4.0, snapshot.Peaks[Block].Day.Demand, 0.0001, "the block in progress is not complete")
+
+	//the minutes of the block in progress are complete so they are in the rolling windows
+	assert.InDelta(t, (20*5)/15.0, snapshot.Peaks[Rolling].Month.Demand, 0.0001)
+	assert.Equal(t, start.Add(time.Minute*50), snapshot.Peaks[Rolling].Month.Time)
+
+	//live readings only replace the seeded peaks when they are higher
+	c.Add(start.Add(time.Minute*50), 0)
+	_, _ = feed(c, start.Add(time.Minute*50), 0, repeat(2, 20)...)
+	assert.InDelta(t, 4.0, c.Snapshot().Peaks[Block].Month.Demand, 0.0001)
+}
+
+func TestSeedHours(t *testing.T) {
+	c := NewCalculator(time.Minute*15, time.UTC, 0)
+
+	hours := []storage.Aggregate{
+		{Start: start.Add(-time.Hour), Count: 60, DeliveredDelta: 2},
+		{Start: start, Count: 60, DeliveredDelta: 3},
+		{Start: start.Add(time.Hour), Count: 30, DeliveredDelta: 1},
+	}
+	c.Seed(hours, time.Hour, start.Add(time.Hour*3/2))
+
+	//the hour in progress is not complete, and would have been in the next month
+	snapshot := c.Snapshot()
+	assert.InDelta(t, 3.0, snapshot.Peaks[Block].Month.Demand, 0.0001)
+	assert.Equal(t, start, snapshot.Peaks[Block].Month.Time)
+	assert.InDelta(t, 3.0, snapshot.Peaks[Rolling].Day.Demand, 0.0001)
+	assert.Equal(t, start.Add(time.Hour), snapshot.Peaks[Rolling].Day.Time)
+}
+
+func round(f float64) float64 {
+	return float64(int(f*1000+0.5)) / 1000
+}
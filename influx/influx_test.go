@@ -0,0 +1,245 @@
+package influx
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, 10, 20, 12, 0, 0, 0, time.UTC)
+
+func TestAppendLine(t *testing.T) {
+	line, err := SamplePoint(client.Sample{Time: now, Metrics: client.BaseMetrics{Demand: 1.5, Delivered: 100, Price: 0.1, Currency: "USD"}}).AppendLine(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "meter,currency=USD delivered=100,demand=1.5,price=0.1,received=0 1540036800000000000\n", string(line))
+
+	device := local.Device{DeviceData: local.DeviceData{
+		HardwareAddress:  "0x0013",
+		Manufacturer:     "Generic",
+		ModelID:          "electric meter",
+		LastContact:      local.NewTimestamp(now),
+		ConnectionStatus: local.Connected,
+	}}
+	line, err = DevicePoints(now, []local.Device{device})[0].AppendLine(nil)
+	require.NoError(t, err)
+	assert.Equal(t, `device,hardware_address=0x0013,manufacturer=Generic,model_id=electric\ meter connected=true,connection_status="Connected",last_contact=1540036800i 1540036800000000000`+"\n", string(line))
+
+	line, err = Point{
+		Measurement: "a b,c",
+		Tags:        map[string]string{"k=1": "v,2", "empty": ""},
+		Fields:      map[string]interface{}{"s": `say "hi" \o/`},
+		Time:        now,
+	}.AppendLine([]byte("prefix\n"))
+	require.NoError(t, err)
+	assert.Equal(t, `prefix`+"\n"+`a\ b\,c,k\=1=v\,2 s="say \"hi\" \\o/" 1540036800000000000`+"\n", string(line))
+
+	line, err = Point{Measurement: "bad", Fields: map[string]interface{}{"a": 1.0, "b": 1}}.AppendLine([]byte("kept\n"))
+	assert.Error(t, err)
+	assert.Equal(t, "kept\n", string(line), "nothing of the bad point is appended")
+}
+
+//influxStandIn accepts writes while up and records the bodies in the order received
+type influxStandIn struct {
+	mu     sync.Mutex
+	status int
+	bodies []string
+}
+
+func (s *influxStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path != "/api/v2/write" || r.URL.Query().Get("bucket") != "meter" || r.Header.Get("Authorization") != "Token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if s.status != http.StatusNoContent {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	b, _ := ioutil.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(b))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *influxStandIn) set(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+func (s *influxStandIn) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := s.bodies
+	s.bodies = nil
+	return taken
+}
+
+type outcomes struct {
+	mu     sync.Mutex
+	points map[string]int
+}
+
+func (o *outcomes) result(outcome string, points int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.points[outcome] += points
+}
+
+func (o *outcomes) get(outcome string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.points[outcome]
+}
+
+func point(i int) Point {
+	return Point{Measurement: "meter", Fields: map[string]interface{}{"demand": float64(i)}, Time: now.Add(time.Second * time.Duration(i))}
+}
+
+func testWriter(t *testing.T, url string, spoolDir string) (*Writer, *outcomes) {
+	w, err := NewWriter(http.DefaultClient, Config{
+		URL:           url,
+		Bucket:        "meter",
+		Token:         "secret",
+		Tags:          map[string]string{"site": "home"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		SpoolDir:      spoolDir,
+	})
+	require.NoError(t, err)
+
+	w.Backoff = time.Millisecond
+	o := &outcomes{points: make(map[string]int)}
+	w.Result = o.result
+	return w, o
+}
+
+func TestWriterSpoolsOutages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	standIn := &influxStandIn{status: http.StatusServiceUnavailable}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+
+	w, o := testWriter(t, ts.URL, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 4; i++ {
+		w.Write(point(i))
+	}
+
+	require.True(t, waitFor(func() bool { return o.get(Spooled) == 4 }), "both batches spooled")
+	batches, _, err := w.Spooled()
+	require.NoError(t, err)
+	assert.Equal(t, 2, batches)
+	assert.Empty(t, standIn.take())
+
+	//once the database is back the next batch is written and then the spool, oldest first
+	standIn.set(http.StatusNoContent)
+	w.Write(point(4))
+	w.Write(point(5))
+
+	require.True(t, waitFor(func() bool { return o.get(Written) == 6 }))
+	bodies := standIn.take()
+	require.Len(t, bodies, 3)
+	assert.True(t, strings.HasPrefix(bodies[0], "meter,site=home demand=4 "))
+	assert.True(t, strings.HasPrefix(bodies[1], "meter,site=home demand=0 "))
+	assert.True(t, strings.HasPrefix(bodies[2], "meter,site=home demand=2 "))
+
+	batches, _, err = w.Spooled()
+	require.NoError(t, err)
+	assert.Equal(t, 0, batches)
+
+	//what is queued at shutdown is spooled rather than lost
+	w.Write(point(6))
+	cancel()
+	<-done
+
+	batches, _, err = w.Spooled()
+	require.NoError(t, err)
+	assert.Equal(t, 1, batches)
+}
+
+func TestWriterDropsRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	standIn := &influxStandIn{status: http.StatusBadRequest}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+
+	w, o := testWriter(t, ts.URL, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	w.Write(point(0))
+	w.Write(point(1))
+
+	require.True(t, waitFor(func() bool { return o.get(Dropped) == 2 }))
+	batches, _, err := w.Spooled()
+	require.NoError(t, err)
+	assert.Equal(t, 0, batches, "a rejected batch would never succeed")
+}
+
+func TestSpoolMax(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := openSpool(dir, 10)
+	require.NoError(t, err)
+
+	dropped, err := s.push([]byte("a 1\nb 2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, dropped)
+
+	dropped, err = s.push([]byte("c 3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, dropped, "the oldest batch is removed")
+
+	_, oldest, err := s.oldest()
+	require.NoError(t, err)
+	assert.Equal(t, "c 3\n", string(oldest))
+}
+
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package influx
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+//Point is one line of line protocol.  Field values must be float64, int64, bool or string
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Time        time.Time
+}
+
+//SamplePoint is the meter measurement of a polled sample
+func SamplePoint(sample client.Sample) Point {
+	tags := map[string]string{}
+	if sample.Metrics.Currency != "" {
+		tags["currency"] = sample.Metrics.Currency
+	}
+
+	return Point{
+		Measurement: "meter",
+		Tags:        tags,
+		Fields: map[string]interface{}{
+			"demand":    sample.Metrics.Demand,
+			"delivered": sample.Metrics.Delivered,
+			"received":  sample.Metrics.Received,
+			"price":     sample.Metrics.Price,
+		},
+		Time: sample.Time,
+	}
+}
+
+//DevicePoints are the device measurements of a device list, one per device
+func DevicePoints(ts time.Time, devices []local.Device) []Point {
+	var points []Point
+	for _, device := range devices {
+		fields := map[string]interface{}{
+			"connected":         device.ConnectionStatus == local.Connected,
+			"connection_status": string(device.ConnectionStatus),
+		}
+
+		if !device.LastContact.IsZero() {
+			fields["last_contact"] = device.LastContact.Unix()
+		}
+
+		points = append(points, Point{
+			Measurement: "device",
+			Tags: map[string]string{
+				"hardware_address": device.HardwareAddress,
+				"manufacturer":     device.Manufacturer,
+				"model_id":         device.ModelID,
+			},
+			Fields: fields,
+			Time:   ts,
+		})
+	}
+
+	return points
+}
+
+//AppendLine appends the point's line, including the trailing newline, to buf.  On error buf is returned as it was
+func (p Point) AppendLine(buf []byte) ([]byte, error) {
+	start := len(buf)
+
+	if p.Measurement == "" {
+		return buf, fmt.Errorf("point has no measurement")
+	}
+
+	if len(p.Fields) == 0 {
+		return buf, fmt.Errorf("point %s has no fields", p.Measurement)
+	}
+
+	buf = append(buf, measurementEscaper.Replace(p.Measurement)...)
+
+	//sorted tags are faster for influx to index
+	for _, k := range sortedKeys(p.Tags) {
+		//empty tag values are not allowed
+		if p.Tags[k] == "" {
+			continue
+		}
+
+		buf = append(buf, ',')
+		buf = append(buf, keyEscaper.Replace(k)...)
+		buf = append(buf, '=')
+		buf = append(buf, keyEscaper.Replace(p.Tags[k])...)
+	}
+
+	fields := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	for i, k := range fields {
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+
+		buf = append(buf, keyEscaper.Replace(k)...)
+		buf = append(buf, '=')
+
+		switch v := p.Fields[k].(type) {
+		case float64:
+			buf = strconv.AppendFloat(buf, v, 'f', -1, 64)
+		case int64:
+			buf = strconv.AppendInt(buf, v, 10)
+			buf = append(buf, 'i')
+		case bool:
+			buf = strconv.AppendBool(buf, v)
+		case string:
+			buf = append(buf, '"')
+			buf = append(buf, stringEscaper.Replace(v)...)
+			buf = append(buf, '"')
+		default:
+			return buf[:start], fmt.Errorf("field %s of %s is an unsupported %T", k, p.Measurement, v)
+		}
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, p.Time.UnixNano(), 10)
+	return append(buf, '\n'), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
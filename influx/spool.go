@@ -0,0 +1,151 @@
+package influx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const spoolExt = ".lp"
+
+//spool keeps batches that could not be written as files of line protocol, oldest first by name
+type spool struct {
+	dir string
+	max int64
+
+	mu  sync.Mutex
+	seq int
+}
+
+func openSpool(dir string, max int64) (*spool, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	//a crash mid push leaves a temp file that was never renamed into the spool
+	temps, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, temp := range temps {
+		os.Remove(temp)
+	}
+
+	return &spool{dir: dir, max: max}, nil
+}
+
+//push adds a batch, then removes the oldest batches until the spool is under its max.  It returns how many lines
+//were removed
+func (s *spool) push(batch []byte) (int, error) {
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spoolExt)
+	s.mu.Unlock()
+
+	temp := filepath.Join(s.dir, name+".tmp")
+	err := ioutil.WriteFile(temp, batch, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(temp, filepath.Join(s.dir, name))
+	if err != nil {
+		os.Remove(temp)
+		return 0, err
+	}
+
+	return s.trim()
+}
+
+func (s *spool) trim() (int, error) {
+	if s.max <= 0 {
+		return 0, nil
+	}
+
+	names, total, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for i := 0; total > s.max && i < len(names)-1; i++ {
+		batch, err := s.read(names[i])
+		if err != nil {
+			return dropped, err
+		}
+
+		err = s.remove(names[i])
+		if err != nil {
+			return dropped, err
+		}
+
+		total -= int64(len(batch))
+		dropped += lines(batch)
+	}
+
+	return dropped, nil
+}
+
+//oldest returns the name and contents of the oldest batch, the name is empty if the spool is empty
+func (s *spool) oldest() (string, []byte, error) {
+	names, _, err := s.list()
+	if err != nil || len(names) == 0 {
+		return "", nil, err
+	}
+
+	batch, err := s.read(names[0])
+	return names[0], batch, err
+}
+
+func (s *spool) read(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s *spool) remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+//size is the number of batches and bytes in the spool
+func (s *spool) size() (int, int64, error) {
+	names, total, err := s.list()
+	return len(names), total, err
+}
+
+func (s *spool) list() ([]string, int64, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var names []string
+	var total int64
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), spoolExt) {
+			continue
+		}
+
+		names = append(names, info.Name())
+		total += info.Size()
+	}
+
+	sort.Strings(names)
+	return names, total, nil
+}
+
+func lines(batch []byte) int {
+	count := 0
+	for _, b := range batch {
+		if b == '\n' {
+			count++
+		}
+	}
+
+	return count
+}
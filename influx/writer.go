@@ -0,0 +1,309 @@
+/*
+Package influx writes meter readings to the InfluxDB v2 write API as line protocol.
+
+Delivery
+
+Points are batched and a batch is written when it is full or the flush interval passes.  A failed write is retried
+with a doubling backoff, and if it still fails the batch goes to the spool directory.  Spooled batches are written,
+oldest first, once a write succeeds again.  Batches the database rejects outright, a 4xx other than 429, are dropped
+rather than spooled since they would never succeed.
+*/
+package influx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/retry"
+)
+
+//Outcomes passed to a Writer's Result
+const (
+	Written = "written"
+	Spooled = "spooled"
+	Dropped = "dropped"
+)
+
+//ErrQueueFull is passed to the writer's Result when a point is dropped because the queue is full
+var ErrQueueFull = errors.New("point queue is full")
+
+type (
+	//Config is where to write and how to batch
+	Config struct {
+		//URL is the InfluxDB server such as http://localhost:8086
+		URL    string `json:"url"`
+		Org    string `json:"org"`
+		Bucket string `json:"bucket"`
+		Token  string `json:"-"`
+
+		//Tags are added to every point, such as a site name
+		Tags map[string]string `json:"tags"`
+
+		BatchSize     int           `json:"batch_size"`
+		FlushInterval time.Duration `json:"flush_interval"`
+
+		//SpoolDir is where failed batches are kept, empty drops them
+		SpoolDir string `json:"spool_dir"`
+
+		//MaxSpoolBytes removes the oldest spooled batches past this size, 0 is unlimited
+		MaxSpoolBytes int64 `json:"max_spool_bytes"`
+	}
+
+	//Writer batches points and writes them in the background
+	Writer struct {
+		config Config
+		client *http.Client
+		queue  chan Point
+		spool  *spool
+
+		//Retries is how many times a failed write is retried, doubling Backoff between each
+		Retries int
+		Backoff time.Duration
+
+		//Result, if set, is called with the outcome of every batch and how many points were in it.  Errors reading or
+		//writing the spool itself are Spooled with no points
+		Result func(outcome string, points int, err error)
+	}
+)
+
+//NewWriter creates a Writer, opening the spool if there is one.  Nothing is written until Run is called
+func NewWriter(client *http.Client, config Config) (*Writer, error) {
+	if config.URL == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("influx needs a url and a bucket")
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second * 10
+	}
+
+	w := &Writer{
+		config:  config,
+		client:  client,
+		queue:   make(chan Point, config.BatchSize*10),
+		Retries: 3,
+		Backoff: time.Second,
+	}
+
+	if config.SpoolDir != "" {
+		s, err := openSpool(config.SpoolDir, config.MaxSpoolBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open spool: %w", err)
+		}
+
+		w.spool = s
+	}
+
+	return w, nil
+}
+
+//Write queues the point, adding the configured tags
+func (w *Writer) Write(p Point) {
+	if len(w.config.Tags) > 0 {
+		tags := make(map[string]string, len(p.Tags)+len(w.config.Tags))
+		for k, v := range w.config.Tags {
+			tags[k] = v
+		}
+
+		for k, v := range p.Tags {
+			tags[k] = v
+		}
+
+		p.Tags = tags
+	}
+
+	select {
+	case w.queue <- p:
+	default:
+		w.result(Dropped, 1, ErrQueueFull)
+	}
+}
+
+//Spooled is the number of batches and bytes waiting in the spool
+func (w *Writer) Spooled() (int, int64, error) {
+	if w.spool == nil {
+		return 0, 0, nil
+	}
+
+	return w.spool.size()
+}
+
+//Run writes batches until the context is done, when whatever has not been written is spooled
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Point
+	for {
+		select {
+		case p := <-w.queue:
+			batch = append(batch, p)
+			if len(batch) < w.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			w.shutdown(batch)
+			return
+		}
+
+		if w.flush(ctx, batch) {
+			w.drain(ctx)
+		}
+
+		batch = nil
+	}
+}
+
+//flush writes the batch, spooling it on failure.  It is true if the database is reachable
+func (w *Writer) flush(ctx context.Context, batch []Point) bool {
+	if len(batch) == 0 {
+		return true
+	}
+
+	body := encode(batch, w.result)
+	if len(body) == 0 {
+		return true
+	}
+
+	err := w.write(ctx, body)
+	switch {
+	case err == nil:
+		w.result(Written, lines(body), nil)
+		return true
+	case !retry.Retryable(err):
+		w.result(Dropped, lines(body), err)
+		return true
+	}
+
+	w.save(body, err)
+	return false
+}
+
+//drain writes spooled batches oldest first until the spool is empty or a write fails
+func (w *Writer) drain(ctx context.Context) {
+	if w.spool == nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		name, body, err := w.spool.oldest()
+		if err != nil {
+			w.result(Spooled, 0, err)
+			return
+		}
+
+		if name == "" {
+			return
+		}
+
+		err = w.write(ctx, body)
+		if err != nil && retry.Retryable(err) {
+			return
+		}
+
+		if err != nil {
+			w.result(Dropped, lines(body), err)
+		} else {
+			w.result(Written, lines(body), nil)
+		}
+
+		err = w.spool.remove(name)
+		if err != nil {
+			w.result(Spooled, 0, err)
+			return
+		}
+	}
+}
+
+func (w *Writer) shutdown(batch []Point) {
+	for {
+		select {
+		case p := <-w.queue:
+			batch = append(batch, p)
+		default:
+			if len(batch) > 0 {
+				w.save(encode(batch, w.result), context.Canceled)
+			}
+			return
+		}
+	}
+}
+
+//save spools the batch that failed with err, or drops it without a spool
+func (w *Writer) save(body []byte, err error) {
+	if w.spool == nil {
+		w.result(Dropped, lines(body), err)
+		return
+	}
+
+	trimmed, spoolErr := w.spool.push(body)
+	if spoolErr != nil {
+		w.result(Dropped, lines(body), fmt.Errorf("unable to spool after %v: %w", err, spoolErr))
+		return
+	}
+
+	w.result(Spooled, lines(body), err)
+	if trimmed > 0 {
+		w.result(Dropped, trimmed, fmt.Errorf("spool is over %v bytes", w.config.MaxSpoolBytes))
+	}
+}
+
+func (w *Writer) write(ctx context.Context, body []byte) error {
+	return retry.Backoff(ctx, w.Retries, w.Backoff, func() error {
+		return w.post(ctx, body)
+	})
+}
+
+func (w *Writer) post(ctx context.Context, body []byte) error {
+	timeout, clean := context.WithTimeout(ctx, time.Second*10)
+	defer clean()
+
+	query := url.Values{}
+	query.Set("org", w.config.Org)
+	query.Set("bucket", w.config.Bucket)
+	query.Set("precision", "ns")
+
+	req, err := http.NewRequestWithContext(timeout, http.MethodPost, strings.TrimSuffix(w.config.URL, "/")+"/api/v2/write?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.config.Token)
+	}
+
+	return retry.Post(w.client, req, "influx write", retry.ServerErrors)
+}
+
+func (w *Writer) result(outcome string, points int, err error) {
+	if w.Result != nil {
+		w.Result(outcome, points, err)
+	}
+}
+
+//encode is the batch as line protocol, points that cannot be encoded are dropped
+func encode(batch []Point, result func(string, int, error)) []byte {
+	var body []byte
+	for _, p := range batch {
+		line, err := p.AppendLine(body)
+		if err != nil {
+			result(Dropped, 1, err)
+			continue
+		}
+
+		body = line
+	}
+
+	return body
+}
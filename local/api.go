@@ -10,8 +10,15 @@ import (
 
 //New returns an API with a default http client and the provided config
 func New(config Config) API {
+	client := &http.Client{}
+	if config.TLS != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config.TLS
+		client.Transport = transport
+	}
+
 	return API{
-		Client: &http.Client{},
+		Client: client,
 		Config: config,
 	}
 }
@@ -39,7 +46,7 @@ func (a API) GetMeterHardwareAddress(ctx context.Context) (string, error) {
 		models = append(models, device.ModelID)
 	}
 
-	return "", fmt.Errorf("no %v found in device list: %v", search, models)
+	return "", &DeviceNotFoundError{ModelID: search, Models: models}
 }
 
 //DeviceDetails returns the available variables
@@ -65,11 +72,21 @@ func (a API) DeviceQuery(ctx context.Context, hardwareAddress string, variables
 	}
 
 	if len(toquery) < 1 {
-		return deviceResponse, fmt.Errorf("Post filter (%v) no variables were available: %v", filter, variables)
+		return deviceResponse, &UnsupportedVariableError{Variables: variables, Reason: fmt.Sprintf("excluded by filter %v", filter), Invalid: true}
 	}
 
 	err := a.post(ctx, NewDeviceQueryCommand(hardwareAddress, toquery...), &deviceResponse)
-	return deviceResponse, err
+	if err != nil {
+		return deviceResponse, err
+	}
+
+	//the eagle will happily answer with stale values for a device it can no longer see
+	status := deviceResponse.DeviceDetails.ConnectionStatus
+	if status != "" && !status.IsConnected() {
+		return deviceResponse, &MeterUnreachableError{HardwareAddress: hardwareAddress, ConnectionStatus: status}
+	}
+
+	return deviceResponse, nil
 }
 
 //DeviceList returns the configured devices
@@ -89,11 +106,11 @@ func (a API) WifiStatus(ctx context.Context) (WifiStatus, error) {
 func (a API) post(ctx context.Context, command interface{}, result interface{}) error {
 	code, body, err := PostCommand(ctx, a.Client, a.Config, command)
 	if err != nil {
-		return fmt.Errorf("%v %v\n %s", code, err, body)
+		return err
 	}
 
 	if a.Config.DebugResponse {
-		log.Printf("%v - %s", code, body)
+		log.Print(redactConfig(a.Config, fmt.Sprintf("%v - %s", code, body)))
 	}
 
 	return unmarshal(code, body, result)
@@ -102,18 +119,8 @@ func (a API) post(ctx context.Context, command interface{}, result interface{})
 func unmarshal(code int, body []byte, v interface{}) error {
 	err := xml.Unmarshal(body, v)
 	if err != nil {
-		return &unmarshalError{code, body, err}
+		return &MalformedXMLError{Code: code, Body: body, Err: err}
 	}
 
 	return nil
 }
-
-type unmarshalError struct {
-	code int
-	body []byte
-	err  error
-}
-
-func (u *unmarshalError) Error() string {
-	return fmt.Sprintf("unable to unmarshal %v: %v - %s", u.err, u.code, u.body)
-}
@@ -2,8 +2,12 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -117,3 +121,75 @@ func TestVariables(t *testing.T) {
 	}
 
 }
+
+func TestErrorTypes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("authentication", func(t *testing.T) {
+		ts, config := statusServer(http.StatusUnauthorized, "<html>401 Unauthorized</html>")
+		defer ts.Close()
+
+		_, err := New(config).WifiStatus(ctx)
+		var auth *AuthenticationError
+		require.True(t, errors.As(err, &auth), fmt.Sprintf("%v", err))
+		assert.Equal(t, "authentication", ErrorKind(err))
+	})
+
+	t.Run("malformed_xml", func(t *testing.T) {
+		ts, config := statusServer(http.StatusOK, "<WiFiStatus><Enabled>")
+		defer ts.Close()
+
+		_, err := New(config).WifiStatus(ctx)
+		var malformed *MalformedXMLError
+		require.True(t, errors.As(err, &malformed), fmt.Sprintf("%v", err))
+		assert.Equal(t, "malformed_xml", ErrorKind(err))
+	})
+
+	t.Run("gateway_unreachable", func(t *testing.T) {
+		api := New(Config{Location: "127.0.0.1:1"})
+
+		_, err := api.WifiStatus(ctx)
+		var gateway *GatewayUnreachableError
+		require.True(t, errors.As(err, &gateway), fmt.Sprintf("%v", err))
+		assert.Equal(t, "gateway_unreachable", ErrorKind(err))
+	})
+
+	t.Run("device_not_found", func(t *testing.T) {
+		ts, config := StartTestServer(ServeDeviceList([]Device{{DeviceData: DeviceData{ModelID: "thermostat"}}}))
+		defer ts.Close()
+
+		_, err := New(config).GetMeterHardwareAddress(ctx)
+		var notFound *DeviceNotFoundError
+		require.True(t, errors.As(err, &notFound), fmt.Sprintf("%v", err))
+		assert.Equal(t, []string{"thermostat"}, notFound.Models)
+	})
+
+	t.Run("meter_unreachable", func(t *testing.T) {
+		ts, config := StartTestServer(ServeDeviceQuery(DeviceQueryResponse{DeviceDetails: DeviceDetails{DeviceData: DeviceData{ConnectionStatus: NotJoined}}}))
+		defer ts.Close()
+
+		_, err := New(config).DeviceQuery(ctx, "0x01", "zigbee:InstantaneousDemand")
+		var meter *MeterUnreachableError
+		require.True(t, errors.As(err, &meter), fmt.Sprintf("%v", err))
+		assert.Equal(t, NotJoined, meter.ConnectionStatus)
+	})
+
+	t.Run("unsupported_variable", func(t *testing.T) {
+		_, err := New(Config{Filter: BadResponseVariables}).DeviceQuery(ctx, "0x01", "zigbee:Divisor")
+		var variable *UnsupportedVariableError
+		require.True(t, errors.As(err, &variable), fmt.Sprintf("%v", err))
+		assert.Equal(t, []string{"zigbee:Divisor"}, variable.Variables)
+		assert.True(t, InvalidVariable(err), "asking for a filtered variable is the caller's problem")
+		assert.False(t, InvalidVariable(&UnsupportedVariableError{Reason: "not present in device query response"}))
+	})
+}
+
+func statusServer(code int, body string) (*httptest.Server, Config) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+
+	u, _ := url.Parse(ts.URL)
+	return ts, Config{Location: u.Host}
+}
@@ -3,10 +3,15 @@ package local
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,3 +39,102 @@ func TestPostCommand(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, code)
 }
+
+func TestPostCommandStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("server_error", func(t *testing.T) {
+		ts, config := statusServer(http.StatusInternalServerError, "<html>oops</html>")
+		defer ts.Close()
+
+		code, _, err := PostCommand(ctx, &http.Client{}, config, NewDeviceListCommand())
+		var server *ServerError
+		require.True(t, errors.As(err, &server), fmt.Sprintf("%v", err))
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		ts, config := statusServer(http.StatusOK, strings.Repeat("x", 100))
+		defer ts.Close()
+
+		config.MaxResponseBytes = 10
+		_, body, err := PostCommand(ctx, &http.Client{}, config, NewDeviceListCommand())
+		var tooLarge *ResponseTooLargeError
+		require.True(t, errors.As(err, &tooLarge), fmt.Sprintf("%v", err))
+		assert.Len(t, body, 10)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer ts.Close()
+		defer close(release)
+
+		u, _ := url.Parse(ts.URL)
+		timeout, clean := context.WithTimeout(ctx, time.Millisecond*50)
+		defer clean()
+
+		_, _, err := PostCommand(timeout, &http.Client{}, Config{Location: u.Host}, NewDeviceListCommand())
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), fmt.Sprintf("%v", err))
+	})
+
+	t.Run("https", func(t *testing.T) {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<DeviceList></DeviceList>")
+		}))
+		defer ts.Close()
+
+		u, _ := url.Parse(ts.URL)
+		config := Config{Location: u.Host, HTTPS: true, TLS: ts.Client().Transport.(*http.Transport).TLSClientConfig}
+
+		_, err := New(config).DeviceList(ctx)
+		require.NoError(t, err)
+
+		config.TLS = nil
+		_, err = New(config).DeviceList(ctx)
+		assert.Error(t, err, "self signed certificate should not be trusted without tls config")
+	})
+}
+
+func TestParseProxiedCommand(t *testing.T) {
+	query := []byte(`<Command>
+  <Name>device_query</Name>
+  <DeviceDetails><HardwareAddress>0x0013500100F5AC41</HardwareAddress></DeviceDetails>
+  <Components><Component><Name>Main</Name><Variables>
+    <Variable><Name>zigbee:Price</Name></Variable>
+    <Variable><Name>zigbee:InstantaneousDemand</Name></Variable>
+  </Variables></Component></Components>
+</Command>`)
+
+	command, err := ParseProxiedCommand(query)
+	require.NoError(t, err)
+	assert.Equal(t, "device_query", command.Name)
+	assert.Equal(t, "0x0013500100f5ac41", command.HardwareAddress)
+	assert.Equal(t, []string{"zigbee:InstantaneousDemand", "zigbee:Price"}, command.Variables)
+	assert.Equal(t, query, command.Body, "the body is forwarded as sent")
+
+	reordered, err := xml.Marshal(NewDeviceQueryCommand("0x0013500100F5AC41", "zigbee:InstantaneousDemand", "zigbee:Price"))
+	require.NoError(t, err)
+	same, err := ParseProxiedCommand(reordered)
+	require.NoError(t, err)
+	assert.Equal(t, command.Key(), same.Key())
+
+	all, err := ParseProxiedCommand([]byte(`<Command><Name>device_query</Name><DeviceDetails><HardwareAddress>0x0013500100F5AC41</HardwareAddress></DeviceDetails><Components><All>Y</All></Components></Command>`))
+	require.NoError(t, err)
+	assert.True(t, all.All)
+	assert.NotEqual(t, command.Key(), all.Key())
+
+	json, err := ParseProxiedCommand([]byte(`<Command><Name>device_list</Name><Format>JSON</Format></Command>`))
+	require.NoError(t, err)
+	list, err := ParseProxiedCommand([]byte(`<Command><Name>device_list</Name></Command>`))
+	require.NoError(t, err)
+	assert.NotEqual(t, list.Key(), json.Key(), "the format changes the response")
+
+	_, err = ParseProxiedCommand([]byte(`<Command><Name>set_setting</Name></Command>`))
+	assert.Error(t, err, "only commands that read are proxied")
+
+	_, err = ParseProxiedCommand([]byte(`not xml`))
+	assert.Error(t, err)
+}
@@ -1,7 +1,11 @@
 package local
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
@@ -14,6 +18,10 @@ const (
 	UserEnv string = "REAGLE_LOCAL_USER"
 	//PasswordEnv is the name of the environment variable that stores the password for the local api authentication, usually the install code of the eagle device
 	PasswordEnv string = "REAGLE_LOCAL_PASSWORD"
+	//PasswordFileEnv is the name of the environment variable that stores the path to a file holding the password, such as a docker secret
+	PasswordFileEnv string = "REAGLE_LOCAL_PASSWORD_FILE"
+	//PasswordCommandEnv is the name of the environment variable that stores a command that outputs the password
+	PasswordCommandEnv string = "REAGLE_LOCAL_PASSWORD_COMMAND"
 
 	//DebugRequestEnv will turn on request debugging if it is any value other than empty
 	DebugRequestEnv string = "REAGLE_DEBUG_REQUEST"
@@ -23,22 +31,35 @@ const (
 	//ImprovedFirmwareEnv set to yes if your firmware responds with well formed queries to multiplier and divisor queries, set to no if not
 	ImprovedFirmwareEnv string = "REAGLE_IMPROVED_FIRMWARE"
 
+	//HTTPSEnv set to true to talk to the eagle over https instead of http
+	HTTPSEnv string = "REAGLE_LOCAL_HTTPS"
+	//CAFileEnv is a pem file of certificate authorities to trust when using https, the eagle ships with a self signed certificate
+	CAFileEnv string = "REAGLE_LOCAL_CA_FILE"
+	//InsecureSkipVerifyEnv set to true to skip verification of the eagle's certificate when using https
+	InsecureSkipVerifyEnv string = "REAGLE_LOCAL_INSECURE_SKIP_VERIFY"
+
 	//MeterModelIDEnv is the name of the 'model_id' returned by the device for the smart meter being watched.  defaults to 'electric_meter' if not set
 	MeterModelIDEnv string = "REAGLE_MODEL_ID_NAME"
 )
 
-//TestConfigOrSkip returns teh Config from the environment variables or skips if any aren't set
+//TestConfigOrSkip returns teh Config from the environment variables, skips if any aren't set and fails if they are set but
+//can not be used
 func TestConfigOrSkip(t testing.TB) Config {
-	config, ok := ConfigFromEnv()
+	config, ok, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("The environment sets the config but it can not be used: %v", err)
+	}
+
 	if !ok {
-		t.Skipf("Skipping because one or more of [%v, %v, %v] is not set", LocationEnv, UserEnv, PasswordEnv)
+		t.Skipf("Skipping because one or more of [%v, %v, %v] is not set", LocationEnv, UserEnv, PasswordEnv+"(_FILE|_COMMAND)")
 	}
 
 	return config
 }
 
-//ConfigFromEnv returns a Config and true using the environment variables or a Config and false if any aren't set
-func ConfigFromEnv() (Config, bool) {
+//ConfigFromEnv returns a Config and true using the environment variables or a Config and false if any aren't set.  The
+//error is for variables that are set but can not be used, such as a missing ca or password file
+func ConfigFromEnv() (Config, bool, error) {
 	//unless affirmatively set assume that they have the improved firmware with the bug around variables
 	improved := strings.ToLower(strings.TrimSpace(os.Getenv(ImprovedFirmwareEnv))) != "false"
 
@@ -47,10 +68,29 @@ func ConfigFromEnv() (Config, bool) {
 		filter = NoFilter
 	}
 
+	https := strings.TrimSpace(os.Getenv(HTTPSEnv)) == "true"
+
+	var tlsConfig *tls.Config
+	if https {
+		var err error
+		tlsConfig, err = TLSConfig(strings.TrimSpace(os.Getenv(CAFileEnv)), strings.TrimSpace(os.Getenv(InsecureSkipVerifyEnv)) == "true")
+		if err != nil {
+			return Config{}, false, fmt.Errorf("unable to configure tls: %v", err)
+		}
+	}
+
+	credentials, err := CredentialsFrom(os.Getenv(PasswordEnv), os.Getenv(PasswordFileEnv), os.Getenv(PasswordCommandEnv))
+	if err != nil {
+		return Config{}, false, fmt.Errorf("unable to configure credentials: %v", err)
+	}
+
 	config := Config{
-		Location: os.Getenv(LocationEnv),
-		User:     os.Getenv(UserEnv),
-		password: os.Getenv(PasswordEnv),
+		Location:    os.Getenv(LocationEnv),
+		User:        os.Getenv(UserEnv),
+		credentials: credentials,
+
+		HTTPS: https,
+		TLS:   tlsConfig,
 
 		ImprovedFirmware: improved,
 
@@ -62,34 +102,74 @@ func ConfigFromEnv() (Config, bool) {
 		DebugResponse: strings.TrimSpace(os.Getenv(DebugResponseEnv)) == "true",
 	}
 
-	return config, ConfigOK(config)
+	if !ConfigOK(config) {
+		return config, false, nil
+	}
+
+	//the password has to load too, such as a password file that exists
+	err = ValidateConfig(config)
+	if err != nil {
+		return config, false, err
+	}
+
+	return config, true, nil
 }
 
 //ConfigOK returns true if the Config can be used
 func ConfigOK(config Config) bool {
-	return config.Location != "" && config.User != "" && config.password != ""
+	return config.Location != "" && config.User != "" && config.credentials != nil
 }
 
-//ValidateConfig returns an error if the Config is not ready for use
+//ValidateConfig returns an error if the Config is not ready for use, including when the password can not be loaded
 func ValidateConfig(c Config) error {
 	if !ConfigOK(c) {
-		return fmt.Errorf("Must provide %s, %s, %s: (%s, %s, '*')", LocationEnv, UserEnv, PasswordEnv, c.Location, c.User)
+		return fmt.Errorf("Must provide %s, %s, %s or %s or %s: (%s, %s, '*')", LocationEnv, UserEnv, PasswordEnv, PasswordFileEnv, PasswordCommandEnv, c.Location, c.User)
+	}
+
+	_, err := c.password()
+	if err != nil {
+		return &CredentialsError{Err: err}
 	}
 
 	return nil
 }
 
-//SetPassword sets the password on the config
+//SetPassword sets a password that does not change on the config
 func SetPassword(c Config, password string) Config {
-	c.password = password
+	c.credentials = nil
+	if password != "" {
+		c.credentials = StaticPassword(password)
+	}
+
+	return c
+}
+
+//SetCredentials sets where the config gets the password from
+func SetCredentials(c Config, credentials Credentials) Config {
+	c.credentials = credentials
 	return c
 }
 
+//Authenticate returns true if the user and password are the eagle's, for things that stand in for the eagle
+func Authenticate(c Config, user string, password string) bool {
+	expected, err := c.password()
+	if err != nil || expected == "" {
+		return false
+	}
+
+	//both are compared so the time does not say which was wrong
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(c.User)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+	return userOK && passwordOK
+}
+
 //Config is used to locate/auth the eagle local api
 type Config struct {
 	Location string `json:"location"`
 	User     string `json:"user"`
-	password string
+
+	//set with SetPassword or SetCredentials so it is never serialized
+	credentials Credentials
 
 	//Older versions of the firmware respond with invalid xml for multiplier/divisor
 	ImprovedFirmware bool `json:"improved_firmware"`
@@ -101,6 +181,65 @@ type Config struct {
 
 	//what the eagle returns for the model id of the smart meter to watch.  defaults to electric_meter
 	ModelIDForMeter string `json:"model_id"`
+
+	//HTTPS switches the post manager endpoint to https, TLS is used for the connection if set
+	HTTPS bool        `json:"https"`
+	TLS   *tls.Config `json:"-"`
+
+	//responses larger than this are rejected, defaults to DefaultMaxResponseBytes
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+}
+
+//TLSConfig builds a tls.Config for talking to the eagle.  caFile is optional and adds the pem encoded certificates to
+//the trusted roots
+func TLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return config, nil
+}
+
+//password is empty when there are no credentials, ValidateConfig is what requires them
+func (c Config) password() (string, error) {
+	if c.credentials == nil {
+		return "", nil
+	}
+
+	return c.credentials.Password()
+}
+
+//refresh rereads the password after the eagle rejected it, returning the password to use next
+func (c Config) refresh() (string, error) {
+	if c.credentials == nil {
+		return "", nil
+	}
+
+	err := c.credentials.Refresh()
+	if err != nil {
+		return "", err
+	}
+
+	return c.credentials.Password()
+}
+
+func (c Config) GetMaxResponseBytes() int64 {
+	if c.MaxResponseBytes <= 0 {
+		return DefaultMaxResponseBytes
+	}
+
+	return c.MaxResponseBytes
 }
 
 func (c Config) GetModelIDForMeter() string {
@@ -0,0 +1,57 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setEnv(t *testing.T, values map[string]string) func() {
+	previous := make(map[string]string)
+	for name, value := range values {
+		previous[name] = os.Getenv(name)
+		require.NoError(t, os.Setenv(name, value))
+	}
+
+	return func() {
+		for name, value := range previous {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	restore := setEnv(t, map[string]string{
+		LocationEnv:        "",
+		UserEnv:            "",
+		PasswordEnv:        "",
+		PasswordFileEnv:    "",
+		PasswordCommandEnv: "",
+		HTTPSEnv:           "",
+		CAFileEnv:          "",
+	})
+	defer restore()
+
+	_, ok, err := ConfigFromEnv()
+	require.NoError(t, err, "nothing set is not an error, the integration tests skip")
+	assert.False(t, ok)
+
+	setEnv(t, map[string]string{LocationEnv: "eagle", UserEnv: "cloudid", PasswordEnv: "installcode"})
+	config, ok, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "eagle", config.Location)
+
+	setEnv(t, map[string]string{HTTPSEnv: "true", CAFileEnv: filepath.Join(os.TempDir(), "reagle-missing-ca.pem")})
+	_, ok, err = ConfigFromEnv()
+	assert.Error(t, err, "a ca file that can not be read is not the same as not being set")
+	assert.False(t, ok)
+
+	setEnv(t, map[string]string{HTTPSEnv: "", PasswordEnv: "", PasswordFileEnv: filepath.Join(os.TempDir(), "reagle-missing-password")})
+	_, ok, err = ConfigFromEnv()
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,152 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+//passwordCommandTimeout bounds a password command, it runs while the password is locked so one that hangs, such as a
+//password manager waiting to be unlocked, would otherwise block every command to the eagle
+var passwordCommandTimeout = 10 * time.Second
+
+//Credentials supplies the eagle password.  Password is called for every command so it should be cheap, Refresh is called
+//when the eagle rejects the password so a provider that caches it can pick up one that has been rotated
+type Credentials interface {
+	Password() (string, error)
+	Refresh() error
+}
+
+//StaticPassword is a password that never changes, such as one given on the command line
+type StaticPassword string
+
+//Password returns the password
+func (p StaticPassword) Password() (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("password is empty")
+	}
+
+	return string(p), nil
+}
+
+//Refresh does nothing, there is nowhere to reread a static password from
+func (p StaticPassword) Refresh() error {
+	return nil
+}
+
+//FilePassword reads the password from a file, such as a docker or kubernetes secret, and rereads it when the eagle rejects
+//it.  Surrounding whitespace is trimmed since secrets are often written with a trailing newline
+func FilePassword(path string) Credentials {
+	return &cachedCredentials{load: func() (string, error) {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		password := strings.TrimSpace(string(contents))
+		if password == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+
+		return password, nil
+	}}
+}
+
+//CommandPassword runs the command and uses what it writes to stdout as the password, such as a password manager's cli.  It
+//is run again when the eagle rejects the password and is killed if it takes longer than 10 seconds
+func CommandPassword(name string, args ...string) Credentials {
+	return &cachedCredentials{load: func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+		defer cancel()
+
+		//stderr is not included in the error, there is no telling what a password manager writes there
+		out, err := exec.CommandContext(ctx, name, args...).Output()
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s did not finish within %v", name, passwordCommandTimeout)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("%s failed: %v", name, err)
+		}
+
+		password := strings.TrimSpace(string(out))
+		if password == "" {
+			return "", fmt.Errorf("%s did not output a password", name)
+		}
+
+		return password, nil
+	}}
+}
+
+//CredentialsFrom returns the Credentials for whichever of a password, a password file or a password command is set.
+//Setting more than one is an error since it is not clear which is meant.  The command is split on whitespace, it is not
+//run by a shell
+func CredentialsFrom(password string, file string, command string) (Credentials, error) {
+	var (
+		credentials Credentials
+		set         int
+	)
+
+	if password != "" {
+		credentials = StaticPassword(password)
+		set++
+	}
+
+	if file != "" {
+		credentials = FilePassword(file)
+		set++
+	}
+
+	if fields := strings.Fields(command); len(fields) > 0 {
+		credentials = CommandPassword(fields[0], fields[1:]...)
+		set++
+	}
+
+	if set > 1 {
+		return nil, fmt.Errorf("only one of a password, password file or password command can be set")
+	}
+
+	return credentials, nil
+}
+
+//cachedCredentials loads the password the first time it is needed and again only when refreshed.  A failed refresh keeps
+//the previous password
+type cachedCredentials struct {
+	load func() (string, error)
+
+	mu       sync.Mutex
+	password string
+}
+
+func (c *cachedCredentials) Password() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.password != "" {
+		return c.password, nil
+	}
+
+	return c.reload()
+}
+
+func (c *cachedCredentials) Refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.reload()
+	return err
+}
+
+func (c *cachedCredentials) reload() (string, error) {
+	password, err := c.load()
+	if err != nil {
+		return "", err
+	}
+
+	c.password = password
+	return password, nil
+}
@@ -0,0 +1,166 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsFrom(t *testing.T) {
+	credentials, err := CredentialsFrom("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, credentials)
+
+	credentials, err = CredentialsFrom("installcode", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, StaticPassword("installcode"), credentials)
+
+	credentials, err = CredentialsFrom("", "", "echo  installcode ")
+	require.NoError(t, err)
+	password, err := credentials.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "installcode", password)
+
+	_, err = CredentialsFrom("installcode", "/run/secrets/eagle", "")
+	assert.Error(t, err)
+}
+
+func TestFilePassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	credentials := FilePassword(path)
+
+	_, err = credentials.Password()
+	assert.Error(t, err, "the file has to exist")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+	password, err := credentials.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "first", password)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("second\n"), 0600))
+	password, err = credentials.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "first", password, "only reread when refreshed")
+
+	require.NoError(t, credentials.Refresh())
+	password, err = credentials.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "second", password)
+
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, credentials.Refresh())
+	password, err = credentials.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "second", password, "a failed refresh keeps the previous password")
+}
+
+func TestCommandPassword(t *testing.T) {
+	_, err := CommandPassword("false").Password()
+	assert.Error(t, err)
+
+	_, err = CommandPassword("true").Password()
+	assert.Error(t, err, "no output is not a password")
+}
+
+func TestCommandPasswordTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { passwordCommandTimeout = timeout }(passwordCommandTimeout)
+	passwordCommandTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := CommandPassword("sleep", "5").Password()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "did not finish")
+	assert.True(t, time.Since(start) < 5*time.Second, "a hanging command is killed")
+}
+
+func TestPostCommandRefreshesPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old"), 0600))
+
+	current := "old"
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		_, password, _ := r.BasicAuth()
+		if password != current {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, "<DeviceList></DeviceList>")
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	config := SetCredentials(Config{Location: u.Host, User: "cloudid"}, FilePassword(path))
+	ctx := context.Background()
+
+	_, err = New(config).DeviceList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+
+	//rotated on the eagle and then in the secret
+	current = "new"
+	require.NoError(t, ioutil.WriteFile(path, []byte("new"), 0600))
+
+	_, err = New(config).DeviceList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts, "rejected once then sent again with the reread password")
+
+	//rotated on the eagle but not yet in the secret, the same password is not sent twice
+	current = "newer"
+	_, err = New(config).DeviceList(ctx)
+	assert.Equal(t, "authentication", ErrorKind(err))
+	assert.Equal(t, 4, attempts)
+
+	require.NoError(t, os.Remove(path))
+	_, _, err = PostCommand(ctx, &http.Client{}, SetCredentials(config, FilePassword(path)), NewDeviceListCommand())
+	assert.Equal(t, "authentication", ErrorKind(err), "a password that can not be loaded is an authentication problem")
+	assert.Equal(t, 4, attempts, "nothing is sent without a password")
+}
+
+func TestDebugLoggingIsRedacted(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	//an eagle that echoes the request, as an error page might
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	config := SetPassword(Config{Location: u.Host, User: "cloudid", DebugRequest: true, DebugResponse: true}, "installcode")
+
+	_, _, err := PostCommandBody(context.Background(), &http.Client{}, config, []byte("<Command><Name>installcode</Name></Command>"))
+	require.NoError(t, err)
+
+	//the echoed command is not a wifi status but the response is logged before it is unmarshalled
+	New(config).WifiStatus(context.Background())
+
+	assert.Contains(t, logged.String(), "<Name>*****</Name>")
+	assert.Contains(t, logged.String(), "wifi_status")
+	assert.NotContains(t, logged.String(), "installcode")
+}
@@ -2,35 +2,23 @@ package local
 
 import (
 	"encoding/xml"
-	"strconv"
 	"time"
 )
 
 //DeviceData is the data about a device, it is named Device in some responses and DeviceDetails in others
 type DeviceData struct {
-	HardwareAddress  string `json:"hardware_address"`
-	Manufacturer     string `json:"manufacturer"`
-	ModelID          string `xml:"ModelId" json:"model_id"`
-	Protocol         string `json:"protocol"`
-	LastContact      string `json:"last_contact"`
-	ConnectionStatus string `json:"connection_status"`
-	NetworkAddress   string `json:"network_address"`
+	HardwareAddress  string           `json:"hardware_address"`
+	Manufacturer     string           `json:"manufacturer"`
+	ModelID          string           `xml:"ModelId" json:"model_id"`
+	Protocol         string           `json:"protocol"`
+	LastContact      Timestamp        `json:"last_contact"`
+	ConnectionStatus ConnectionStatus `json:"connection_status"`
+	NetworkAddress   HexInt           `json:"network_address"`
 }
 
-//LastContactTime parses the contact time string into a golang time
-//TODO: change this so the unmarshaller does it
-func (item DeviceData) LastContactTime() (t time.Time, err error) {
-	var (
-		i int64
-	)
-
-	i, err = strconv.ParseInt(item.LastContact, 0, 64)
-	if err != nil {
-		return
-	}
-
-	t = time.Unix(i, 0)
-	return
+//LastContactTime returns the last contact as a golang time, it is kept for callers from before the unmarshaller did the parsing
+func (item DeviceData) LastContactTime() (time.Time, error) {
+	return item.LastContact.Time, nil
 }
 
 //Device is sometimes used
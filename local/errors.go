@@ -0,0 +1,170 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//AuthenticationError is returned when the eagle rejects the configured user/password
+type AuthenticationError struct {
+	Code int
+	User string
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("eagle rejected credentials for user %s: %v", e.User, e.Code)
+}
+
+//CredentialsError is returned when the password can not be loaded, such as a missing password file or a failing password
+//command.  It is counted as an authentication error since the fix is the same
+type CredentialsError struct {
+	Err error
+}
+
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("unable to load eagle password: %v", e.Err)
+}
+
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
+
+//ServerError is returned when the eagle responds with an unexpected status code, usually a 5xx with an html body
+type ServerError struct {
+	Code int
+	Body []byte
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("eagle responded with %v: %s", e.Code, e.Body)
+}
+
+//ResponseTooLargeError is returned when the eagle response is larger than the configured limit
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("eagle response exceeded %v bytes", e.Limit)
+}
+
+//DeviceNotFoundError is returned when the device list does not contain the expected model id
+type DeviceNotFoundError struct {
+	ModelID string
+	Models  []string
+}
+
+func (e *DeviceNotFoundError) Error() string {
+	return fmt.Sprintf("no %v found in device list: %v", e.ModelID, e.Models)
+}
+
+//UnsupportedVariableError is returned when a variable can not be queried, either because it is filtered or because the device does not report it
+type UnsupportedVariableError struct {
+	Variables []string
+	Reason    string
+
+	//Invalid is set when the variables asked for can not be queried, rather than the device not reporting them
+	Invalid bool
+}
+
+func (e *UnsupportedVariableError) Error() string {
+	return fmt.Sprintf("unsupported variables [%s]: %s", strings.Join(e.Variables, ", "), e.Reason)
+}
+
+//InvalidVariable returns true if the error is from asking for variables that can not be queried, which is the caller's
+//problem.  A device that does not report a variable is not
+func InvalidVariable(err error) bool {
+	var variable *UnsupportedVariableError
+	return errors.As(err, &variable) && variable.Invalid
+}
+
+//MalformedXMLError is returned when the eagle responds with something that can not be unmarshalled
+type MalformedXMLError struct {
+	Code int
+	Body []byte
+	Err  error
+}
+
+func (e *MalformedXMLError) Error() string {
+	return fmt.Sprintf("unable to unmarshal %v: %v - %s", e.Err, e.Code, e.Body)
+}
+
+func (e *MalformedXMLError) Unwrap() error {
+	return e.Err
+}
+
+//MeterUnreachableError is returned when the eagle responds but reports that it has lost contact with the device
+type MeterUnreachableError struct {
+	HardwareAddress  string
+	ConnectionStatus ConnectionStatus
+}
+
+func (e *MeterUnreachableError) Error() string {
+	return fmt.Sprintf("eagle reports %s as %s", e.HardwareAddress, e.ConnectionStatus)
+}
+
+//GatewayUnreachableError is returned when the eagle itself can not be contacted
+type GatewayUnreachableError struct {
+	Location string
+	Err      error
+}
+
+func (e *GatewayUnreachableError) Error() string {
+	return fmt.Sprintf("unable to reach eagle at %s: %v", e.Location, e.Err)
+}
+
+func (e *GatewayUnreachableError) Unwrap() error {
+	return e.Err
+}
+
+//ErrorKind returns a short, metric label safe, name for the kind of error.  Errors not defined by this package are "other"
+func ErrorKind(err error) string {
+	var (
+		auth     *AuthenticationError
+		creds    *CredentialsError
+		server   *ServerError
+		tooLarge *ResponseTooLargeError
+		notFound *DeviceNotFoundError
+		variable *UnsupportedVariableError
+		xmlErr   *MalformedXMLError
+		meter    *MeterUnreachableError
+		gateway  *GatewayUnreachableError
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &auth), errors.As(err, &creds):
+		return "authentication"
+	case errors.As(err, &server):
+		return "server_error"
+	case errors.As(err, &tooLarge):
+		return "response_too_large"
+	case errors.As(err, &notFound):
+		return "device_not_found"
+	case errors.As(err, &variable):
+		return "unsupported_variable"
+	case errors.As(err, &xmlErr):
+		return "malformed_xml"
+	case errors.As(err, &meter):
+		return "meter_unreachable"
+	case errors.As(err, &gateway):
+		return "gateway_unreachable"
+	default:
+		return "other"
+	}
+}
+
+//ErrorKinds are all of the values ErrorKind can return for a non nil error
+var ErrorKinds = []string{
+	"authentication",
+	"server_error",
+	"response_too_large",
+	"device_not_found",
+	"unsupported_variable",
+	"malformed_xml",
+	"meter_unreachable",
+	"gateway_unreachable",
+	"other",
+}
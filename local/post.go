@@ -4,49 +4,141 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 )
 
+//DefaultMaxResponseBytes is used when the Config does not set MaxResponseBytes.  The largest eagle responses (device details for
+//a chatty device) are a few kilobytes so this is generous
+const DefaultMaxResponseBytes int64 = 1 << 20
+
 //PostManagerEndpoint returns the url to the PostManagerEndpoint
 func PostManagerEndpoint(config Config) string {
-	return fmt.Sprintf("http://%s/cgi-bin/post_manager", config.Location)
+	scheme := "http"
+	if config.HTTPS {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s/cgi-bin/post_manager", scheme, config.Location)
 }
 
-//PostCommand posts the provided command to the location using the provided client
+//PostCommand posts the provided command to the location using the provided client.  The returned error is one of the
+//error types in this package when the eagle could not be reached or did not respond with a 2xx
 func PostCommand(ctx context.Context, client *http.Client, config Config, command interface{}) (code int, body []byte, err error) {
-	var (
-		commandBody []byte
-		req         *http.Request
-		resp        *http.Response
-	)
+	commandBody, err := xml.MarshalIndent(command, "  ", "   ")
+	if err != nil {
+		return
+	}
+
+	return PostCommandBody(ctx, client, config, commandBody)
+}
 
-	commandBody, err = xml.MarshalIndent(command, "  ", "   ")
+//PostCommandBody is PostCommand for a command that is already xml, such as one being proxied.  If the eagle rejects the
+//password the credentials are refreshed and the command is sent once more when that gives a different password, so a
+//rotated password is picked up without a restart
+func PostCommandBody(ctx context.Context, client *http.Client, config Config, commandBody []byte) (code int, body []byte, err error) {
+	password, err := config.password()
 	if err != nil {
+		err = &CredentialsError{Err: err}
 		return
 	}
 
+	code, body, err = post(ctx, client, config, password, commandBody)
+
+	var auth *AuthenticationError
+	if !errors.As(err, &auth) {
+		return
+	}
+
+	refreshed, refreshErr := config.refresh()
+	if refreshErr != nil || refreshed == password {
+		return
+	}
+
+	return post(ctx, client, config, refreshed, commandBody)
+}
+
+func post(ctx context.Context, client *http.Client, config Config, password string, commandBody []byte) (code int, body []byte, err error) {
+	var (
+		req  *http.Request
+		resp *http.Response
+	)
+
 	endpoint := PostManagerEndpoint(config)
 
 	if config.DebugRequest {
-		log.Printf("%s\n%s", endpoint, commandBody)
+		log.Print(redact(password, fmt.Sprintf("%s\n%s", endpoint, commandBody)))
 	}
 
-	req, err = http.NewRequest("POST", endpoint, bytes.NewReader(commandBody))
+	req, err = http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(commandBody))
 	if err != nil {
 		return
 	}
 
-	req.SetBasicAuth(config.User, config.password)
+	req.SetBasicAuth(config.User, password)
 
 	resp, err = client.Do(req)
 	if err != nil {
+		err = &GatewayUnreachableError{Location: config.Location, Err: err}
 		return
 	}
+	defer resp.Body.Close()
 
 	code = resp.StatusCode
-	body, err = ioutil.ReadAll(resp.Body)
+	body, err = readLimited(resp.Body, config.GetMaxResponseBytes())
+	if err != nil {
+		return
+	}
+
+	err = checkStatus(config, code, body)
 	return
 }
+
+//redact keeps the password out of debug logs.  It is never sent in a command but a proxied command or an eagle error page
+//could echo it back
+func redact(password string, s string) string {
+	if password == "" {
+		return s
+	}
+
+	return strings.Replace(s, password, "*****", -1)
+}
+
+//redactConfig is redact with whatever password the config currently has
+func redactConfig(config Config, s string) string {
+	password, err := config.password()
+	if err != nil {
+		return s
+	}
+
+	return redact(password, s)
+}
+
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return body, err
+	}
+
+	if int64(len(body)) > max {
+		return body[:max], &ResponseTooLargeError{Limit: max}
+	}
+
+	return body, nil
+}
+
+func checkStatus(config Config, code int, body []byte) error {
+	switch {
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return &AuthenticationError{Code: code, User: config.User}
+	case code < 200, code > 299:
+		return &ServerError{Code: code, Body: body}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package local
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//ProxiedCommands are the commands a proxy forwards, the same ones this package sends.  They only read from the eagle
+var ProxiedCommands = []string{"device_list", "device_details", "device_query", "wifi_status"}
+
+//ProxiedCommand is a post_manager command from another client.  Body is kept as it was sent so it can be forwarded
+//unchanged, the rest is what identifies the response it will get
+type ProxiedCommand struct {
+	Name            string
+	Format          string
+	HardwareAddress string
+	All             bool
+	Variables       []string
+	Body            []byte
+}
+
+//proxiedCommand is every part of a command that changes its response
+type proxiedCommand struct {
+	XMLName       xml.Name `xml:"Command"`
+	Name          string
+	Format        string
+	DeviceDetails struct {
+		HardwareAddress string
+	}
+	Components struct {
+		All       string
+		Component []struct {
+			Name      string
+			Variables struct {
+				Variable []struct {
+					Name string
+				}
+			}
+		}
+	}
+}
+
+//ParseProxiedCommand parses a command for forwarding, commands other than ProxiedCommands are rejected
+func ParseProxiedCommand(body []byte) (ProxiedCommand, error) {
+	parsed := proxiedCommand{}
+	err := xml.Unmarshal(body, &parsed)
+	if err != nil {
+		return ProxiedCommand{}, fmt.Errorf("invalid command: %w", err)
+	}
+
+	command := ProxiedCommand{
+		Name:            strings.TrimSpace(parsed.Name),
+		Format:          strings.ToLower(strings.TrimSpace(parsed.Format)),
+		HardwareAddress: strings.ToLower(strings.TrimSpace(parsed.DeviceDetails.HardwareAddress)),
+		All:             strings.EqualFold(strings.TrimSpace(parsed.Components.All), "Y"),
+		Body:            body,
+	}
+
+	supported := false
+	for _, name := range ProxiedCommands {
+		supported = supported || command.Name == name
+	}
+
+	if !supported {
+		return command, fmt.Errorf("unsupported command %q, only %s are proxied", command.Name, strings.Join(ProxiedCommands, ", "))
+	}
+
+	for _, component := range parsed.Components.Component {
+		for _, variable := range component.Variables.Variable {
+			command.Variables = append(command.Variables, strings.TrimSpace(variable.Name))
+		}
+	}
+	sort.Strings(command.Variables)
+
+	return command, nil
+}
+
+//Key is the same for commands that get the same response, however they were formatted
+func (c ProxiedCommand) Key() string {
+	return strings.Join([]string{c.Name, c.Format, c.HardwareAddress, fmt.Sprintf("%v", c.All), strings.Join(c.Variables, ",")}, "|")
+}
@@ -0,0 +1,216 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//UndefinedValue is what the eagle responds with for a variable it has not received from the device yet
+const UndefinedValue = "undefined"
+
+//Readings is variable name -> Reading for a single component
+type Readings map[string]Reading
+
+//Reading is a Variable with its value converted to a go type.  Value is nil if the eagle reported the variable as undefined,
+//a float64, int64, string or time.Time for the known zigbee variables and the raw string for everything else, including a
+//known variable whose value did not parse
+type Reading struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value"`
+	Units       string      `json:"units,omitempty"`
+	Raw         string      `json:"raw"`
+	Description string      `json:"description,omitempty"`
+}
+
+//Defined returns false if the eagle did not have a value for the variable
+func (r Reading) Defined() bool {
+	return r.Value != nil
+}
+
+//Float returns the value if it is a float64
+func (r Reading) Float() (float64, bool) {
+	f, ok := r.Value.(float64)
+	return f, ok
+}
+
+//Int returns the value if it is an int64
+func (r Reading) Int() (int64, bool) {
+	i, ok := r.Value.(int64)
+	return i, ok
+}
+
+//Text returns the value if it is a string
+func (r Reading) Text() (string, bool) {
+	s, ok := r.Value.(string)
+	return s, ok
+}
+
+//Time returns the value if it is a time.Time
+func (r Reading) Time() (time.Time, bool) {
+	t, ok := r.Value.(time.Time)
+	return t, ok
+}
+
+type readingConverter struct {
+	units string
+	parse func(string) (interface{}, error)
+}
+
+var (
+	kilowatts     = readingConverter{units: "kW", parse: parseFloat}
+	kilowattHours = readingConverter{units: "kWh", parse: parseFloat}
+	timestamp     = readingConverter{parse: parseTimestamp}
+
+	readingConverters = map[string]readingConverter{
+		"zigbee:InstantaneousDemand":          kilowatts,
+		"zigbee:CurrentSummationDelivered":    kilowattHours,
+		"zigbee:CurrentSummationReceived":     kilowattHours,
+		"zigbee:SummationDelivered":           kilowattHours,
+		"zigbee:SummationReceived":            kilowattHours,
+		"zigbee:Price":                        {units: "/kWh", parse: parseFloat},
+		"zigbee:PriceCurrency":                {parse: parseCurrency},
+		"zigbee:PriceTrailingDigits":          {parse: parseInt},
+		"zigbee:Message":                      {parse: parseText},
+		"zigbee:UtcTime":                      timestamp,
+		"zigbee:LocalTime":                    timestamp,
+		"zigbee:PriceStartTime":               timestamp,
+		"zigbee:MessageStartTime":             timestamp,
+		"zigbee:CurrentSummationReceivedTime": timestamp,
+	}
+
+	//the eagle reports the ISO 4217 numeric code, these are the ones we've seen or are likely to
+	currencyCodes = map[int64]string{
+		36:  "AUD",
+		124: "CAD",
+		554: "NZD",
+		826: "GBP",
+		840: "USD",
+		978: "EUR",
+	}
+)
+
+//NewReading converts the Variable into a Reading.  Variables this package doesn't know about are kept as raw strings
+func NewReading(variable Variable) (Reading, error) {
+	reading := Reading{
+		Name:        variable.Name,
+		Units:       variable.Units,
+		Raw:         variable.Value,
+		Description: variable.Description,
+	}
+
+	raw := strings.TrimSpace(variable.Value)
+	if raw == UndefinedValue {
+		return reading, nil
+	}
+
+	converter, known := readingConverters[variable.Name]
+	if !known {
+		reading.Value = variable.Value
+		return reading, nil
+	}
+
+	//some firmwares put the units in the value (e.g. 0.120 kW)
+	if fields := strings.Fields(raw); converter.units != "" && len(fields) == 2 {
+		raw = fields[0]
+		if reading.Units == "" {
+			reading.Units = fields[1]
+		}
+	}
+
+	if reading.Units == "" {
+		reading.Units = converter.units
+	}
+
+	value, err := converter.parse(raw)
+	if err != nil {
+		return reading, fmt.Errorf("%s:%s - %v", variable.Name, variable.Value, err)
+	}
+
+	reading.Value = value
+	return reading, nil
+}
+
+//ReadingsFromDetailsResponse is the typed counterpart of ResultsFromDetailsResponse, it returns a map of component name -> Readings.
+//A known variable that does not parse, such as a timestamp in a format a new firmware started using, is kept with the raw
+//string as its value so the rest of the response can still be used
+func ReadingsFromDetailsResponse(response DeviceQueryResponse) (map[string]Readings, error) {
+	readings := make(map[string]Readings)
+	for component, variables := range ResultsFromDetailsResponse(response) {
+		readings[component] = make(Readings)
+		for name, variable := range variables {
+			reading, err := NewReading(variable)
+			if err != nil {
+				log.Printf("keeping the raw value: %v", err)
+				reading.Value = variable.Value
+			}
+
+			readings[component][name] = reading
+		}
+	}
+
+	return readings, nil
+}
+
+func parseFloat(raw string) (interface{}, error) {
+	return strconv.ParseFloat(raw, 64)
+}
+
+func parseInt(raw string) (interface{}, error) {
+	return parseEagleInt(raw)
+}
+
+func parseText(raw string) (interface{}, error) {
+	return raw, nil
+}
+
+func parseCurrency(raw string) (interface{}, error) {
+	code, err := parseEagleInt(raw)
+	if err != nil {
+		//already an alphabetic code
+		return raw, nil
+	}
+
+	if alpha, ok := CurrencyCode(code); ok {
+		return alpha, nil
+	}
+
+	return raw, nil
+}
+
+//CurrencyCode is the ISO 4217 alphabetic code for the numeric code the eagle reports, if it is one this package knows
+func CurrencyCode(code int64) (string, bool) {
+	alpha, ok := currencyCodes[code]
+	return alpha, ok
+}
+
+//CurrencyNumber is the ISO 4217 numeric code for the alphabetic code, the inverse of CurrencyCode.  Codes this package
+//does not know are returned as they are, which is how the eagle reported them
+func CurrencyNumber(alpha string) string {
+	for code, known := range currencyCodes {
+		if known == alpha {
+			return strconv.FormatInt(code, 10)
+		}
+	}
+
+	return alpha
+}
+
+//timestamps are seconds since the unix epoch, in hex or decimal, though some firmwares respond with a formatted time
+func parseTimestamp(raw string) (interface{}, error) {
+	seconds, err := parseEagleInt(raw)
+	if err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		t, err := time.Parse(layout, raw)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized timestamp")
+}
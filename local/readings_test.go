@@ -0,0 +1,72 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReading(t *testing.T) {
+	for _, tc := range []struct {
+		variable Variable
+		value    interface{}
+		units    string
+	}{
+		{Variable{Name: "zigbee:InstantaneousDemand", Value: "0.120 kW"}, 0.12, "kW"},
+		{Variable{Name: "zigbee:InstantaneousDemand", Value: "1.5", Units: "kW"}, 1.5, "kW"},
+		{Variable{Name: "zigbee:CurrentSummationDelivered", Value: "12345.678"}, 12345.678, "kWh"},
+		{Variable{Name: "zigbee:Price", Value: "undefined"}, nil, "/kWh"},
+		{Variable{Name: "zigbee:PriceCurrency", Value: "840"}, "USD", ""},
+		{Variable{Name: "zigbee:PriceTrailingDigits", Value: "0x02"}, int64(2), ""},
+		{Variable{Name: "zigbee:Message", Value: "hello"}, "hello", ""},
+		{Variable{Name: "zigbee:UtcTime", Value: "0x59a0b67c"}, time.Unix(0x59a0b67c, 0).UTC(), ""},
+		{Variable{Name: "safeplug:RightplugState", Value: "on"}, "on", ""},
+	} {
+		t.Run(tc.variable.Name+"/"+tc.variable.Value, func(t *testing.T) {
+			reading, err := NewReading(tc.variable)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.value, reading.Value)
+			assert.Equal(t, tc.variable.Value, reading.Raw)
+			if tc.value != nil {
+				assert.Equal(t, tc.units, reading.Units)
+			}
+		})
+	}
+}
+
+func TestCurrencyNumber(t *testing.T) {
+	assert.Equal(t, "840", CurrencyNumber("USD"))
+	assert.Equal(t, "999", CurrencyNumber("999"), "unknown codes are kept as the eagle reported them")
+	assert.Equal(t, "", CurrencyNumber(""))
+}
+
+func TestNewReadingInvalid(t *testing.T) {
+	_, err := NewReading(Variable{Name: "zigbee:InstantaneousDemand", Value: "lots"})
+	assert.Error(t, err)
+}
+
+func TestReadingsKeepUnparsedValues(t *testing.T) {
+	response := DeviceQueryResponse{Components: NewComponents(Component{
+		Name: "Main",
+		Variables: NewVariables(
+			Variable{Name: "zigbee:InstantaneousDemand", Value: "0.120"},
+			Variable{Name: "zigbee:UtcTime", Value: "Tuesday at noon"},
+		),
+	})}
+
+	readings, err := ReadingsFromDetailsResponse(response)
+	require.NoError(t, err, "one variable that does not parse does not fail the rest")
+
+	demand, ok := readings["Main"]["zigbee:InstantaneousDemand"].Float()
+	require.True(t, ok)
+	assert.Equal(t, 0.12, demand)
+
+	utc := readings["Main"]["zigbee:UtcTime"]
+	assert.Equal(t, "Tuesday at noon", utc.Raw)
+	assert.Equal(t, "Tuesday at noon", utc.Value)
+	_, ok = utc.Time()
+	assert.False(t, ok)
+}
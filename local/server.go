@@ -73,7 +73,7 @@ func testServer(payload TestServerPayload) func(w http.ResponseWriter, r *http.R
 		var response interface{}
 		switch command.Name {
 		case "device_list":
-			response = payload.DeviceList
+			response = DeviceList{Device: payload.DeviceList}
 		case "device_details":
 			response = payload.DeviceDetails
 		case "device_query":
@@ -10,10 +10,10 @@ func NewWifiStatusCommand() Command {
 //WifiStatus is the response from the wifi_status command
 type WifiStatus struct {
 	XMLName    xml.Name `xml:"WiFiStatus" json:"-"`
-	Enabled    string   `json:"enabled"`
+	Enabled    YesNo    `json:"enabled"`
 	Type       string   `json:"type"`
 	SSID       string   `json:"ssid"`
 	Encryption string   `json:"encryption"`
-	Channel    string   `json:"channel"`
+	Channel    int      `json:"channel"`
 	IPAddress  string   `xml:"IpAddress" json:"ip_address"`
 }
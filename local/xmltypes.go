@@ -0,0 +1,196 @@
+package local
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Timestamp is a time the eagle reports as seconds since the unix epoch, either hex (0x5989f8f5) or decimal
+type Timestamp struct {
+	time.Time
+}
+
+//NewTimestamp returns a Timestamp for the provided time truncated to the second, which is all the eagle reports
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{t.Truncate(time.Second)}
+}
+
+//UnmarshalXML parses the epoch, an empty element is the zero Timestamp
+func (t *Timestamp) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	seconds, err := parseEagleInt(raw)
+	if err != nil {
+		return fmt.Errorf("timestamp %s: %v", raw, err)
+	}
+
+	*t = Timestamp{time.Unix(seconds, 0).UTC()}
+	return nil
+}
+
+//MarshalXML writes the time as a hex epoch the way the eagle does
+func (t Timestamp) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.IsZero() {
+		return e.EncodeElement("", start)
+	}
+
+	return e.EncodeElement(fmt.Sprintf("0x%x", t.Unix()), start)
+}
+
+//MarshalJSON writes the time as RFC3339, or null if it is zero
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.UTC().Format(time.RFC3339))
+}
+
+//UnmarshalJSON reads RFC3339 or null
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var raw *string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	if raw == nil || *raw == "" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, *raw)
+	if err != nil {
+		return err
+	}
+
+	*t = Timestamp{parsed}
+	return nil
+}
+
+//HexInt is an integer the eagle reports in hex (0x20db), decimal values are accepted as well
+type HexInt int64
+
+//UnmarshalXML parses the integer, an empty element is 0
+func (h *HexInt) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		*h = 0
+		return nil
+	}
+
+	i, err := parseEagleInt(raw)
+	if err != nil {
+		return fmt.Errorf("hex int %s: %v", raw, err)
+	}
+
+	*h = HexInt(i)
+	return nil
+}
+
+//MarshalXML writes the integer in hex
+func (h HexInt) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(h.String(), start)
+}
+
+func (h HexInt) String() string {
+	return fmt.Sprintf("0x%04x", int64(h))
+}
+
+//ConnectionStatus is the state of a device's connection to the eagle
+type ConnectionStatus string
+
+const (
+	//Connected means the eagle is in contact with the device
+	Connected ConnectionStatus = "Connected"
+	//Disconnected means the device has joined the network but the eagle has lost contact with it
+	Disconnected ConnectionStatus = "Disconnected"
+	//Joining means the device is in the process of joining the network
+	Joining ConnectionStatus = "Joining"
+	//NotJoined means the device is known to the eagle but has never joined the network
+	NotJoined ConnectionStatus = "Not joined"
+)
+
+var connectionStatuses = []ConnectionStatus{Connected, Disconnected, Joining, NotJoined}
+
+//IsConnected returns true if the eagle is in contact with the device
+func (s ConnectionStatus) IsConnected() bool {
+	return s == Connected
+}
+
+//UnmarshalXML normalizes the spelling/case the firmware uses to one of the known statuses, unknown statuses are kept as is
+func (s *ConnectionStatus) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	*s = ParseConnectionStatus(raw)
+	return nil
+}
+
+//ParseConnectionStatus returns the known ConnectionStatus matching raw, ignoring case and whitespace
+func ParseConnectionStatus(raw string) ConnectionStatus {
+	normalized := strings.Join(strings.Fields(raw), " ")
+	for _, status := range connectionStatuses {
+		if strings.EqualFold(normalized, string(status)) {
+			return status
+		}
+	}
+
+	return ConnectionStatus(normalized)
+}
+
+//YesNo is a boolean the eagle reports as Y or N
+type YesNo bool
+
+//UnmarshalXML parses Y/Yes/true as true, everything else is false
+func (y *YesNo) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "y", "yes", "true", "enabled":
+		*y = true
+	default:
+		*y = false
+	}
+
+	return nil
+}
+
+//MarshalXML writes Y or N
+func (y YesNo) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if y {
+		return e.EncodeElement("Y", start)
+	}
+
+	return e.EncodeElement("N", start)
+}
+
+//the eagle uses a 0x prefix for hex, anything else is decimal.  strconv's base 0 would treat a leading 0 as octal
+func parseEagleInt(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "0x") || strings.HasPrefix(raw, "0X") {
+		return strconv.ParseInt(raw[2:], 16, 64)
+	}
+
+	return strconv.ParseInt(raw, 10, 64)
+}
@@ -0,0 +1,61 @@
+package local
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalDevice(t *testing.T) {
+	body := `<DeviceList>
+<Device>
+<HardwareAddress>0x00244600000abcde</HardwareAddress>
+<Manufacturer>emerson</Manufacturer>
+<ModelId>ee542</ModelId>
+<Protocol>Zigbee</Protocol>
+<LastContact>0x5989f5f1</LastContact>
+<ConnectionStatus>Not Joined</ConnectionStatus>
+<NetworkAddress>0xffff</NetworkAddress>
+</Device>
+</DeviceList>`
+
+	list := DeviceList{}
+	require.NoError(t, xml.Unmarshal([]byte(body), &list))
+	require.Len(t, list.Device, 1)
+
+	device := list.Device[0]
+	assert.Equal(t, time.Unix(0x5989f5f1, 0).UTC(), device.LastContact.Time)
+	assert.Equal(t, NotJoined, device.ConnectionStatus)
+	assert.False(t, device.ConnectionStatus.IsConnected())
+	assert.Equal(t, HexInt(0xffff), device.NetworkAddress)
+
+	b, err := json.Marshal(device)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"last_contact":"2017-08-08T17:33:37Z"`)
+
+	roundTrip, err := xml.Marshal(list)
+	require.NoError(t, err)
+
+	again := DeviceList{}
+	require.NoError(t, xml.Unmarshal(roundTrip, &again))
+	assert.Equal(t, list.Device, again.Device)
+}
+
+func TestUnmarshalWifiStatus(t *testing.T) {
+	body := `<WiFiStatus> <Enabled>Y</Enabled> <Type>router</Type> <SSID>eagle-004792 (router)</SSID> <Encryption>WPA2 PSK (CCMP)</Encryption> <Channel>11</Channel> <IpAddress>192.168.7.1</IpAddress> </WiFiStatus>`
+
+	status := WifiStatus{}
+	require.NoError(t, xml.Unmarshal([]byte(body), &status))
+	assert.True(t, bool(status.Enabled))
+	assert.Equal(t, 11, status.Channel)
+}
+
+func TestUnmarshalBadTimestamp(t *testing.T) {
+	device := Device{}
+	err := xml.Unmarshal([]byte(`<Device><LastContact>yesterday</LastContact></Device>`), &device)
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/kklipsch/reagle/local"
+)
+
+type (
+	discovery struct {
+		topic  string
+		config discoveryConfig
+	}
+
+	//discoveryConfig is the subset of Home Assistant's mqtt sensor and binary_sensor config reagled uses
+	discoveryConfig struct {
+		Name              string          `json:"name"`
+		UniqueID          string          `json:"unique_id"`
+		StateTopic        string          `json:"state_topic"`
+		ValueTemplate     string          `json:"value_template"`
+		AvailabilityTopic string          `json:"availability_topic"`
+		DeviceClass       string          `json:"device_class,omitempty"`
+		StateClass        string          `json:"state_class,omitempty"`
+		UnitOfMeasurement string          `json:"unit_of_measurement,omitempty"`
+		Device            discoveryDevice `json:"device"`
+	}
+
+	discoveryDevice struct {
+		Identifiers  []string `json:"identifiers"`
+		Name         string   `json:"name"`
+		Manufacturer string   `json:"manufacturer,omitempty"`
+		Model        string   `json:"model,omitempty"`
+		ViaDevice    string   `json:"via_device,omitempty"`
+	}
+)
+
+func gatewayDevice(config Config) discoveryDevice {
+	return discoveryDevice{
+		Identifiers:  []string{"reagle_" + config.NodeID},
+		Name:         "Eagle 200",
+		Manufacturer: "Rainforest Automation",
+		Model:        "EAGLE-200",
+	}
+}
+
+//meterDiscovery configures the sensors on the state topic.  Delivered and received are total_increasing energy sensors
+//so Home Assistant's energy dashboard can use them directly
+func meterDiscovery(config Config) []discovery {
+	sensors := []struct {
+		object      string
+		name        string
+		field       string
+		deviceClass string
+		stateClass  string
+		unit        string
+	}{
+		{"demand", "Demand", "demand", "power", "measurement", "kW"},
+		{"delivered", "Energy Delivered", "delivered", "energy", "total_increasing", "kWh"},
+		{"received", "Energy Received", "received", "energy", "total_increasing", "kWh"},
+		{"price", "Price", "price", "", "measurement", ""},
+	}
+
+	var discoveries []discovery
+	for _, s := range sensors {
+		discoveries = append(discoveries, discovery{
+			topic: fmt.Sprintf("%s/sensor/%s/%s/config", config.DiscoveryPrefix, config.NodeID, s.object),
+			config: discoveryConfig{
+				Name:              s.name,
+				UniqueID:          fmt.Sprintf("reagle_%s_%s", config.NodeID, s.object),
+				StateTopic:        config.TopicPrefix + "/state",
+				ValueTemplate:     fmt.Sprintf("{{ value_json.%s }}", s.field),
+				AvailabilityTopic: config.TopicPrefix + "/status",
+				DeviceClass:       s.deviceClass,
+				StateClass:        s.stateClass,
+				UnitOfMeasurement: s.unit,
+				Device:            gatewayDevice(config),
+			},
+		})
+	}
+
+	return discoveries
+}
+
+//deviceDiscovery configures a connectivity binary sensor for a device on the eagle's zigbee network
+func deviceDiscovery(config Config, device local.Device) discovery {
+	object := "connected_" + device.HardwareAddress
+
+	return discovery{
+		topic: fmt.Sprintf("%s/binary_sensor/%s/%s/config", config.DiscoveryPrefix, config.NodeID, object),
+		config: discoveryConfig{
+			Name:              fmt.Sprintf("%s %s Connected", device.Manufacturer, device.ModelID),
+			UniqueID:          fmt.Sprintf("reagle_%s_%s", config.NodeID, object),
+			StateTopic:        fmt.Sprintf("%s/devices/%s", config.TopicPrefix, device.HardwareAddress),
+			ValueTemplate:     fmt.Sprintf("{{ 'ON' if value_json.connection_status == '%s' else 'OFF' }}", local.Connected),
+			AvailabilityTopic: config.TopicPrefix + "/status",
+			DeviceClass:       "connectivity",
+			Device: discoveryDevice{
+				Identifiers:  []string{"reagle_" + device.HardwareAddress},
+				Name:         fmt.Sprintf("%s %s", device.Manufacturer, device.ModelID),
+				Manufacturer: device.Manufacturer,
+				Model:        device.ModelID,
+				ViaDevice:    "reagle_" + config.NodeID,
+			},
+		},
+	}
+}
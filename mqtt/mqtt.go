@@ -0,0 +1,300 @@
+/*
+Package mqtt publishes meter readings to an MQTT broker, with Home Assistant discovery so the sensors show up in its
+energy dashboard without any configuration.
+
+Topics
+
+Everything is published retained under the topic prefix:
+
+	<prefix>/status                  online, or offline as the last will when reagled goes away
+	<prefix>/state                   json BaseMetrics of the latest poll
+	<prefix>/variables/<name>        json of each meter variable, e.g. variables/InstantaneousDemand
+	<prefix>/devices/<address>       json of each device on the eagle's zigbee network
+
+Discovery configs are published under the discovery prefix every time the publisher connects.
+
+Delivery
+
+Messages are queued and published in the background, so a broker that is slow or reconnecting does not hold up the
+poller.  While the queue is full new messages are dropped, the retained topics are republished by the next poll
+anyway.  reagled starts whether or not the broker can be reached and keeps trying to connect.
+*/
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+)
+
+//Outcomes passed to a Publisher's Result
+const (
+	Published = "published"
+	Dropped   = "dropped"
+	Failed    = "failed"
+)
+
+const (
+	online  = "online"
+	offline = "offline"
+
+	publishTimeout = time.Second * 10
+
+	//queueSize is how many messages wait while the broker is slow or reconnecting
+	queueSize = 100
+)
+
+//ErrQueueFull is passed to the publisher's Result when a message is dropped because the queue is full
+var ErrQueueFull = errors.New("mqtt publish queue is full")
+
+type (
+	//Config is how to connect and where to publish
+	Config struct {
+		//Broker is a url such as tcp://localhost:1883 or ssl://broker:8883
+		Broker   string `json:"broker"`
+		ClientID string `json:"client_id"`
+		Username string `json:"username"`
+		Password string `json:"-"`
+		QoS      byte   `json:"qos"`
+
+		TopicPrefix string `json:"topic_prefix"`
+
+		//DiscoveryPrefix is Home Assistant's discovery prefix, empty disables discovery
+		DiscoveryPrefix string `json:"discovery_prefix"`
+
+		//NodeID identifies this eagle in Home Assistant unique ids, so it has to be unique among the eagles sharing a broker
+		NodeID string `json:"node_id"`
+	}
+
+	//Publisher queues messages and publishes them in the background so a slow or unreachable broker does not hold up
+	//the caller, it reconnects automatically
+	Publisher struct {
+		config Config
+		client paho.Client
+		queue  chan message
+
+		//addresses of the devices that already have a discovery config
+		devices map[string]bool
+
+		//RetryInterval is how long Run waits between attempts to connect the first time
+		RetryInterval time.Duration
+
+		//Result, if set, is called with the outcome of every message, and with Failed and no topic when connecting
+		//fails
+		Result func(outcome string, topic string, err error)
+	}
+
+	message struct {
+		topic   string
+		payload []byte
+	}
+
+	state struct {
+		Time time.Time `json:"time"`
+		client.BaseMetrics
+	}
+)
+
+//NewPublisher creates a Publisher, nothing is published until Run is called.  The online status and discovery configs
+//are published on every connection
+func NewPublisher(config Config) (*Publisher, error) {
+	if config.QoS > 2 {
+		return nil, fmt.Errorf("invalid qos %v, it must be 0, 1 or 2", config.QoS)
+	}
+
+	p := &Publisher{
+		config:        config,
+		queue:         make(chan message, queueSize),
+		devices:       make(map[string]bool),
+		RetryInterval: time.Second * 10,
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(publishTimeout).
+		SetWill(p.topic("status"), offline, config.QoS, true).
+		SetOnConnectHandler(func(paho.Client) {
+			err := p.announce()
+			if err != nil {
+				p.result(Failed, p.topic("status"), err)
+			}
+		})
+
+	p.client = paho.NewClient(options)
+	return p, nil
+}
+
+//Run connects, retrying until the broker can be reached, and publishes the queue until the context is done.  After the
+//first connection paho reconnects on its own
+func (p *Publisher) Run(ctx context.Context) {
+	for {
+		err := wait(p.client.Connect())
+		if err == nil {
+			break
+		}
+
+		p.result(Failed, "", fmt.Errorf("unable to connect to %s: %w", p.config.Broker, err))
+
+		select {
+		case <-time.After(p.RetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	for {
+		select {
+		case m := <-p.queue:
+			err := p.publish(m.topic, m.payload)
+			if err != nil {
+				p.result(Failed, m.topic, err)
+			} else {
+				p.result(Published, m.topic, nil)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//Close publishes the offline status, as a clean disconnect does not send the will, and disconnects.  It is called once
+//Run has returned
+func (p *Publisher) Close() error {
+	if !p.client.IsConnected() {
+		return nil
+	}
+
+	err := p.publish(p.topic("status"), offline)
+	p.client.Disconnect(uint(publishTimeout / time.Millisecond))
+	return err
+}
+
+//PublishSample queues the state topic
+func (p *Publisher) PublishSample(sample client.Sample) error {
+	return p.publishJSON(p.topic("state"), state{Time: sample.Time, BaseMetrics: sample.Metrics})
+}
+
+//PublishVariables queues a topic per variable, the result of a client.RequestAllVariables
+func (p *Publisher) PublishVariables(components map[string]local.Readings) error {
+	for _, readings := range components {
+		for name, reading := range readings {
+			if err := p.publishJSON(p.topic("variables", topicName(name)), reading); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//PublishDevices queues a topic per device, with a connectivity discovery config for devices not seen before
+func (p *Publisher) PublishDevices(devices []local.Device) error {
+	for _, device := range devices {
+		if err := p.publishJSON(p.topic("devices", device.HardwareAddress), device); err != nil {
+			return err
+		}
+
+		if p.config.DiscoveryPrefix == "" || p.devices[device.HardwareAddress] {
+			continue
+		}
+
+		d := deviceDiscovery(p.config, device)
+		if err := p.publishJSON(d.topic, d.config); err != nil {
+			return err
+		}
+
+		p.devices[device.HardwareAddress] = true
+	}
+
+	return nil
+}
+
+//publishJSON queues the message, it is dropped if the queue is full
+func (p *Publisher) publishJSON(topic string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case p.queue <- message{topic: topic, payload: b}:
+		return nil
+	default:
+		p.result(Dropped, topic, ErrQueueFull)
+		return ErrQueueFull
+	}
+}
+
+func (p *Publisher) announce() error {
+	if err := p.publish(p.topic("status"), online); err != nil {
+		return err
+	}
+
+	if p.config.DiscoveryPrefix == "" {
+		return nil
+	}
+
+	//published directly rather than queued, they come before anything that was queued while disconnected
+	for _, d := range meterDiscovery(p.config) {
+		b, err := json.Marshal(d.config)
+		if err != nil {
+			return err
+		}
+
+		if err := p.publish(d.topic, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Publisher) publish(topic string, payload interface{}) error {
+	return wait(p.client.Publish(topic, p.config.QoS, true, payload))
+}
+
+func (p *Publisher) result(outcome string, topic string, err error) {
+	if p.Result != nil {
+		p.Result(outcome, topic, err)
+	}
+}
+
+func (p *Publisher) topic(parts ...string) string {
+	return strings.Join(append([]string{p.config.TopicPrefix}, parts...), "/")
+}
+
+//wait does not use WaitTimeout, it holds the token's lock so an error set while waiting is not seen until the timeout
+func wait(token paho.Token) error {
+	done := make(chan struct{})
+	go func() {
+		token.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return token.Error()
+	case <-time.After(publishTimeout):
+		return fmt.Errorf("timed out waiting for the broker")
+	}
+}
+
+//topicName drops the namespace from a variable name, zigbee:InstantaneousDemand is InstantaneousDemand
+func topicName(variable string) string {
+	if i := strings.LastIndex(variable, ":"); i >= 0 {
+		variable = variable[i+1:]
+	}
+
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(variable)
+}
@@ -0,0 +1,248 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//broker is just enough of an mqtt broker to accept one client and record what it publishes
+type broker struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	connect  *packets.ConnectPacket
+	retained map[string][]byte
+}
+
+func newBroker(t *testing.T) *broker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	b := &broker{listener: listener, retained: make(map[string][]byte)}
+	go b.serve()
+	return b
+}
+
+func (b *broker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *broker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go b.handle(conn)
+	}
+}
+
+func (b *broker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		var reply packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			b.mu.Lock()
+			b.connect = p
+			b.mu.Unlock()
+
+			reply = packets.NewControlPacket(packets.Connack)
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			if p.Retain {
+				b.retained[p.TopicName] = p.Payload
+			}
+			b.mu.Unlock()
+
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				reply = ack
+			}
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+
+		if reply != nil {
+			if err := reply.Write(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *broker) topic(t *testing.T, topic string) []byte {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		payload, ok := b.retained[topic]
+		b.mu.Unlock()
+
+		if ok {
+			return payload
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	require.Fail(t, "never published", topic)
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	b := newBroker(t)
+	defer b.listener.Close()
+
+	p, err := NewPublisher(Config{
+		Broker:          b.url(),
+		ClientID:        "reagled-test",
+		QoS:             1,
+		TopicPrefix:     "reagle",
+		DiscoveryPrefix: "homeassistant",
+		NodeID:          "house",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx)
+	}()
+
+	assert.Equal(t, "online", string(b.topic(t, "reagle/status")))
+
+	b.mu.Lock()
+	assert.Equal(t, "reagle/status", b.connect.WillTopic)
+	assert.Equal(t, "offline", string(b.connect.WillMessage))
+	assert.True(t, b.connect.WillRetain)
+	b.mu.Unlock()
+
+	delivered := discoveryConfig{}
+	require.NoError(t, json.Unmarshal(b.topic(t, "homeassistant/sensor/house/delivered/config"), &delivered))
+	assert.Equal(t, "energy", delivered.DeviceClass)
+	assert.Equal(t, "total_increasing", delivered.StateClass)
+	assert.Equal(t, "kWh", delivered.UnitOfMeasurement)
+	assert.Equal(t, "reagle/state", delivered.StateTopic)
+	assert.Equal(t, "reagle/status", delivered.AvailabilityTopic)
+
+	now := time.Date(2018, 10, 20, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, p.PublishSample(client.Sample{Time: now, Metrics: client.BaseMetrics{Demand: 1.5, Delivered: 100}}))
+
+	state := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(b.topic(t, "reagle/state"), &state))
+	assert.Equal(t, 1.5, state["demand"])
+	assert.Equal(t, 100.0, state["delivered"])
+
+	require.NoError(t, p.PublishVariables(map[string]local.Readings{
+		"Main": {"zigbee:InstantaneousDemand": local.Reading{Name: "zigbee:InstantaneousDemand", Value: 1.5, Units: "kW"}},
+	}))
+	assert.Contains(t, string(b.topic(t, "reagle/variables/InstantaneousDemand")), `"kW"`)
+
+	device := local.Device{DeviceData: local.DeviceData{HardwareAddress: "0x0013", ModelID: "electric_meter", ConnectionStatus: local.Connected}}
+	require.NoError(t, p.PublishDevices([]local.Device{device}))
+	assert.Contains(t, string(b.topic(t, "reagle/devices/0x0013")), `"Connected"`)
+
+	connected := discoveryConfig{}
+	require.NoError(t, json.Unmarshal(b.topic(t, "homeassistant/binary_sensor/house/connected_0x0013/config"), &connected))
+	assert.Equal(t, "connectivity", connected.DeviceClass)
+	assert.Equal(t, "reagle_house", connected.Device.ViaDevice)
+
+	cancel()
+	<-done
+	require.NoError(t, p.Close())
+	assert.Equal(t, "offline", string(b.topic(t, "reagle/status")))
+}
+
+func TestPublisherDoesNotBlock(t *testing.T) {
+	//nothing is listening so it never connects
+	p, err := NewPublisher(Config{Broker: "tcp://127.0.0.1:1", ClientID: "reagled-test", QoS: 1, TopicPrefix: "reagle"})
+	require.NoError(t, err)
+
+	dropped := 0
+	p.Result = func(outcome string, topic string, err error) {
+		if outcome == Dropped {
+			dropped++
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < queueSize; i++ {
+		require.NoError(t, p.PublishSample(client.Sample{}))
+	}
+
+	assert.Equal(t, ErrQueueFull, p.PublishSample(client.Sample{}))
+	assert.Equal(t, 1, dropped)
+	assert.True(t, time.Since(start) < time.Second, "publishing only queues")
+	assert.NoError(t, p.Close(), "nothing to say offline to")
+}
+
+func TestPublisherRetriesConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	p, err := NewPublisher(Config{Broker: "tcp://" + address, ClientID: "reagled-test", QoS: 1, TopicPrefix: "reagle"})
+	require.NoError(t, err)
+	p.RetryInterval = time.Millisecond * 10
+
+	var mu sync.Mutex
+	failed := 0
+	p.Result = func(outcome string, topic string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if outcome == Failed && topic == "" {
+			failed++
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	//queued while the broker is down and published once it is up
+	require.NoError(t, p.PublishSample(client.Sample{Metrics: client.BaseMetrics{Demand: 2}}))
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		mu.Lock()
+		attempted := failed > 0
+		mu.Unlock()
+
+		if attempted || time.Now().After(deadline) {
+			require.True(t, attempted, "a failed connection is reported")
+			break
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	listener, err = net.Listen("tcp", address)
+	require.NoError(t, err)
+	b := &broker{listener: listener, retained: make(map[string][]byte)}
+	go b.serve()
+	defer b.listener.Close()
+
+	assert.Contains(t, string(b.topic(t, "reagle/state")), `"demand":2`)
+}
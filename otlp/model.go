@@ -0,0 +1,322 @@
+package otlp
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+//the OTLP/HTTP JSON encoding of ExportMetricsServiceRequest, 64 bit integers are strings as protobuf's JSON mapping
+//has them
+type (
+	exportRequest struct {
+		ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+	}
+
+	resourceMetrics struct {
+		Resource     resource       `json:"resource"`
+		ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+	}
+
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+
+	scopeMetrics struct {
+		Scope   scope    `json:"scope"`
+		Metrics []metric `json:"metrics"`
+	}
+
+	scope struct {
+		Name string `json:"name"`
+	}
+
+	metric struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description,omitempty"`
+		Unit        string     `json:"unit,omitempty"`
+		Gauge       *gauge     `json:"gauge,omitempty"`
+		Sum         *sum       `json:"sum,omitempty"`
+		Histogram   *histogram `json:"histogram,omitempty"`
+		Summary     *summary   `json:"summary,omitempty"`
+	}
+
+	gauge struct {
+		DataPoints []numberDataPoint `json:"dataPoints"`
+	}
+
+	sum struct {
+		DataPoints             []numberDataPoint `json:"dataPoints"`
+		AggregationTemporality int               `json:"aggregationTemporality"`
+		IsMonotonic            bool              `json:"isMonotonic"`
+	}
+
+	numberDataPoint struct {
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string     `json:"timeUnixNano"`
+		AsDouble          double     `json:"asDouble"`
+	}
+
+	histogram struct {
+		DataPoints             []histogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                  `json:"aggregationTemporality"`
+	}
+
+	histogramDataPoint struct {
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		TimeUnixNano      string     `json:"timeUnixNano"`
+		Count             string     `json:"count"`
+		Sum               double     `json:"sum"`
+		BucketCounts      []string   `json:"bucketCounts"`
+		ExplicitBounds    []double   `json:"explicitBounds"`
+	}
+
+	summary struct {
+		DataPoints []summaryDataPoint `json:"dataPoints"`
+	}
+
+	summaryDataPoint struct {
+		Attributes        []keyValue      `json:"attributes,omitempty"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               double          `json:"sum"`
+		QuantileValues    []quantileValue `json:"quantileValues"`
+	}
+
+	quantileValue struct {
+		Quantile double `json:"quantile"`
+		Value    double `json:"value"`
+	}
+
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+
+	anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+
+	//double is a float64 that encodes NaN and the infinities the way protobuf's JSON mapping does, an empty summary's
+	//quantiles are NaN
+	double float64
+
+	//instrument is what a Prometheus family is called in OTel
+	instrument struct {
+		name string
+		unit string
+	}
+)
+
+const cumulative = 2
+
+//instruments renames the meter readings to OTel style names with units, the client metrics are renamed by prefix
+var instruments = map[string]instrument{
+	"instantaneous_demand":        {"meter.demand", "kW"},
+	"current_summation_delivered": {"meter.energy.delivered", "kWh"},
+	"current_summation_received":  {"meter.energy.received", "kWh"},
+	"price":                       {"meter.price", "{currency}/kWh"},
+	"client_requests":             {"reagle.client.requests", "{request}"},
+	"client_replies":              {"reagle.client.replies", "{reply}"},
+	"client_errors":               {"reagle.client.errors", "{error}"},
+	"client_rate_limits":          {"reagle.client.rate_limits", "{request}"},
+}
+
+func instrumentFor(name string) instrument {
+	if i, ok := instruments[name]; ok {
+		return i
+	}
+
+	if strings.HasPrefix(name, "client_") {
+		return instrument{name: "reagle.client." + strings.TrimPrefix(name, "client_")}
+	}
+
+	return instrument{name: name}
+}
+
+func (d double) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+
+	return json.Marshal(f)
+}
+
+func (d *double) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		switch s {
+		case "NaN":
+			*d = double(math.NaN())
+		case "Infinity":
+			*d = double(math.Inf(1))
+		case "-Infinity":
+			*d = double(math.Inf(-1))
+		default:
+			f, err := strconv.ParseFloat(s, 64)
+			*d = double(f)
+			return err
+		}
+
+		return nil
+	}
+
+	var f float64
+	err := json.Unmarshal(b, &f)
+	*d = double(f)
+	return err
+}
+
+//toMetrics converts metric families to OTel metrics.  Counters are cumulative sums since start, metrics without a
+//timestamp get now
+func toMetrics(families []*dto.MetricFamily, start time.Time, now time.Time) []metric {
+	startNano := nanos(start)
+
+	var metrics []metric
+	for _, family := range families {
+		i := instrumentFor(family.GetName())
+		m := metric{Name: i.name, Description: family.GetHelp(), Unit: i.unit}
+
+		for _, pm := range family.GetMetric() {
+			ts := nanos(now)
+			if pm.TimestampMs != nil {
+				ts = strconv.FormatInt(pm.GetTimestampMs()*int64(time.Millisecond), 10)
+			}
+
+			attrs := attributes(pm.GetLabel())
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				if m.Sum == nil {
+					m.Sum = &sum{AggregationTemporality: cumulative, IsMonotonic: true}
+				}
+
+				m.Sum.DataPoints = append(m.Sum.DataPoints, numberDataPoint{
+					Attributes:        attrs,
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      ts,
+					AsDouble:          double(pm.GetCounter().GetValue()),
+				})
+			case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+				if m.Gauge == nil {
+					m.Gauge = &gauge{}
+				}
+
+				value := pm.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = pm.GetUntyped().GetValue()
+				}
+
+				m.Gauge.DataPoints = append(m.Gauge.DataPoints, numberDataPoint{
+					Attributes:   attrs,
+					TimeUnixNano: ts,
+					AsDouble:     double(value),
+				})
+			case dto.MetricType_HISTOGRAM:
+				if m.Histogram == nil {
+					m.Histogram = &histogram{AggregationTemporality: cumulative}
+				}
+
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramPoint(pm.GetHistogram(), attrs, startNano, ts))
+			case dto.MetricType_SUMMARY:
+				if m.Summary == nil {
+					m.Summary = &summary{}
+				}
+
+				s := pm.GetSummary()
+				point := summaryDataPoint{
+					Attributes:        attrs,
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      ts,
+					Count:             strconv.FormatUint(s.GetSampleCount(), 10),
+					Sum:               double(s.GetSampleSum()),
+				}
+
+				for _, q := range s.GetQuantile() {
+					point.QuantileValues = append(point.QuantileValues, quantileValue{double(q.GetQuantile()), double(q.GetValue())})
+				}
+
+				m.Summary.DataPoints = append(m.Summary.DataPoints, point)
+			}
+		}
+
+		//a family without any metrics yet, such as an unused vec
+		if m.Sum == nil && m.Gauge == nil && m.Histogram == nil && m.Summary == nil {
+			continue
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
+
+//histogramPoint converts Prometheus' cumulative buckets to OTel's bucket counts, which have one more count than
+//bounds for everything above the last bound
+func histogramPoint(h *dto.Histogram, attrs []keyValue, start string, ts string) histogramDataPoint {
+	point := histogramDataPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             strconv.FormatUint(h.GetSampleCount(), 10),
+		Sum:               double(h.GetSampleSum()),
+	}
+
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			break
+		}
+
+		point.ExplicitBounds = append(point.ExplicitBounds, double(b.GetUpperBound()))
+		point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(b.GetCumulativeCount()-previous, 10))
+		previous = b.GetCumulativeCount()
+	}
+
+	point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(h.GetSampleCount()-previous, 10))
+	return point
+}
+
+func attributes(pairs []*dto.LabelPair) []keyValue {
+	var all []keyValue
+	for _, p := range pairs {
+		all = append(all, keyValue{Key: p.GetName(), Value: anyValue{StringValue: p.GetValue()}})
+	}
+
+	return all
+}
+
+func resourceAttributes(r Resource) []keyValue {
+	keys := make([]string, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var all []keyValue
+	for _, k := range keys {
+		if r[k] != "" {
+			all = append(all, keyValue{Key: k, Value: anyValue{StringValue: r[k]}})
+		}
+	}
+
+	return all
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,121 @@
+/*
+Package otlp exports metrics to an OpenTelemetry collector over OTLP/HTTP with the JSON encoding.
+
+Instruments
+
+Prometheus metric families are converted to the matching OTel instrument: counters are cumulative monotonic sums,
+gauges are gauges, histograms and summaries are histograms and summaries.  The meter readings are renamed to
+meter.demand, meter.energy.delivered, meter.energy.received and meter.price with their units, and the client_ metrics
+to reagle.client.  Everything else keeps its Prometheus name.
+*/
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/retry"
+	dto "github.com/prometheus/client_model/go"
+)
+
+//Resource attribute keys reagled sets
+const (
+	ServiceNameAttribute  = "service.name"
+	GatewayAttribute      = "reagle.gateway"
+	MeterAddressAttribute = "reagle.meter.hardware_address"
+	MeterModelAttribute   = "reagle.meter.model_id"
+)
+
+type (
+	//Config is where to export
+	Config struct {
+		//Endpoint is the collector's base url such as http://localhost:4318, metrics are posted to /v1/metrics
+		Endpoint string `json:"endpoint"`
+
+		//Headers are added to every export, such as an api key.  They are not logged
+		Headers map[string]string `json:"-"`
+
+		Interval time.Duration `json:"interval"`
+	}
+
+	//Resource is the attributes of what the metrics are about
+	Resource map[string]string
+
+	//Exporter posts metrics to a collector
+	Exporter struct {
+		config Config
+		client *http.Client
+		start  time.Time
+
+		//Retries is how many times a failed export is retried, doubling Backoff between each
+		Retries int
+		Backoff time.Duration
+	}
+)
+
+//NewExporter creates an Exporter, cumulative sums start from now
+func NewExporter(client *http.Client, config Config) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("otlp needs an endpoint")
+	}
+
+	return &Exporter{config: config, client: client, start: time.Now(), Retries: 2, Backoff: time.Second}, nil
+}
+
+//Export posts the metric families, stamped with now unless they have their own timestamp
+func (e *Exporter) Export(ctx context.Context, r Resource, families []*dto.MetricFamily, now time.Time) error {
+	metrics := toMetrics(families, e.start, now)
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(exportRequest{
+		ResourceMetrics: []resourceMetrics{{
+			Resource: resource{Attributes: resourceAttributes(r)},
+			ScopeMetrics: []scopeMetrics{{
+				Scope:   scope{Name: "github.com/kklipsch/reagle"},
+				Metrics: metrics,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return retry.Backoff(ctx, e.Retries, e.Backoff, func() error {
+		return e.post(ctx, body)
+	})
+}
+
+func (e *Exporter) post(ctx context.Context, body []byte) error {
+	timeout, clean := context.WithTimeout(ctx, time.Second*10)
+	defer clean()
+
+	req, err := http.NewRequestWithContext(timeout, http.MethodPost, strings.TrimSuffix(e.config.Endpoint, "/")+"/v1/metrics", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reagled")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	return retry.Post(e.client, req, "otlp export", retryStatus)
+}
+
+//retryStatus is true for the statuses the OTLP spec says to retry
+func retryStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,130 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, 10, 20, 12, 0, 0, 0, time.UTC)
+
+func testFamilies(t *testing.T) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "client_requests", Help: "Count of requests to the client"}, []string{"type"})
+	requests.WithLabelValues("local_base_metrics").Add(3)
+
+	pollErrors := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "client_poll_errors", Help: "h"}, []string{"kind"})
+
+	demand := prometheus.NewGauge(prometheus.GaugeOpts{Name: "instantaneous_demand", Help: "current demand"})
+	demand.Set(1.5)
+
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "h", Buckets: []float64{0.5, 1}})
+	latency.Observe(0.25)
+	latency.Observe(0.75)
+	latency.Observe(2)
+
+	quantiles := prometheus.NewSummary(prometheus.SummaryOpts{Name: "wait_seconds", Help: "h", Objectives: map[float64]float64{0.5: 0.05}})
+
+	for _, c := range []prometheus.Collector{requests, pollErrors, demand, latency, quantiles} {
+		require.NoError(t, reg.Register(c))
+	}
+
+	return reg
+}
+
+func TestExport(t *testing.T) {
+	received := make(chan exportRequest, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		req := exportRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received <- req
+	}))
+	defer ts.Close()
+
+	e, err := NewExporter(ts.Client(), Config{Endpoint: ts.URL + "/", Headers: map[string]string{"X-Api-Key": "secret"}})
+	require.NoError(t, err)
+	e.start = now.Add(-time.Hour)
+
+	families, err := testFamilies(t).Gather()
+	require.NoError(t, err)
+
+	resource := Resource{ServiceNameAttribute: "reagled", MeterAddressAttribute: "0x0013", MeterModelAttribute: ""}
+	require.NoError(t, e.Export(context.Background(), resource, families, now))
+
+	req := <-received
+	require.Len(t, req.ResourceMetrics, 1)
+	assert.Equal(t, []keyValue{
+		{Key: MeterAddressAttribute, Value: anyValue{StringValue: "0x0013"}},
+		{Key: ServiceNameAttribute, Value: anyValue{StringValue: "reagled"}},
+	}, req.ResourceMetrics[0].Resource.Attributes, "sorted and without empty attributes")
+
+	metrics := make(map[string]metric)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	assert.Len(t, metrics, 4, "the unused poll errors vec is left out")
+
+	requests := metrics["reagle.client.requests"]
+	assert.Equal(t, "{request}", requests.Unit)
+	require.NotNil(t, requests.Sum)
+	assert.True(t, requests.Sum.IsMonotonic)
+	assert.Equal(t, cumulative, requests.Sum.AggregationTemporality)
+	assert.Equal(t, numberDataPoint{
+		Attributes:        []keyValue{{Key: "type", Value: anyValue{StringValue: "local_base_metrics"}}},
+		StartTimeUnixNano: "1540033200000000000",
+		TimeUnixNano:      "1540036800000000000",
+		AsDouble:          3,
+	}, requests.Sum.DataPoints[0])
+
+	demand := metrics["meter.demand"]
+	assert.Equal(t, "kW", demand.Unit)
+	require.NotNil(t, demand.Gauge)
+	assert.Equal(t, double(1.5), demand.Gauge.DataPoints[0].AsDouble)
+
+	latency := metrics["latency_seconds"].Histogram
+	require.NotNil(t, latency)
+	assert.Equal(t, []double{0.5, 1}, latency.DataPoints[0].ExplicitBounds)
+	assert.Equal(t, []string{"1", "1", "1"}, latency.DataPoints[0].BucketCounts, "not cumulative, with the overflow bucket")
+	assert.Equal(t, "3", latency.DataPoints[0].Count)
+
+	wait := metrics["wait_seconds"].Summary
+	require.NotNil(t, wait)
+	assert.True(t, math.IsNaN(float64(wait.DataPoints[0].QuantileValues[0].Value)), "an empty summary is NaN, which plain JSON cannot encode")
+}
+
+func TestExportRetries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls])
+		calls++
+	}))
+	defer ts.Close()
+
+	e, err := NewExporter(ts.Client(), Config{Endpoint: ts.URL})
+	require.NoError(t, err)
+	e.Backoff = time.Millisecond
+
+	families, err := testFamilies(t).Gather()
+	require.NoError(t, err)
+
+	require.NoError(t, e.Export(context.Background(), Resource{}, families, now))
+	assert.Equal(t, 2, calls)
+
+	err = e.Export(context.Background(), Resource{}, families, now)
+	require.Error(t, err)
+	assert.Equal(t, 3, calls, "a bad request is not retried")
+}
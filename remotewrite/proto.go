@@ -0,0 +1,72 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+//protobuf wire types
+const (
+	varintType  = 0
+	fixed64Type = 1
+	bytesType   = 2
+)
+
+//encode is the WriteRequest protobuf of the series.  The message is small enough that encoding it by hand is simpler
+//than carrying the generated code:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encode(batch []series) []byte {
+	var request, ts, msg []byte
+	for _, s := range batch {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			msg = msg[:0]
+			msg = appendString(msg, 1, l.name)
+			msg = appendString(msg, 2, l.value)
+			ts = appendBytes(ts, 1, msg)
+		}
+
+		msg = msg[:0]
+		msg = appendKey(msg, 1, fixed64Type)
+		msg = appendFixed64(msg, math.Float64bits(s.value))
+		msg = appendKey(msg, 2, varintType)
+		msg = appendVarint(msg, uint64(s.timestamp))
+		ts = appendBytes(ts, 2, msg)
+
+		request = appendBytes(request, 1, ts)
+	}
+
+	return request
+}
+
+func appendKey(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendKey(b, field, bytesType)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	b = appendKey(b, field, bytesType)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,261 @@
+/*
+Package remotewrite pushes gathered metrics to a Prometheus remote_write endpoint, for reagleds that cannot be scraped
+such as those behind NAT.
+
+Buffering
+
+Every interval the metrics are gathered into a batch and the pending batches are sent oldest first, so samples of a
+series always arrive in order.  While the endpoint is unreachable batches are kept, up to MaxPending after which the
+oldest is dropped.  Batches the endpoint rejects outright, a 4xx other than 429, are dropped as they would never
+succeed.
+
+Timestamps
+
+Metrics collected with a timestamp, such as the bridge's when it is collecting the poller's samples, keep it and are
+only sent when the timestamp changes.  Everything else is stamped with the time of the gather.
+*/
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/kklipsch/reagle/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+//Outcomes passed to a Pusher's Result.  Failed is a push that is retried next interval, its samples are still pending
+const (
+	Sent    = "sent"
+	Dropped = "dropped"
+	Failed  = "failed"
+)
+
+//ErrBufferFull is passed to the pusher's Result when the oldest batch is dropped to make room
+var ErrBufferFull = errors.New("remote write buffer is full")
+
+type (
+	//Config is where to push and how often
+	Config struct {
+		URL         string `json:"url"`
+		Username    string `json:"username"`
+		Password    string `json:"-"`
+		BearerToken string `json:"-"`
+
+		Interval time.Duration `json:"interval"`
+
+		//Labels are added to every series, the job and instance labels a scrape would have added for example
+		Labels map[string]string `json:"labels"`
+
+		//MaxPending is how many batches are kept while the endpoint is unreachable
+		MaxPending int `json:"max_pending"`
+	}
+
+	//Pusher gathers and pushes metrics in the background
+	Pusher struct {
+		config   Config
+		client   *http.Client
+		gatherer prometheus.Gatherer
+
+		mu      sync.Mutex
+		pending [][]series
+
+		//last timestamp pushed of the series that have their own
+		stamped map[string]int64
+
+		//Retries is how many times a failed push is retried, doubling Backoff between each, before waiting for the
+		//next interval
+		Retries int
+		Backoff time.Duration
+
+		//Result, if set, is called with the outcome of every batch and how many samples were in it.  Errors gathering
+		//are Dropped with no samples
+		Result func(outcome string, samples int, err error)
+	}
+)
+
+//NewPusher creates a Pusher of the gatherer's metrics, nothing is pushed until Run is called
+func NewPusher(client *http.Client, gatherer prometheus.Gatherer, config Config) (*Pusher, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("remote write needs a url")
+	}
+
+	for name := range config.Labels {
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = time.Second * 30
+	}
+
+	if config.MaxPending <= 0 {
+		config.MaxPending = 1
+	}
+
+	return &Pusher{
+		config:   config,
+		client:   client,
+		gatherer: gatherer,
+		stamped:  make(map[string]int64),
+		Retries:  2,
+		Backoff:  time.Second,
+	}, nil
+}
+
+//Pending is the number of batches waiting to be sent
+func (p *Pusher) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.pending)
+}
+
+//Run gathers and pushes every interval until the context is done
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.Push(ctx, time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//Push gathers a batch stamped with now and sends the pending batches
+func (p *Pusher) Push(ctx context.Context, now time.Time) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		//a failing collector does not stop the others from being pushed
+		p.result(Dropped, 0, fmt.Errorf("error gathering metrics: %w", err))
+	}
+
+	p.enqueue(p.fresh(toSeries(families, now, p.config.Labels)))
+
+	for ctx.Err() == nil {
+		batch, ok := p.oldest()
+		if !ok {
+			return
+		}
+
+		err := p.send(ctx, batch)
+		if err != nil && retry.Retryable(err) {
+			p.result(Failed, len(batch), err)
+			return
+		}
+
+		p.pop()
+		if err != nil {
+			p.result(Dropped, len(batch), err)
+		} else {
+			p.result(Sent, len(batch), nil)
+		}
+	}
+}
+
+//fresh drops series with their own timestamp that has already been pushed
+func (p *Pusher) fresh(all []series) []series {
+	fresh := all[:0]
+	for _, s := range all {
+		if s.stamped {
+			key := s.key()
+			if last, ok := p.stamped[key]; ok && s.timestamp <= last {
+				continue
+			}
+
+			p.stamped[key] = s.timestamp
+		}
+
+		fresh = append(fresh, s)
+	}
+
+	return fresh
+}
+
+func (p *Pusher) enqueue(batch []series) {
+	if len(batch) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, batch)
+	var dropped [][]series
+	for len(p.pending) > p.config.MaxPending {
+		dropped = append(dropped, p.pending[0])
+		p.pending = p.pending[1:]
+	}
+	p.mu.Unlock()
+
+	for _, d := range dropped {
+		p.result(Dropped, len(d), ErrBufferFull)
+	}
+}
+
+func (p *Pusher) oldest() ([]series, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.pending) == 0 {
+		return nil, false
+	}
+
+	return p.pending[0], true
+}
+
+func (p *Pusher) pop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = p.pending[1:]
+}
+
+func (p *Pusher) send(ctx context.Context, batch []series) error {
+	body := snappy.Encode(nil, encode(batch))
+	return retry.Backoff(ctx, p.Retries, p.Backoff, func() error {
+		return p.post(ctx, body)
+	})
+}
+
+func (p *Pusher) post(ctx context.Context, body []byte) error {
+	timeout, clean := context.WithTimeout(ctx, time.Second*10)
+	defer clean()
+
+	req, err := http.NewRequestWithContext(timeout, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "reagled")
+
+	switch {
+	case p.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.config.BearerToken)
+	case p.config.Username != "":
+		req.SetBasicAuth(p.config.Username, p.config.Password)
+	}
+
+	return retry.Post(p.client, req, "remote write", retry.ServerErrors)
+}
+
+func (p *Pusher) result(outcome string, samples int, err error) {
+	if p.Result != nil {
+		p.Result(outcome, samples, err)
+	}
+}
+
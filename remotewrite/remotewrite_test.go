@@ -0,0 +1,238 @@
+package remotewrite
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, 10, 20, 12, 0, 0, 0, time.UTC)
+
+//writeRequest and friends mirror the remote write protobuf so the hand written encoding is checked against a real
+//decoder
+type (
+	writeRequest struct {
+		Timeseries []*timeSeries `protobuf:"bytes,1,rep,name=timeseries"`
+	}
+
+	timeSeries struct {
+		Labels  []*labelPair `protobuf:"bytes,1,rep,name=labels"`
+		Samples []*sample    `protobuf:"bytes,2,rep,name=samples"`
+	}
+
+	labelPair struct {
+		Name  string `protobuf:"bytes,1,opt,name=name,proto3"`
+		Value string `protobuf:"bytes,2,opt,name=value,proto3"`
+	}
+
+	sample struct {
+		Value     float64 `protobuf:"fixed64,1,opt,name=value,proto3"`
+		Timestamp int64   `protobuf:"varint,2,opt,name=timestamp,proto3"`
+	}
+)
+
+func (m *writeRequest) Reset()         { *m = writeRequest{} }
+func (m *writeRequest) String() string { return proto.CompactTextString(m) }
+func (*writeRequest) ProtoMessage()    {}
+func (m *timeSeries) Reset()           { *m = timeSeries{} }
+func (m *timeSeries) String() string   { return proto.CompactTextString(m) }
+func (*timeSeries) ProtoMessage()      {}
+func (m *labelPair) Reset()            { *m = labelPair{} }
+func (m *labelPair) String() string    { return proto.CompactTextString(m) }
+func (*labelPair) ProtoMessage()       {}
+func (m *sample) Reset()               { *m = sample{} }
+func (m *sample) String() string       { return proto.CompactTextString(m) }
+func (*sample) ProtoMessage()          {}
+
+//flat is a decoded series as name{labels} -> sample
+func flat(req *writeRequest) map[string]sample {
+	all := make(map[string]sample)
+	for _, ts := range req.Timeseries {
+		key := ""
+		for _, l := range ts.Labels {
+			key += l.Name + "=" + l.Value + ";"
+		}
+
+		all[key] = *ts.Samples[0]
+	}
+
+	return all
+}
+
+//stampedCollector is a gauge with a timestamp, like the bridge collecting the poller's samples
+type stampedCollector struct {
+	desc  *prometheus.Desc
+	mu    sync.Mutex
+	value float64
+	ts    time.Time
+}
+
+func (c *stampedCollector) set(value float64, ts time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.value, c.ts = value, ts
+}
+
+func (c *stampedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *stampedCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch <- prometheus.NewMetricWithTimestamp(c.ts, prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.value))
+}
+
+func testRegistry(t *testing.T) (*prometheus.Registry, *stampedCollector) {
+	reg := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "h"}, []string{"code"})
+	counter.WithLabelValues("200").Add(3)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "h", Buckets: []float64{0.5, 1}})
+	histogram.Observe(0.25)
+	histogram.Observe(2)
+
+	stamped := &stampedCollector{desc: prometheus.NewDesc("instantaneous_demand", "h", nil, nil), value: 1.5, ts: now.Add(-time.Second * 10)}
+
+	require.NoError(t, reg.Register(counter))
+	require.NoError(t, reg.Register(histogram))
+	require.NoError(t, reg.Register(stamped))
+	return reg, stamped
+}
+
+func TestToSeries(t *testing.T) {
+	reg, _ := testRegistry(t)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	req := &writeRequest{}
+	require.NoError(t, proto.Unmarshal(encode(toSeries(families, now, map[string]string{"job": "reagled"})), req))
+
+	all := flat(req)
+	assert.Len(t, all, 7)
+
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	assert.Equal(t, sample{Value: 3, Timestamp: nowMs}, all["__name__=requests_total;code=200;job=reagled;"])
+	assert.Equal(t, sample{Value: 1, Timestamp: nowMs}, all["__name__=latency_seconds_bucket;job=reagled;le=0.5;"])
+	assert.Equal(t, sample{Value: 1, Timestamp: nowMs}, all["__name__=latency_seconds_bucket;job=reagled;le=1;"])
+	assert.Equal(t, sample{Value: 2, Timestamp: nowMs}, all["__name__=latency_seconds_bucket;job=reagled;le=+Inf;"])
+	assert.Equal(t, sample{Value: 2.25, Timestamp: nowMs}, all["__name__=latency_seconds_sum;job=reagled;"])
+	assert.Equal(t, sample{Value: 1.5, Timestamp: nowMs - 10000}, all["__name__=instantaneous_demand;job=reagled;"], "keeps its own timestamp")
+}
+
+//endpoint is a remote write stand in that records decoded requests while up
+type endpoint struct {
+	mu       sync.Mutex
+	status   int
+	requests []*writeRequest
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Content-Encoding") != "snappy" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if e.status != http.StatusNoContent {
+		w.WriteHeader(e.status)
+		return
+	}
+
+	compressed, _ := ioutil.ReadAll(r.Body)
+	b, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &writeRequest{}
+	if err := proto.Unmarshal(b, req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.requests = append(e.requests, req)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *endpoint) set(status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.status = status
+}
+
+func (e *endpoint) take() []*writeRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	taken := e.requests
+	e.requests = nil
+	return taken
+}
+
+func TestPusherBuffers(t *testing.T) {
+	reg, stamped := testRegistry(t)
+
+	e := &endpoint{status: http.StatusServiceUnavailable}
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	p, err := NewPusher(ts.Client(), reg, Config{URL: ts.URL, BearerToken: "secret", MaxPending: 2})
+	require.NoError(t, err)
+	p.Retries = 0
+
+	outcomes := make(map[string]int)
+	p.Result = func(outcome string, samples int, err error) {
+		outcomes[outcome] += samples
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		p.Push(ctx, now.Add(time.Minute*time.Duration(i)))
+	}
+
+	assert.Equal(t, 2, p.Pending())
+	assert.Equal(t, 7, outcomes[Dropped], "the first batch was dropped to make room")
+	assert.Empty(t, e.take())
+
+	//the stamped gauge was only in the dropped first batch, it has a new sample now
+	stamped.set(2.5, now.Add(time.Minute*3))
+	e.set(http.StatusNoContent)
+	p.Push(ctx, now.Add(time.Minute*3))
+
+	assert.Equal(t, 0, p.Pending())
+	assert.Equal(t, 13, outcomes[Sent])
+	requests := e.take()
+	require.Len(t, requests, 2)
+
+	minuteMs := int64(time.Minute / time.Millisecond)
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	for i, req := range requests {
+		all := flat(req)
+		assert.Equal(t, nowMs+minuteMs*int64(i+2), all["__name__=requests_total;code=200;"].Timestamp, "oldest first")
+
+		_, hasStamped := all["__name__=instantaneous_demand;"]
+		assert.Equal(t, i == 1, hasStamped)
+	}
+
+	e.set(http.StatusBadRequest)
+	p.Push(ctx, now.Add(time.Minute*4))
+	assert.Equal(t, 0, p.Pending(), "a rejected batch would never succeed")
+}
@@ -0,0 +1,143 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+type (
+	label struct {
+		name  string
+		value string
+	}
+
+	//series is one sample of a time series, labels are sorted by name with __name__ among them
+	series struct {
+		labels    []label
+		value     float64
+		timestamp int64
+
+		//stamped is true when the metric had its own timestamp rather than the time of the gather
+		stamped bool
+	}
+)
+
+//key identifies the time series regardless of the sample
+func (s series) key() string {
+	var b strings.Builder
+	for _, l := range s.labels {
+		b.WriteString(l.name)
+		b.WriteByte(0)
+		b.WriteString(l.value)
+		b.WriteByte(0)
+	}
+
+	return b.String()
+}
+
+//toSeries flattens metric families the way the text exposition does, summaries and histograms become a series per
+//quantile or bucket plus _sum and _count.  Metrics without a timestamp get now, external labels do not override
+//a metric's own labels
+func toSeries(families []*dto.MetricFamily, now time.Time, external map[string]string) []series {
+	var all []series
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			ts := timestampMs(now)
+			stamped := m.TimestampMs != nil
+			if stamped {
+				ts = m.GetTimestampMs()
+			}
+
+			add := func(suffix string, value float64, extra ...label) {
+				all = append(all, series{
+					labels:    labels(name+suffix, m.GetLabel(), external, extra...),
+					value:     value,
+					timestamp: ts,
+					stamped:   stamped,
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					add("", q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+
+				add("_sum", summary.GetSampleSum())
+				add("_count", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				histogram := m.GetHistogram()
+				infinite := false
+				for _, b := range histogram.GetBucket() {
+					infinite = infinite || math.IsInf(b.GetUpperBound(), 1)
+					add("_bucket", float64(b.GetCumulativeCount()), label{"le", formatFloat(b.GetUpperBound())})
+				}
+
+				if !infinite {
+					add("_bucket", float64(histogram.GetSampleCount()), label{"le", "+Inf"})
+				}
+
+				add("_sum", histogram.GetSampleSum())
+				add("_count", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+
+	return all
+}
+
+func labels(name string, pairs []*dto.LabelPair, external map[string]string, extra ...label) []label {
+	set := make(map[string]string, len(pairs)+len(external)+len(extra)+1)
+	for k, v := range external {
+		set[k] = v
+	}
+
+	for _, p := range pairs {
+		set[p.GetName()] = p.GetValue()
+	}
+
+	for _, l := range extra {
+		set[l.name] = l.value
+	}
+
+	set["__name__"] = name
+
+	all := make([]label, 0, len(set))
+	for k, v := range set {
+		//an empty label is the same as no label
+		if v != "" {
+			all = append(all, label{k, v})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func timestampMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,95 @@
+/*
+Package retry posts to the endpoints readings and metrics are exported to, retrying failures with a doubling backoff.
+
+A response outside 2xx is a StatusError.  Whether it is worth retrying depends on the endpoint, most reject a request
+that will never succeed with a 4xx other than 429 and that is what ServerErrors retries.  Errors that are not a
+StatusError, such as the endpoint being unreachable, are always retryable.
+*/
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//StatusError is a post the endpoint responded to with an error
+type StatusError struct {
+	//Service names the endpoint in the error, such as influx write
+	Service    string
+	StatusCode int
+	Message    string
+
+	retryable bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %v: %s", e.Service, e.StatusCode, e.Message)
+}
+
+//Retryable is true unless the endpoint rejected the request itself
+func (e *StatusError) Retryable() bool {
+	return e.retryable
+}
+
+//Retryable is true unless err is a StatusError that is not
+func Retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Retryable()
+	}
+
+	return true
+}
+
+//ServerErrors retries a 429 and any 5xx
+func ServerErrors(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+//Post sends the request and returns a *StatusError for a response outside 2xx, which is retryable if retryStatus is
+//true for its status code
+func Post(client *http.Client, req *http.Request, service string, retryStatus func(statusCode int) bool) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		//drain so the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &StatusError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+		retryable:  retryStatus(resp.StatusCode),
+	}
+}
+
+//Backoff calls post until it succeeds, fails with an error that is not Retryable or has been retried retries times,
+//doubling backoff between each.  It returns the context's error if the context is done while waiting
+func Backoff(ctx context.Context, retries int, backoff time.Duration, post func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := post()
+		if err == nil || !Retryable(err) || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, status int, retryStatus func(int) bool) error {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, " bad request \n")
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL, nil)
+	require.NoError(t, err)
+	return Post(&http.Client{}, req, "test write", retryStatus)
+}
+
+func TestPost(t *testing.T) {
+	assert.NoError(t, post(t, http.StatusNoContent, ServerErrors))
+
+	err := post(t, http.StatusBadRequest, ServerErrors)
+	var status *StatusError
+	require.True(t, errors.As(err, &status))
+	assert.Equal(t, "test write returned 400: bad request", err.Error())
+	assert.False(t, Retryable(err))
+
+	assert.True(t, Retryable(post(t, http.StatusTooManyRequests, ServerErrors)))
+	assert.True(t, Retryable(post(t, http.StatusInternalServerError, ServerErrors)))
+	assert.False(t, Retryable(post(t, http.StatusInternalServerError, func(int) bool { return false })), "the endpoint decides what is retried")
+	assert.True(t, Retryable(errors.New("connection refused")))
+}
+
+func TestBackoff(t *testing.T) {
+	ctx := context.Background()
+	rejected := &StatusError{StatusCode: http.StatusBadRequest}
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable, retryable: true}
+
+	attempts := 0
+	err := Backoff(ctx, 3, time.Millisecond, func() error {
+		attempts++
+		return unavailable
+	})
+	assert.Equal(t, unavailable, err)
+	assert.Equal(t, 4, attempts, "tried once and retried 3 times")
+
+	attempts = 0
+	err = Backoff(ctx, 3, time.Millisecond, func() error {
+		attempts++
+		return rejected
+	})
+	assert.Equal(t, rejected, err)
+	assert.Equal(t, 1, attempts, "a rejected request is not retried")
+
+	attempts = 0
+	err = Backoff(ctx, 3, time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return unavailable
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = Backoff(canceled, 3, time.Hour, func() error { return unavailable })
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+//StartTestServer serves the ReagleServer in process over a buffer rather than the network, for testing it and things
+//that call it.  The returned func stops the server and closes the client's connection
+func StartTestServer(server ReagleServer, opts ...grpc.ServerOption) (ReagleClient, func(), error) {
+	listener := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer(opts...)
+	RegisterReagleServer(srv, server)
+	go srv.Serve(listener)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return listener.Dial() }),
+	)
+	if err != nil {
+		srv.Stop()
+		return nil, nil, err
+	}
+
+	return NewReagleClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}, nil
+}
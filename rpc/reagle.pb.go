@@ -0,0 +1,1383 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: reagle.proto
+
+package rpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type GetBaseMetricsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetBaseMetricsRequest) Reset()         { *m = GetBaseMetricsRequest{} }
+func (m *GetBaseMetricsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetBaseMetricsRequest) ProtoMessage()    {}
+func (*GetBaseMetricsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{0}
+}
+func (m *GetBaseMetricsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetBaseMetricsRequest.Unmarshal(m, b)
+}
+func (m *GetBaseMetricsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetBaseMetricsRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetBaseMetricsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetBaseMetricsRequest.Merge(dst, src)
+}
+func (m *GetBaseMetricsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetBaseMetricsRequest.Size(m)
+}
+func (m *GetBaseMetricsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetBaseMetricsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetBaseMetricsRequest proto.InternalMessageInfo
+
+type GetVariableRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetVariableRequest) Reset()         { *m = GetVariableRequest{} }
+func (m *GetVariableRequest) String() string { return proto.CompactTextString(m) }
+func (*GetVariableRequest) ProtoMessage()    {}
+func (*GetVariableRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{1}
+}
+func (m *GetVariableRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetVariableRequest.Unmarshal(m, b)
+}
+func (m *GetVariableRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetVariableRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetVariableRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetVariableRequest.Merge(dst, src)
+}
+func (m *GetVariableRequest) XXX_Size() int {
+	return xxx_messageInfo_GetVariableRequest.Size(m)
+}
+func (m *GetVariableRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetVariableRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetVariableRequest proto.InternalMessageInfo
+
+func (m *GetVariableRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type GetAllVariablesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAllVariablesRequest) Reset()         { *m = GetAllVariablesRequest{} }
+func (m *GetAllVariablesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAllVariablesRequest) ProtoMessage()    {}
+func (*GetAllVariablesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{2}
+}
+func (m *GetAllVariablesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAllVariablesRequest.Unmarshal(m, b)
+}
+func (m *GetAllVariablesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAllVariablesRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetAllVariablesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAllVariablesRequest.Merge(dst, src)
+}
+func (m *GetAllVariablesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAllVariablesRequest.Size(m)
+}
+func (m *GetAllVariablesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAllVariablesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAllVariablesRequest proto.InternalMessageInfo
+
+type GetMeterDetailsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMeterDetailsRequest) Reset()         { *m = GetMeterDetailsRequest{} }
+func (m *GetMeterDetailsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMeterDetailsRequest) ProtoMessage()    {}
+func (*GetMeterDetailsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{3}
+}
+func (m *GetMeterDetailsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMeterDetailsRequest.Unmarshal(m, b)
+}
+func (m *GetMeterDetailsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMeterDetailsRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetMeterDetailsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMeterDetailsRequest.Merge(dst, src)
+}
+func (m *GetMeterDetailsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMeterDetailsRequest.Size(m)
+}
+func (m *GetMeterDetailsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMeterDetailsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMeterDetailsRequest proto.InternalMessageInfo
+
+type GetDeviceListRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDeviceListRequest) Reset()         { *m = GetDeviceListRequest{} }
+func (m *GetDeviceListRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDeviceListRequest) ProtoMessage()    {}
+func (*GetDeviceListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{4}
+}
+func (m *GetDeviceListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDeviceListRequest.Unmarshal(m, b)
+}
+func (m *GetDeviceListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDeviceListRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetDeviceListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDeviceListRequest.Merge(dst, src)
+}
+func (m *GetDeviceListRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDeviceListRequest.Size(m)
+}
+func (m *GetDeviceListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDeviceListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDeviceListRequest proto.InternalMessageInfo
+
+type GetWifiStatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetWifiStatusRequest) Reset()         { *m = GetWifiStatusRequest{} }
+func (m *GetWifiStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*GetWifiStatusRequest) ProtoMessage()    {}
+func (*GetWifiStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{5}
+}
+func (m *GetWifiStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetWifiStatusRequest.Unmarshal(m, b)
+}
+func (m *GetWifiStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetWifiStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetWifiStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetWifiStatusRequest.Merge(dst, src)
+}
+func (m *GetWifiStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_GetWifiStatusRequest.Size(m)
+}
+func (m *GetWifiStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetWifiStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetWifiStatusRequest proto.InternalMessageInfo
+
+type WatchReadingsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchReadingsRequest) Reset()         { *m = WatchReadingsRequest{} }
+func (m *WatchReadingsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchReadingsRequest) ProtoMessage()    {}
+func (*WatchReadingsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{6}
+}
+func (m *WatchReadingsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchReadingsRequest.Unmarshal(m, b)
+}
+func (m *WatchReadingsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchReadingsRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchReadingsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchReadingsRequest.Merge(dst, src)
+}
+func (m *WatchReadingsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchReadingsRequest.Size(m)
+}
+func (m *WatchReadingsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchReadingsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchReadingsRequest proto.InternalMessageInfo
+
+// BaseMetrics has demand in kW and energy in kWh.
+type BaseMetrics struct {
+	Demand               float64  `protobuf:"fixed64,1,opt,name=demand,proto3" json:"demand,omitempty"`
+	Delivered            float64  `protobuf:"fixed64,2,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	Received             float64  `protobuf:"fixed64,3,opt,name=received,proto3" json:"received,omitempty"`
+	Price                float64  `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+	Currency             string   `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BaseMetrics) Reset()         { *m = BaseMetrics{} }
+func (m *BaseMetrics) String() string { return proto.CompactTextString(m) }
+func (*BaseMetrics) ProtoMessage()    {}
+func (*BaseMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{7}
+}
+func (m *BaseMetrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BaseMetrics.Unmarshal(m, b)
+}
+func (m *BaseMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BaseMetrics.Marshal(b, m, deterministic)
+}
+func (dst *BaseMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BaseMetrics.Merge(dst, src)
+}
+func (m *BaseMetrics) XXX_Size() int {
+	return xxx_messageInfo_BaseMetrics.Size(m)
+}
+func (m *BaseMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_BaseMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BaseMetrics proto.InternalMessageInfo
+
+func (m *BaseMetrics) GetDemand() float64 {
+	if m != nil {
+		return m.Demand
+	}
+	return 0
+}
+
+func (m *BaseMetrics) GetDelivered() float64 {
+	if m != nil {
+		return m.Delivered
+	}
+	return 0
+}
+
+func (m *BaseMetrics) GetReceived() float64 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *BaseMetrics) GetPrice() float64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *BaseMetrics) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+// Reading is BaseMetrics and when they were read.
+type Reading struct {
+	Time                 *timestamp.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Metrics              *BaseMetrics         `protobuf:"bytes,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Reading) Reset()         { *m = Reading{} }
+func (m *Reading) String() string { return proto.CompactTextString(m) }
+func (*Reading) ProtoMessage()    {}
+func (*Reading) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{8}
+}
+func (m *Reading) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Reading.Unmarshal(m, b)
+}
+func (m *Reading) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Reading.Marshal(b, m, deterministic)
+}
+func (dst *Reading) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Reading.Merge(dst, src)
+}
+func (m *Reading) XXX_Size() int {
+	return xxx_messageInfo_Reading.Size(m)
+}
+func (m *Reading) XXX_DiscardUnknown() {
+	xxx_messageInfo_Reading.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Reading proto.InternalMessageInfo
+
+func (m *Reading) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *Reading) GetMetrics() *BaseMetrics {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+// Variable is a queried variable, value is not set if the eagle reported it as undefined.
+type Variable struct {
+	Name        string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Raw         string `protobuf:"bytes,2,opt,name=raw,proto3" json:"raw,omitempty"`
+	Units       string `protobuf:"bytes,3,opt,name=units,proto3" json:"units,omitempty"`
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//	*Variable_FloatValue
+	//	*Variable_IntValue
+	//	*Variable_TextValue
+	//	*Variable_TimeValue
+	Value                isVariable_Value `protobuf_oneof:"value"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *Variable) Reset()         { *m = Variable{} }
+func (m *Variable) String() string { return proto.CompactTextString(m) }
+func (*Variable) ProtoMessage()    {}
+func (*Variable) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{9}
+}
+func (m *Variable) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Variable.Unmarshal(m, b)
+}
+func (m *Variable) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Variable.Marshal(b, m, deterministic)
+}
+func (dst *Variable) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Variable.Merge(dst, src)
+}
+func (m *Variable) XXX_Size() int {
+	return xxx_messageInfo_Variable.Size(m)
+}
+func (m *Variable) XXX_DiscardUnknown() {
+	xxx_messageInfo_Variable.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Variable proto.InternalMessageInfo
+
+func (m *Variable) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Variable) GetRaw() string {
+	if m != nil {
+		return m.Raw
+	}
+	return ""
+}
+
+func (m *Variable) GetUnits() string {
+	if m != nil {
+		return m.Units
+	}
+	return ""
+}
+
+func (m *Variable) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+type isVariable_Value interface {
+	isVariable_Value()
+}
+
+type Variable_FloatValue struct {
+	FloatValue float64 `protobuf:"fixed64,5,opt,name=float_value,json=floatValue,proto3,oneof"`
+}
+
+type Variable_IntValue struct {
+	IntValue int64 `protobuf:"varint,6,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Variable_TextValue struct {
+	TextValue string `protobuf:"bytes,7,opt,name=text_value,json=textValue,proto3,oneof"`
+}
+
+type Variable_TimeValue struct {
+	TimeValue *timestamp.Timestamp `protobuf:"bytes,8,opt,name=time_value,json=timeValue,proto3,oneof"`
+}
+
+func (*Variable_FloatValue) isVariable_Value() {}
+
+func (*Variable_IntValue) isVariable_Value() {}
+
+func (*Variable_TextValue) isVariable_Value() {}
+
+func (*Variable_TimeValue) isVariable_Value() {}
+
+func (m *Variable) GetValue() isVariable_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Variable) GetFloatValue() float64 {
+	if x, ok := m.GetValue().(*Variable_FloatValue); ok {
+		return x.FloatValue
+	}
+	return 0
+}
+
+func (m *Variable) GetIntValue() int64 {
+	if x, ok := m.GetValue().(*Variable_IntValue); ok {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (m *Variable) GetTextValue() string {
+	if x, ok := m.GetValue().(*Variable_TextValue); ok {
+		return x.TextValue
+	}
+	return ""
+}
+
+func (m *Variable) GetTimeValue() *timestamp.Timestamp {
+	if x, ok := m.GetValue().(*Variable_TimeValue); ok {
+		return x.TimeValue
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*Variable) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _Variable_OneofMarshaler, _Variable_OneofUnmarshaler, _Variable_OneofSizer, []interface{}{
+		(*Variable_FloatValue)(nil),
+		(*Variable_IntValue)(nil),
+		(*Variable_TextValue)(nil),
+		(*Variable_TimeValue)(nil),
+	}
+}
+
+func _Variable_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*Variable)
+	// value
+	switch x := m.Value.(type) {
+	case *Variable_FloatValue:
+		b.EncodeVarint(5<<3 | proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(x.FloatValue))
+	case *Variable_IntValue:
+		b.EncodeVarint(6<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.IntValue))
+	case *Variable_TextValue:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.TextValue)
+	case *Variable_TimeValue:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.TimeValue); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("Variable.Value has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _Variable_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*Variable)
+	switch tag {
+	case 5: // value.float_value
+		if wire != proto.WireFixed64 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed64()
+		m.Value = &Variable_FloatValue{math.Float64frombits(x)}
+		return true, err
+	case 6: // value.int_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &Variable_IntValue{int64(x)}
+		return true, err
+	case 7: // value.text_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Value = &Variable_TextValue{x}
+		return true, err
+	case 8: // value.time_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(timestamp.Timestamp)
+		err := b.DecodeMessage(msg)
+		m.Value = &Variable_TimeValue{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _Variable_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*Variable)
+	// value
+	switch x := m.Value.(type) {
+	case *Variable_FloatValue:
+		n += 1 // tag and wire
+		n += 8
+	case *Variable_IntValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.IntValue))
+	case *Variable_TextValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.TextValue)))
+		n += len(x.TextValue)
+	case *Variable_TimeValue:
+		s := proto.Size(x.TimeValue)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// Component is a group of variables on a device.
+type Component struct {
+	Name                 string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Variables            []*Variable `protobuf:"bytes,2,rep,name=variables,proto3" json:"variables,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *Component) Reset()         { *m = Component{} }
+func (m *Component) String() string { return proto.CompactTextString(m) }
+func (*Component) ProtoMessage()    {}
+func (*Component) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{10}
+}
+func (m *Component) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Component.Unmarshal(m, b)
+}
+func (m *Component) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Component.Marshal(b, m, deterministic)
+}
+func (dst *Component) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Component.Merge(dst, src)
+}
+func (m *Component) XXX_Size() int {
+	return xxx_messageInfo_Component.Size(m)
+}
+func (m *Component) XXX_DiscardUnknown() {
+	xxx_messageInfo_Component.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Component proto.InternalMessageInfo
+
+func (m *Component) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Component) GetVariables() []*Variable {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+type Components struct {
+	Components           []*Component `protobuf:"bytes,1,rep,name=components,proto3" json:"components,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Components) Reset()         { *m = Components{} }
+func (m *Components) String() string { return proto.CompactTextString(m) }
+func (*Components) ProtoMessage()    {}
+func (*Components) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{11}
+}
+func (m *Components) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Components.Unmarshal(m, b)
+}
+func (m *Components) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Components.Marshal(b, m, deterministic)
+}
+func (dst *Components) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Components.Merge(dst, src)
+}
+func (m *Components) XXX_Size() int {
+	return xxx_messageInfo_Components.Size(m)
+}
+func (m *Components) XXX_DiscardUnknown() {
+	xxx_messageInfo_Components.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Components proto.InternalMessageInfo
+
+func (m *Components) GetComponents() []*Component {
+	if m != nil {
+		return m.Components
+	}
+	return nil
+}
+
+type Device struct {
+	HardwareAddress      string               `protobuf:"bytes,1,opt,name=hardware_address,json=hardwareAddress,proto3" json:"hardware_address,omitempty"`
+	Manufacturer         string               `protobuf:"bytes,2,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	ModelId              string               `protobuf:"bytes,3,opt,name=model_id,json=modelId,proto3" json:"model_id,omitempty"`
+	Protocol             string               `protobuf:"bytes,4,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	LastContact          *timestamp.Timestamp `protobuf:"bytes,5,opt,name=last_contact,json=lastContact,proto3" json:"last_contact,omitempty"`
+	ConnectionStatus     string               `protobuf:"bytes,6,opt,name=connection_status,json=connectionStatus,proto3" json:"connection_status,omitempty"`
+	NetworkAddress       int64                `protobuf:"varint,7,opt,name=network_address,json=networkAddress,proto3" json:"network_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Device) Reset()         { *m = Device{} }
+func (m *Device) String() string { return proto.CompactTextString(m) }
+func (*Device) ProtoMessage()    {}
+func (*Device) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{12}
+}
+func (m *Device) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Device.Unmarshal(m, b)
+}
+func (m *Device) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Device.Marshal(b, m, deterministic)
+}
+func (dst *Device) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Device.Merge(dst, src)
+}
+func (m *Device) XXX_Size() int {
+	return xxx_messageInfo_Device.Size(m)
+}
+func (m *Device) XXX_DiscardUnknown() {
+	xxx_messageInfo_Device.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Device proto.InternalMessageInfo
+
+func (m *Device) GetHardwareAddress() string {
+	if m != nil {
+		return m.HardwareAddress
+	}
+	return ""
+}
+
+func (m *Device) GetManufacturer() string {
+	if m != nil {
+		return m.Manufacturer
+	}
+	return ""
+}
+
+func (m *Device) GetModelId() string {
+	if m != nil {
+		return m.ModelId
+	}
+	return ""
+}
+
+func (m *Device) GetProtocol() string {
+	if m != nil {
+		return m.Protocol
+	}
+	return ""
+}
+
+func (m *Device) GetLastContact() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastContact
+	}
+	return nil
+}
+
+func (m *Device) GetConnectionStatus() string {
+	if m != nil {
+		return m.ConnectionStatus
+	}
+	return ""
+}
+
+func (m *Device) GetNetworkAddress() int64 {
+	if m != nil {
+		return m.NetworkAddress
+	}
+	return 0
+}
+
+// ComponentNames is a component and the names of its variables.
+type ComponentNames struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	HardwareId           string   `protobuf:"bytes,2,opt,name=hardware_id,json=hardwareId,proto3" json:"hardware_id,omitempty"`
+	FixedId              int64    `protobuf:"varint,3,opt,name=fixed_id,json=fixedId,proto3" json:"fixed_id,omitempty"`
+	Variables            []string `protobuf:"bytes,4,rep,name=variables,proto3" json:"variables,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ComponentNames) Reset()         { *m = ComponentNames{} }
+func (m *ComponentNames) String() string { return proto.CompactTextString(m) }
+func (*ComponentNames) ProtoMessage()    {}
+func (*ComponentNames) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{13}
+}
+func (m *ComponentNames) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ComponentNames.Unmarshal(m, b)
+}
+func (m *ComponentNames) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ComponentNames.Marshal(b, m, deterministic)
+}
+func (dst *ComponentNames) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ComponentNames.Merge(dst, src)
+}
+func (m *ComponentNames) XXX_Size() int {
+	return xxx_messageInfo_ComponentNames.Size(m)
+}
+func (m *ComponentNames) XXX_DiscardUnknown() {
+	xxx_messageInfo_ComponentNames.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ComponentNames proto.InternalMessageInfo
+
+func (m *ComponentNames) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ComponentNames) GetHardwareId() string {
+	if m != nil {
+		return m.HardwareId
+	}
+	return ""
+}
+
+func (m *ComponentNames) GetFixedId() int64 {
+	if m != nil {
+		return m.FixedId
+	}
+	return 0
+}
+
+func (m *ComponentNames) GetVariables() []string {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+type MeterDetails struct {
+	Device               *Device           `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Components           []*ComponentNames `protobuf:"bytes,2,rep,name=components,proto3" json:"components,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *MeterDetails) Reset()         { *m = MeterDetails{} }
+func (m *MeterDetails) String() string { return proto.CompactTextString(m) }
+func (*MeterDetails) ProtoMessage()    {}
+func (*MeterDetails) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{14}
+}
+func (m *MeterDetails) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MeterDetails.Unmarshal(m, b)
+}
+func (m *MeterDetails) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MeterDetails.Marshal(b, m, deterministic)
+}
+func (dst *MeterDetails) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MeterDetails.Merge(dst, src)
+}
+func (m *MeterDetails) XXX_Size() int {
+	return xxx_messageInfo_MeterDetails.Size(m)
+}
+func (m *MeterDetails) XXX_DiscardUnknown() {
+	xxx_messageInfo_MeterDetails.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MeterDetails proto.InternalMessageInfo
+
+func (m *MeterDetails) GetDevice() *Device {
+	if m != nil {
+		return m.Device
+	}
+	return nil
+}
+
+func (m *MeterDetails) GetComponents() []*ComponentNames {
+	if m != nil {
+		return m.Components
+	}
+	return nil
+}
+
+type DeviceList struct {
+	Devices              []*Device `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *DeviceList) Reset()         { *m = DeviceList{} }
+func (m *DeviceList) String() string { return proto.CompactTextString(m) }
+func (*DeviceList) ProtoMessage()    {}
+func (*DeviceList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{15}
+}
+func (m *DeviceList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeviceList.Unmarshal(m, b)
+}
+func (m *DeviceList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeviceList.Marshal(b, m, deterministic)
+}
+func (dst *DeviceList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeviceList.Merge(dst, src)
+}
+func (m *DeviceList) XXX_Size() int {
+	return xxx_messageInfo_DeviceList.Size(m)
+}
+func (m *DeviceList) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeviceList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeviceList proto.InternalMessageInfo
+
+func (m *DeviceList) GetDevices() []*Device {
+	if m != nil {
+		return m.Devices
+	}
+	return nil
+}
+
+type WifiStatus struct {
+	Enabled              bool     `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Ssid                 string   `protobuf:"bytes,3,opt,name=ssid,proto3" json:"ssid,omitempty"`
+	Encryption           string   `protobuf:"bytes,4,opt,name=encryption,proto3" json:"encryption,omitempty"`
+	Channel              int64    `protobuf:"varint,5,opt,name=channel,proto3" json:"channel,omitempty"`
+	IpAddress            string   `protobuf:"bytes,6,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WifiStatus) Reset()         { *m = WifiStatus{} }
+func (m *WifiStatus) String() string { return proto.CompactTextString(m) }
+func (*WifiStatus) ProtoMessage()    {}
+func (*WifiStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_reagle_0245062d95010e5c, []int{16}
+}
+func (m *WifiStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WifiStatus.Unmarshal(m, b)
+}
+func (m *WifiStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WifiStatus.Marshal(b, m, deterministic)
+}
+func (dst *WifiStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WifiStatus.Merge(dst, src)
+}
+func (m *WifiStatus) XXX_Size() int {
+	return xxx_messageInfo_WifiStatus.Size(m)
+}
+func (m *WifiStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_WifiStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WifiStatus proto.InternalMessageInfo
+
+func (m *WifiStatus) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *WifiStatus) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *WifiStatus) GetSsid() string {
+	if m != nil {
+		return m.Ssid
+	}
+	return ""
+}
+
+func (m *WifiStatus) GetEncryption() string {
+	if m != nil {
+		return m.Encryption
+	}
+	return ""
+}
+
+func (m *WifiStatus) GetChannel() int64 {
+	if m != nil {
+		return m.Channel
+	}
+	return 0
+}
+
+func (m *WifiStatus) GetIpAddress() string {
+	if m != nil {
+		return m.IpAddress
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*GetBaseMetricsRequest)(nil), "reagle.GetBaseMetricsRequest")
+	proto.RegisterType((*GetVariableRequest)(nil), "reagle.GetVariableRequest")
+	proto.RegisterType((*GetAllVariablesRequest)(nil), "reagle.GetAllVariablesRequest")
+	proto.RegisterType((*GetMeterDetailsRequest)(nil), "reagle.GetMeterDetailsRequest")
+	proto.RegisterType((*GetDeviceListRequest)(nil), "reagle.GetDeviceListRequest")
+	proto.RegisterType((*GetWifiStatusRequest)(nil), "reagle.GetWifiStatusRequest")
+	proto.RegisterType((*WatchReadingsRequest)(nil), "reagle.WatchReadingsRequest")
+	proto.RegisterType((*BaseMetrics)(nil), "reagle.BaseMetrics")
+	proto.RegisterType((*Reading)(nil), "reagle.Reading")
+	proto.RegisterType((*Variable)(nil), "reagle.Variable")
+	proto.RegisterType((*Component)(nil), "reagle.Component")
+	proto.RegisterType((*Components)(nil), "reagle.Components")
+	proto.RegisterType((*Device)(nil), "reagle.Device")
+	proto.RegisterType((*ComponentNames)(nil), "reagle.ComponentNames")
+	proto.RegisterType((*MeterDetails)(nil), "reagle.MeterDetails")
+	proto.RegisterType((*DeviceList)(nil), "reagle.DeviceList")
+	proto.RegisterType((*WifiStatus)(nil), "reagle.WifiStatus")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ReagleClient is the client API for Reagle service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ReagleClient interface {
+	// GetBaseMetrics is the most commonly used meter readings.
+	GetBaseMetrics(ctx context.Context, in *GetBaseMetricsRequest, opts ...grpc.CallOption) (*BaseMetrics, error)
+	// GetVariable queries one variable on the meter.
+	GetVariable(ctx context.Context, in *GetVariableRequest, opts ...grpc.CallOption) (*Components, error)
+	// GetAllVariables queries every variable the meter has.
+	GetAllVariables(ctx context.Context, in *GetAllVariablesRequest, opts ...grpc.CallOption) (*Components, error)
+	// GetMeterDetails is the meter's device details and the variables it has.
+	GetMeterDetails(ctx context.Context, in *GetMeterDetailsRequest, opts ...grpc.CallOption) (*MeterDetails, error)
+	// GetDeviceList is every device on the eagle's zigbee network.
+	GetDeviceList(ctx context.Context, in *GetDeviceListRequest, opts ...grpc.CallOption) (*DeviceList, error)
+	// GetWifiStatus is the eagle's wifi status.
+	GetWifiStatus(ctx context.Context, in *GetWifiStatusRequest, opts ...grpc.CallOption) (*WifiStatus, error)
+	// WatchReadings streams each reading reagled polls or is pushed, starting with the latest if there is one.
+	WatchReadings(ctx context.Context, in *WatchReadingsRequest, opts ...grpc.CallOption) (Reagle_WatchReadingsClient, error)
+}
+
+type reagleClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewReagleClient(cc *grpc.ClientConn) ReagleClient {
+	return &reagleClient{cc}
+}
+
+func (c *reagleClient) GetBaseMetrics(ctx context.Context, in *GetBaseMetricsRequest, opts ...grpc.CallOption) (*BaseMetrics, error) {
+	out := new(BaseMetrics)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetBaseMetrics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) GetVariable(ctx context.Context, in *GetVariableRequest, opts ...grpc.CallOption) (*Components, error) {
+	out := new(Components)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetVariable", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) GetAllVariables(ctx context.Context, in *GetAllVariablesRequest, opts ...grpc.CallOption) (*Components, error) {
+	out := new(Components)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetAllVariables", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) GetMeterDetails(ctx context.Context, in *GetMeterDetailsRequest, opts ...grpc.CallOption) (*MeterDetails, error) {
+	out := new(MeterDetails)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetMeterDetails", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) GetDeviceList(ctx context.Context, in *GetDeviceListRequest, opts ...grpc.CallOption) (*DeviceList, error) {
+	out := new(DeviceList)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetDeviceList", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) GetWifiStatus(ctx context.Context, in *GetWifiStatusRequest, opts ...grpc.CallOption) (*WifiStatus, error) {
+	out := new(WifiStatus)
+	err := c.cc.Invoke(ctx, "/reagle.Reagle/GetWifiStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reagleClient) WatchReadings(ctx context.Context, in *WatchReadingsRequest, opts ...grpc.CallOption) (Reagle_WatchReadingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Reagle_serviceDesc.Streams[0], "/reagle.Reagle/WatchReadings", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reagleWatchReadingsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Reagle_WatchReadingsClient interface {
+	Recv() (*Reading, error)
+	grpc.ClientStream
+}
+
+type reagleWatchReadingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *reagleWatchReadingsClient) Recv() (*Reading, error) {
+	m := new(Reading)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReagleServer is the server API for Reagle service.
+type ReagleServer interface {
+	// GetBaseMetrics is the most commonly used meter readings.
+	GetBaseMetrics(context.Context, *GetBaseMetricsRequest) (*BaseMetrics, error)
+	// GetVariable queries one variable on the meter.
+	GetVariable(context.Context, *GetVariableRequest) (*Components, error)
+	// GetAllVariables queries every variable the meter has.
+	GetAllVariables(context.Context, *GetAllVariablesRequest) (*Components, error)
+	// GetMeterDetails is the meter's device details and the variables it has.
+	GetMeterDetails(context.Context, *GetMeterDetailsRequest) (*MeterDetails, error)
+	// GetDeviceList is every device on the eagle's zigbee network.
+	GetDeviceList(context.Context, *GetDeviceListRequest) (*DeviceList, error)
+	// GetWifiStatus is the eagle's wifi status.
+	GetWifiStatus(context.Context, *GetWifiStatusRequest) (*WifiStatus, error)
+	// WatchReadings streams each reading reagled polls or is pushed, starting with the latest if there is one.
+	WatchReadings(*WatchReadingsRequest, Reagle_WatchReadingsServer) error
+}
+
+func RegisterReagleServer(s *grpc.Server, srv ReagleServer) {
+	s.RegisterService(&_Reagle_serviceDesc, srv)
+}
+
+func _Reagle_GetBaseMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBaseMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetBaseMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetBaseMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetBaseMetrics(ctx, req.(*GetBaseMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_GetVariable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVariableRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetVariable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetVariable",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetVariable(ctx, req.(*GetVariableRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_GetAllVariables_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllVariablesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetAllVariables(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetAllVariables",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetAllVariables(ctx, req.(*GetAllVariablesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_GetMeterDetails_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMeterDetailsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetMeterDetails(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetMeterDetails",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetMeterDetails(ctx, req.(*GetMeterDetailsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_GetDeviceList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetDeviceList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetDeviceList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetDeviceList(ctx, req.(*GetDeviceListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_GetWifiStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWifiStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReagleServer).GetWifiStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/reagle.Reagle/GetWifiStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReagleServer).GetWifiStatus(ctx, req.(*GetWifiStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reagle_WatchReadings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchReadingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReagleServer).WatchReadings(m, &reagleWatchReadingsServer{stream})
+}
+
+type Reagle_WatchReadingsServer interface {
+	Send(*Reading) error
+	grpc.ServerStream
+}
+
+type reagleWatchReadingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *reagleWatchReadingsServer) Send(m *Reading) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Reagle_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "reagle.Reagle",
+	HandlerType: (*ReagleServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBaseMetrics",
+			Handler:    _Reagle_GetBaseMetrics_Handler,
+		},
+		{
+			MethodName: "GetVariable",
+			Handler:    _Reagle_GetVariable_Handler,
+		},
+		{
+			MethodName: "GetAllVariables",
+			Handler:    _Reagle_GetAllVariables_Handler,
+		},
+		{
+			MethodName: "GetMeterDetails",
+			Handler:    _Reagle_GetMeterDetails_Handler,
+		},
+		{
+			MethodName: "GetDeviceList",
+			Handler:    _Reagle_GetDeviceList_Handler,
+		},
+		{
+			MethodName: "GetWifiStatus",
+			Handler:    _Reagle_GetWifiStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchReadings",
+			Handler:       _Reagle_WatchReadings_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "reagle.proto",
+}
+
+func init() { proto.RegisterFile("reagle.proto", fileDescriptor_reagle_0245062d95010e5c) }
+
+var fileDescriptor_reagle_0245062d95010e5c = []byte{
+	// 925 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0xdd, 0x6e, 0x1b, 0x45,
+	0x14, 0xae, 0xb3, 0xf1, 0xcf, 0x1e, 0xa7, 0x49, 0x7a, 0x08, 0x61, 0xb1, 0x9a, 0xc6, 0xec, 0x05,
+	0x18, 0x21, 0x5c, 0x08, 0x52, 0x6f, 0x50, 0x05, 0x49, 0x83, 0x9c, 0x48, 0x14, 0xa4, 0x01, 0xb5,
+	0x12, 0x37, 0xd6, 0x64, 0xf7, 0x38, 0x19, 0xb1, 0x3b, 0x6b, 0x66, 0xc7, 0x4e, 0x73, 0xc3, 0x3b,
+	0xf4, 0x19, 0xb8, 0xe0, 0xc9, 0x78, 0x0f, 0xb4, 0xb3, 0x33, 0xeb, 0x71, 0x62, 0xa9, 0x77, 0x7b,
+	0xbe, 0xf3, 0xb3, 0x73, 0xce, 0xf7, 0xcd, 0x1c, 0xd8, 0x51, 0xc4, 0xaf, 0x33, 0x1a, 0xcf, 0x55,
+	0xa1, 0x0b, 0xec, 0xd4, 0xd6, 0xe0, 0xf8, 0xba, 0x28, 0xae, 0x33, 0x7a, 0x6e, 0xd0, 0xab, 0xc5,
+	0xec, 0xb9, 0x16, 0x39, 0x95, 0x9a, 0xe7, 0xf3, 0x3a, 0x30, 0xfe, 0x04, 0x3e, 0x9e, 0x90, 0x3e,
+	0xe3, 0x25, 0xbd, 0x26, 0xad, 0x44, 0x52, 0x32, 0xfa, 0x6b, 0x41, 0xa5, 0x8e, 0x47, 0x80, 0x13,
+	0xd2, 0x6f, 0xb8, 0x12, 0xfc, 0x2a, 0x23, 0x8b, 0x22, 0xc2, 0xb6, 0xe4, 0x39, 0x45, 0xad, 0x61,
+	0x6b, 0x14, 0x32, 0xf3, 0x1d, 0x47, 0x70, 0x38, 0x21, 0x7d, 0x9a, 0x65, 0x2e, 0xb8, 0xa9, 0x51,
+	0x7b, 0x5e, 0x93, 0x26, 0x75, 0x4e, 0x9a, 0x8b, 0xac, 0xf1, 0x1c, 0xc2, 0xc1, 0x84, 0xf4, 0x39,
+	0x2d, 0x45, 0x42, 0x3f, 0x8b, 0x52, 0xaf, 0xe3, 0x6f, 0xc5, 0x4c, 0xfc, 0xa6, 0xb9, 0x5e, 0xf8,
+	0xf1, 0x6f, 0xb9, 0x4e, 0x6e, 0x18, 0xf1, 0x54, 0xc8, 0xeb, 0x06, 0x7f, 0xdf, 0x82, 0xbe, 0x77,
+	0x78, 0x3c, 0x84, 0x4e, 0x4a, 0x39, 0x97, 0xa9, 0x39, 0x61, 0x8b, 0x59, 0x0b, 0x9f, 0x42, 0x98,
+	0x52, 0x26, 0x96, 0xa4, 0x28, 0x8d, 0xb6, 0x8c, 0x6b, 0x05, 0xe0, 0x00, 0x7a, 0x8a, 0x12, 0x12,
+	0x4b, 0x4a, 0xa3, 0xc0, 0x38, 0x1b, 0x1b, 0x0f, 0xa0, 0x3d, 0x57, 0x22, 0xa1, 0x68, 0xdb, 0x38,
+	0x6a, 0xa3, 0xca, 0x48, 0x16, 0x4a, 0x91, 0x4c, 0xee, 0xa2, 0xb6, 0x99, 0x45, 0x63, 0xc7, 0x37,
+	0xd0, 0xb5, 0xc7, 0xc4, 0x31, 0x6c, 0x57, 0x03, 0x37, 0x87, 0xe9, 0x9f, 0x0c, 0xc6, 0x35, 0x1b,
+	0x63, 0xc7, 0xc6, 0xf8, 0x77, 0xc7, 0x06, 0x33, 0x71, 0xf8, 0x35, 0x74, 0xf3, 0xba, 0x13, 0x73,
+	0xc8, 0xfe, 0xc9, 0x47, 0x63, 0x4b, 0xab, 0xcf, 0x90, 0x8b, 0x89, 0xdf, 0x6f, 0x41, 0xcf, 0x0d,
+	0x7d, 0x13, 0x35, 0xb8, 0x0f, 0x81, 0xe2, 0xb7, 0xa6, 0x56, 0xc8, 0xaa, 0xcf, 0xaa, 0x9d, 0x85,
+	0x14, 0xba, 0x34, 0x7d, 0x86, 0xac, 0x36, 0x70, 0x08, 0xfd, 0x94, 0xca, 0x44, 0x89, 0xb9, 0x16,
+	0x85, 0x34, 0xad, 0x86, 0xcc, 0x87, 0xf0, 0x33, 0xe8, 0xcf, 0xb2, 0x82, 0xeb, 0xe9, 0x92, 0x67,
+	0x0b, 0x32, 0x3d, 0xb7, 0x2e, 0x1e, 0x31, 0x30, 0xe0, 0x9b, 0x0a, 0xc3, 0x23, 0x08, 0x85, 0x74,
+	0x01, 0x9d, 0x61, 0x6b, 0x14, 0x5c, 0x3c, 0x62, 0x3d, 0x21, 0xad, 0xfb, 0x18, 0x40, 0xd3, 0x3b,
+	0xe7, 0xef, 0x56, 0xbf, 0xb8, 0x78, 0xc4, 0xc2, 0x0a, 0xab, 0x03, 0xbe, 0x07, 0xa8, 0x86, 0x60,
+	0x03, 0x7a, 0x1f, 0x1a, 0x99, 0x49, 0x16, 0x39, 0x99, 0xe4, 0xb3, 0x2e, 0xb4, 0x4d, 0x5e, 0xfc,
+	0x2b, 0x84, 0xaf, 0x8a, 0x7c, 0x5e, 0x48, 0x92, 0x1b, 0xe5, 0x8a, 0x63, 0x08, 0x97, 0x4e, 0xa8,
+	0xd1, 0xd6, 0x30, 0x18, 0xf5, 0x4f, 0xf6, 0xdd, 0x94, 0x1b, 0xb9, 0xaf, 0x42, 0xe2, 0x1f, 0x00,
+	0x9a, 0x82, 0x25, 0x7e, 0x0b, 0x90, 0x34, 0x56, 0xd4, 0x32, 0xe9, 0x4f, 0x5c, 0x7a, 0x13, 0xc7,
+	0xbc, 0xa0, 0xf8, 0x9f, 0x2d, 0xe8, 0xd4, 0x4a, 0xc7, 0x2f, 0x61, 0xff, 0x86, 0xab, 0xf4, 0x96,
+	0x2b, 0x9a, 0xf2, 0x34, 0x55, 0x54, 0x96, 0xf6, 0x6c, 0x7b, 0x0e, 0x3f, 0xad, 0x61, 0x8c, 0x61,
+	0x27, 0xe7, 0x72, 0x31, 0xe3, 0x89, 0x5e, 0x28, 0x52, 0x96, 0xc3, 0x35, 0x0c, 0x3f, 0x85, 0x5e,
+	0x5e, 0xa4, 0x94, 0x4d, 0x45, 0x6a, 0xf9, 0xec, 0x1a, 0xfb, 0xd2, 0x48, 0xda, 0x8c, 0x2c, 0x29,
+	0x32, 0x4b, 0x67, 0x63, 0xe3, 0x4b, 0xd8, 0xc9, 0x78, 0xa9, 0xa7, 0x49, 0x21, 0x35, 0x4f, 0x74,
+	0xd4, 0xfe, 0xd0, 0xa8, 0x59, 0xbf, 0x8a, 0x7f, 0x55, 0x87, 0xe3, 0x57, 0xf0, 0x24, 0x29, 0xa4,
+	0xa4, 0xa4, 0x12, 0xc6, 0xb4, 0x34, 0xf7, 0xd4, 0xf0, 0x1d, 0xb2, 0xfd, 0x95, 0xa3, 0xbe, 0xbf,
+	0xf8, 0x05, 0xec, 0x49, 0xd2, 0xb7, 0x85, 0xfa, 0xb3, 0x69, 0xb8, 0xa2, 0x3e, 0x60, 0xbb, 0x16,
+	0xb6, 0xfd, 0xc6, 0x7f, 0xc3, 0x6e, 0x33, 0xbe, 0x5f, 0x78, 0x4e, 0xe5, 0x46, 0xf2, 0x8e, 0xa1,
+	0xdf, 0x0c, 0x50, 0xa4, 0x76, 0x28, 0xe0, 0xa0, 0xcb, 0xb4, 0x1a, 0xc9, 0x4c, 0xbc, 0xa3, 0xd4,
+	0x8d, 0x24, 0x60, 0x5d, 0x63, 0x5f, 0x9a, 0x37, 0x60, 0x45, 0xfc, 0xf6, 0x30, 0x18, 0x85, 0x3e,
+	0xcd, 0x12, 0x76, 0xfc, 0x87, 0x0a, 0x3f, 0xaf, 0x5e, 0x92, 0x8a, 0x34, 0x7b, 0x79, 0x77, 0x1d,
+	0xc9, 0x35, 0x95, 0xcc, 0x7a, 0xf1, 0xc5, 0x9a, 0x20, 0x6a, 0x3d, 0x1d, 0x3e, 0x10, 0x84, 0xe9,
+	0x68, 0x4d, 0x15, 0x2f, 0x00, 0x56, 0xcf, 0x1f, 0x8e, 0xa0, 0x5b, 0xd7, 0x73, 0x9a, 0xba, 0xff,
+	0x3b, 0xe7, 0x8e, 0xff, 0x6d, 0x01, 0xac, 0xde, 0x47, 0x8c, 0xa0, 0x4b, 0xb2, 0xea, 0xa0, 0x7e,
+	0xf1, 0x7a, 0xcc, 0x99, 0xd5, 0xf8, 0xf4, 0xdd, 0x9c, 0xec, 0x8c, 0xcc, 0x77, 0x85, 0x95, 0x65,
+	0x23, 0x16, 0xf3, 0x8d, 0xcf, 0x00, 0x48, 0x26, 0xea, 0xce, 0xbf, 0xfa, 0x1e, 0x52, 0xfd, 0x21,
+	0xb9, 0xe1, 0x52, 0x52, 0x66, 0x84, 0x12, 0x30, 0x67, 0xe2, 0x11, 0x80, 0x98, 0x37, 0xb4, 0xd6,
+	0x0a, 0x08, 0xc5, 0xdc, 0x32, 0x7a, 0xf2, 0x5f, 0x00, 0x1d, 0x66, 0x9a, 0xc0, 0x73, 0xd8, 0x5d,
+	0xdf, 0x32, 0x78, 0xe4, 0xfa, 0xdb, 0xb8, 0x7d, 0x06, 0x9b, 0xde, 0x3d, 0x7c, 0x09, 0x7d, 0x6f,
+	0x25, 0xe1, 0xc0, 0x2b, 0x71, 0x6f, 0x4f, 0x0d, 0xf0, 0x01, 0x03, 0x25, 0xfe, 0x04, 0x7b, 0xf7,
+	0xf6, 0x14, 0x3e, 0xf3, 0x4a, 0x6c, 0x58, 0x60, 0x1b, 0xcb, 0x4c, 0x4c, 0x99, 0x35, 0xad, 0xf8,
+	0x65, 0x36, 0x6c, 0xbb, 0xc1, 0x81, 0xf3, 0xaf, 0x65, 0x9d, 0xc2, 0xe3, 0xb5, 0x1d, 0x88, 0x4f,
+	0xbd, 0x32, 0x0f, 0x56, 0xe3, 0xea, 0x2c, 0x5e, 0x46, 0x5d, 0xc2, 0x93, 0x83, 0x5f, 0xe2, 0xc1,
+	0x16, 0x5d, 0x95, 0xf0, 0x32, 0x7e, 0x84, 0xc7, 0x6b, 0x9b, 0x75, 0x55, 0x62, 0xd3, 0xc2, 0x1d,
+	0xec, 0x39, 0xaf, 0x75, 0x7c, 0xd3, 0x3a, 0x6b, 0xff, 0x11, 0xa8, 0x79, 0x72, 0xd5, 0x31, 0xef,
+	0xc6, 0x77, 0xff, 0x0f, 0x00, 0xb1, 0xef, 0xc2, 0x79, 0x89, 0x08, 0x00, 0x00,
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func startServer(ctx context.Context, t *testing.T, payload local.TestServerPayload) (ReagleClient, *client.Poller, func()) {
+	eagle, config := local.StartTestServer(payload)
+
+	c := client.NewDangerous(ctx, local.New(config), time.Millisecond)
+	poller := client.NewPoller(c, 0)
+
+	rc, stop, err := StartTestServer(NewServer(ctx, c, poller))
+	require.NoError(t, err)
+
+	return rc, poller, func() {
+		stop()
+		eagle.Close()
+	}
+}
+
+func TestGetDeviceList(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	contact := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	rc, _, stop := startServer(ctx, t, local.ServeDeviceList([]local.Device{
+		{DeviceData: local.DeviceData{HardwareAddress: "0x0001", ModelID: "meter", LastContact: local.Timestamp{Time: contact}, ConnectionStatus: local.Connected}},
+		{DeviceData: local.DeviceData{HardwareAddress: "0x0002", ModelID: "thermostat"}},
+	}))
+	defer stop()
+
+	list, err := rc.GetDeviceList(ctx, &GetDeviceListRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Devices, 2)
+
+	assert.Equal(t, "0x0001", list.Devices[0].HardwareAddress)
+	assert.Equal(t, "meter", list.Devices[0].ModelId)
+	assert.Equal(t, string(local.Connected), list.Devices[0].ConnectionStatus)
+	assert.Equal(t, contact.Unix(), list.Devices[0].LastContact.Seconds)
+
+	assert.Equal(t, "thermostat", list.Devices[1].ModelId)
+	assert.Nil(t, list.Devices[1].LastContact, "never is not sent as the zero time")
+}
+
+func TestGetWifiStatus(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	rc, _, stop := startServer(ctx, t, local.ServeWifiStatus(local.WifiStatus{Enabled: true, SSID: "ssid", Channel: 6}))
+	defer stop()
+
+	wifi, err := rc.GetWifiStatus(ctx, &GetWifiStatusRequest{})
+	require.NoError(t, err)
+	assert.True(t, wifi.Enabled)
+	assert.Equal(t, "ssid", wifi.Ssid)
+	assert.Equal(t, int64(6), wifi.Channel)
+}
+
+func TestGetVariableRequiresName(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	rc, _, stop := startServer(ctx, t, local.ServeWifiStatus(local.WifiStatus{}))
+	defer stop()
+
+	_, err := rc.GetVariable(ctx, &GetVariableRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestEagleErrorsAreUnavailable(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	eagle, config := local.StartTestServer(local.ServeWifiStatus(local.WifiStatus{}))
+	eagle.Close()
+
+	c := client.NewDangerous(ctx, local.New(config), time.Millisecond)
+	rc, stop, err := StartTestServer(NewServer(ctx, c, client.NewPoller(c, 0)))
+	require.NoError(t, err)
+	defer stop()
+
+	_, err = rc.GetWifiStatus(ctx, &GetWifiStatusRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestWatchReadings(t *testing.T) {
+	ctx, clean := context.WithCancel(context.Background())
+	defer clean()
+
+	rc, poller, stop := startServer(ctx, t, local.ServeWifiStatus(local.WifiStatus{}))
+	defer stop()
+
+	first := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	poller.Publish(ctx, client.Sample{Time: first, Metrics: client.BaseMetrics{Demand: 1.5}})
+
+	watch, err := rc.WatchReadings(ctx, &WatchReadingsRequest{})
+	require.NoError(t, err)
+
+	reading, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, first.Unix(), reading.Time.Seconds, "starts with the latest")
+	assert.Equal(t, 1.5, reading.Metrics.Demand)
+
+	poller.Publish(ctx, client.Sample{Time: first.Add(time.Minute), Metrics: client.BaseMetrics{Demand: 2, Delivered: 100}})
+
+	reading, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, first.Add(time.Minute).Unix(), reading.Time.Seconds)
+	assert.Equal(t, 2.0, reading.Metrics.Demand)
+	assert.Equal(t, 100.0, reading.Metrics.Delivered)
+}
+
+func TestWatchReadingsDropsOldest(t *testing.T) {
+	server := &Server{watchers: make(map[chan *Reading]bool)}
+	readings, _ := server.watch()
+
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < watchBuffer+2; i++ {
+		server.publish(context.Background(), client.Sample{Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	require.Len(t, readings, watchBuffer)
+	assert.Equal(t, start.Add(2*time.Second).Unix(), (<-readings).Time.Seconds)
+}
+
+func TestUnsupportedVariableCodes(t *testing.T) {
+	invalid := &local.UnsupportedVariableError{Variables: []string{"zigbee:Divisor"}, Reason: "excluded by filter", Invalid: true}
+	assert.Equal(t, codes.InvalidArgument, codeForError(invalid))
+
+	missing := &local.UnsupportedVariableError{Variables: []string{"zigbee:Price"}, Reason: "not present in device query response"}
+	assert.Equal(t, codes.Unavailable, codeForError(missing), "the meter leaving a variable out is not the caller's problem")
+}
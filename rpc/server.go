@@ -0,0 +1,298 @@
+/*
+Package rpc is reagled's gRPC API.  reagle.pb.go is generated from reagle.proto, see it for how to regenerate.
+
+The Server answers each call with a client Request, so calls are rate limited and linearized with everything else
+asking the eagle.  WatchReadings streams the poller's samples rather than asking the eagle at all.
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/kklipsch/reagle/client"
+	"github.com/kklipsch/reagle/local"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//watchBuffer is how many readings a slow watcher can fall behind before its oldest are dropped
+const watchBuffer = 16
+
+//Server implements ReagleServer
+type Server struct {
+	ctx context.Context
+	c   client.Local
+
+	mu       sync.Mutex
+	latest   *Reading
+	watchers map[chan *Reading]bool
+
+	//Timeout is how long a call waits on the eagle
+	Timeout time.Duration
+}
+
+//NewServer creates a Server, watches end when the context is done
+func NewServer(ctx context.Context, c client.Local, poller *client.Poller) *Server {
+	s := &Server{ctx: ctx, c: c, watchers: make(map[chan *Reading]bool), Timeout: time.Second * 5}
+	poller.Add(s.publish)
+	return s
+}
+
+//GetBaseMetrics implements ReagleServer
+func (s *Server) GetBaseMetrics(ctx context.Context, _ *GetBaseMetricsRequest) (*BaseMetrics, error) {
+	response, err := s.request(ctx, client.RequestBaseMetrics())
+	if err != nil {
+		return nil, err
+	}
+
+	return baseMetrics(response.(client.BaseMetrics)), nil
+}
+
+//GetVariable implements ReagleServer
+func (s *Server) GetVariable(ctx context.Context, req *GetVariableRequest) (*Components, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a variable name is required")
+	}
+
+	response, err := s.request(ctx, client.RequestSpecificVariable(req.GetName()))
+	if err != nil {
+		return nil, err
+	}
+
+	return components(response.(map[string]local.Readings)), nil
+}
+
+//GetAllVariables implements ReagleServer
+func (s *Server) GetAllVariables(ctx context.Context, _ *GetAllVariablesRequest) (*Components, error) {
+	response, err := s.request(ctx, client.RequestAllVariables())
+	if err != nil {
+		return nil, err
+	}
+
+	return components(response.(map[string]local.Readings)), nil
+}
+
+//GetMeterDetails implements ReagleServer
+func (s *Server) GetMeterDetails(ctx context.Context, _ *GetMeterDetailsRequest) (*MeterDetails, error) {
+	response, err := s.request(ctx, client.RequestMeterDetails())
+	if err != nil {
+		return nil, err
+	}
+
+	details := response.(local.DeviceDetailsResponse)
+	meter := &MeterDetails{Device: device(details.DeviceDetails.DeviceData)}
+	for _, c := range details.Components.Component {
+		meter.Components = append(meter.Components, &ComponentNames{
+			Name:       c.Name,
+			HardwareId: c.HardwareID,
+			FixedId:    int64(c.FixedID),
+			Variables:  c.Variables.Variable,
+		})
+	}
+
+	return meter, nil
+}
+
+//GetDeviceList implements ReagleServer
+func (s *Server) GetDeviceList(ctx context.Context, _ *GetDeviceListRequest) (*DeviceList, error) {
+	response, err := s.request(ctx, client.RequestDeviceList())
+	if err != nil {
+		return nil, err
+	}
+
+	list := &DeviceList{}
+	for _, d := range response.([]local.Device) {
+		list.Devices = append(list.Devices, device(d.DeviceData))
+	}
+
+	return list, nil
+}
+
+//GetWifiStatus implements ReagleServer
+func (s *Server) GetWifiStatus(ctx context.Context, _ *GetWifiStatusRequest) (*WifiStatus, error) {
+	response, err := s.request(ctx, client.RequestWifiStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	wifi := response.(local.WifiStatus)
+	return &WifiStatus{
+		Enabled:    bool(wifi.Enabled),
+		Type:       wifi.Type,
+		Ssid:       wifi.SSID,
+		Encryption: wifi.Encryption,
+		Channel:    int64(wifi.Channel),
+		IpAddress:  wifi.IPAddress,
+	}, nil
+}
+
+//WatchReadings implements ReagleServer, a watcher that falls behind misses its oldest readings rather than holding up
+//the poller
+func (s *Server) WatchReadings(_ *WatchReadingsRequest, stream Reagle_WatchReadingsServer) error {
+	readings, latest := s.watch()
+	defer s.unwatch(readings)
+
+	if latest != nil {
+		err := stream.Send(latest)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case reading := <-readings:
+			err := stream.Send(reading)
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.ctx.Done():
+			return status.Error(codes.Unavailable, "reagled is shutting down")
+		}
+	}
+}
+
+func (s *Server) publish(_ context.Context, sample client.Sample) {
+	reading := &Reading{Time: timestampProto(sample.Time), Metrics: baseMetrics(sample.Metrics)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latest = reading
+	for watcher := range s.watchers {
+		select {
+		case watcher <- reading:
+			continue
+		default:
+		}
+
+		//only publish sends so after taking the oldest there is room
+		select {
+		case <-watcher:
+		default:
+		}
+
+		watcher <- reading
+	}
+}
+
+func (s *Server) watch() (chan *Reading, *Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readings := make(chan *Reading, watchBuffer)
+	s.watchers[readings] = true
+	return readings, s.latest
+}
+
+func (s *Server) unwatch(readings chan *Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watchers, readings)
+}
+
+func (s *Server) request(ctx context.Context, req client.Request) (interface{}, error) {
+	timeout, clean := context.WithTimeout(ctx, s.Timeout)
+	defer clean()
+
+	response, err := s.c.Request(timeout, req)
+	if err != nil {
+		return nil, status.Error(codeForError(err), err.Error())
+	}
+
+	return response, nil
+}
+
+//codeForError maps the kind of error to a status code the way the http endpoints map them to status codes.  Problems
+//talking to the eagle are the eagle's problem not the caller's
+func codeForError(err error) codes.Code {
+	switch client.ErrorKind(err) {
+	case "rate_limited", "meter_unreachable", "gateway_unreachable":
+		return codes.Unavailable
+	case "context":
+		if errors.Is(err, context.Canceled) {
+			return codes.Canceled
+		}
+		return codes.DeadlineExceeded
+	case "device_not_found":
+		return codes.NotFound
+	case "unsupported_variable":
+		if local.InvalidVariable(err) {
+			return codes.InvalidArgument
+		}
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+func baseMetrics(m client.BaseMetrics) *BaseMetrics {
+	return &BaseMetrics{Demand: m.Demand, Delivered: m.Delivered, Received: m.Received, Price: m.Price, Currency: m.Currency}
+}
+
+//components sorts by name, as maps are not
+func components(readings map[string]local.Readings) *Components {
+	names := make([]string, 0, len(readings))
+	for name := range readings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := &Components{}
+	for _, name := range names {
+		component := &Component{Name: name}
+		for _, r := range readings[name] {
+			component.Variables = append(component.Variables, variable(r))
+		}
+
+		sort.Slice(component.Variables, func(i, j int) bool { return component.Variables[i].Name < component.Variables[j].Name })
+		all.Components = append(all.Components, component)
+	}
+
+	return all
+}
+
+func variable(r local.Reading) *Variable {
+	v := &Variable{Name: r.Name, Raw: r.Raw, Units: r.Units, Description: r.Description}
+	switch value := r.Value.(type) {
+	case float64:
+		v.Value = &Variable_FloatValue{FloatValue: value}
+	case int64:
+		v.Value = &Variable_IntValue{IntValue: value}
+	case string:
+		v.Value = &Variable_TextValue{TextValue: value}
+	case time.Time:
+		v.Value = &Variable_TimeValue{TimeValue: timestampProto(value)}
+	}
+
+	return v
+}
+
+func device(d local.DeviceData) *Device {
+	return &Device{
+		HardwareAddress:  d.HardwareAddress,
+		Manufacturer:     d.Manufacturer,
+		ModelId:          d.ModelID,
+		Protocol:         d.Protocol,
+		LastContact:      timestampProto(d.LastContact.Time),
+		ConnectionStatus: string(d.ConnectionStatus),
+		NetworkAddress:   int64(d.NetworkAddress),
+	}
+}
+
+//timestampProto is nil for the zero time, which the eagle uses for never
+func timestampProto(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+}
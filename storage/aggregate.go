@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+//Resolution is the bucket size of a tier of storage
+type Resolution time.Duration
+
+const (
+	//Raw is every polled reading as it was read
+	Raw Resolution = 0
+	//Minute is readings rolled up into one minute buckets
+	Minute = Resolution(time.Minute)
+	//Hour is readings rolled up into one hour buckets
+	Hour = Resolution(time.Hour)
+)
+
+//Resolutions are the tiers of storage from finest to coarsest
+var Resolutions = []Resolution{Raw, Minute, Hour}
+
+func (r Resolution) String() string {
+	switch r {
+	case Raw:
+		return "raw"
+	case Minute:
+		return "1m"
+	case Hour:
+		return "1h"
+	default:
+		return time.Duration(r).String()
+	}
+}
+
+//ParseResolution is the inverse of Resolution.String
+func ParseResolution(s string) (Resolution, error) {
+	for _, r := range Resolutions {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+
+	return Raw, fmt.Errorf("unknown resolution: %s", s)
+}
+
+//ResolutionFor returns the coarsest resolution that is still at least as fine as step
+func ResolutionFor(step time.Duration) Resolution {
+	switch {
+	case step >= time.Hour:
+		return Hour
+	case step >= time.Minute:
+		return Minute
+	default:
+		return Raw
+	}
+}
+
+func (r Resolution) bucket(t time.Time) time.Time {
+	if r == Raw {
+		return t
+	}
+
+	return t.Truncate(time.Duration(r))
+}
+
+//Point is a single reading from the meter.  Demand is kW, Delivered and Received are the summation counters in kWh
+type Point struct {
+	Time      time.Time `json:"time"`
+	Demand    float64   `json:"demand"`
+	Delivered float64   `json:"delivered"`
+	Received  float64   `json:"received"`
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency,omitempty"`
+
+	//the energy since the previous point, filled in by the Store when the point is appended
+	DeliveredDelta float64 `json:"delivered_delta"`
+	ReceivedDelta  float64 `json:"received_delta"`
+}
+
+//Aggregate summarizes the Points in a bucket.  Delivered/Received are the counters at the end of the bucket while the
+//deltas are the energy used during it, summed point by point so a counter reset mid bucket is handled
+type Aggregate struct {
+	Start     time.Time `json:"start"`
+	Count     int       `json:"count"`
+	DemandMin float64   `json:"demand_min"`
+	DemandMax float64   `json:"demand_max"`
+	DemandSum float64   `json:"demand_sum"`
+
+	Delivered      float64 `json:"delivered"`
+	Received       float64 `json:"received"`
+	DeliveredDelta float64 `json:"delivered_delta"`
+	ReceivedDelta  float64 `json:"received_delta"`
+
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+//DemandAvg is the mean demand of the points in the bucket
+func (a Aggregate) DemandAvg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+
+	return a.DemandSum / float64(a.Count)
+}
+
+func newAggregate(start time.Time) *Aggregate {
+	return &Aggregate{Start: start, DemandMin: math.Inf(1), DemandMax: math.Inf(-1)}
+}
+
+func pointAggregate(p Point) Aggregate {
+	a := newAggregate(p.Time)
+	a.add(p)
+	return *a
+}
+
+func (a *Aggregate) add(p Point) {
+	a.merge(Aggregate{
+		Start:          p.Time,
+		Count:          1,
+		DemandMin:      p.Demand,
+		DemandMax:      p.Demand,
+		DemandSum:      p.Demand,
+		Delivered:      p.Delivered,
+		Received:       p.Received,
+		DeliveredDelta: p.DeliveredDelta,
+		ReceivedDelta:  p.ReceivedDelta,
+		Price:          p.Price,
+		Currency:       p.Currency,
+	})
+}
+
+//merge folds b, which must be later than everything already in a, into a
+func (a *Aggregate) merge(b Aggregate) {
+	if b.Count == 0 {
+		return
+	}
+
+	a.Count += b.Count
+	a.DemandMin = math.Min(a.DemandMin, b.DemandMin)
+	a.DemandMax = math.Max(a.DemandMax, b.DemandMax)
+	a.DemandSum += b.DemandSum
+
+	a.Delivered = b.Delivered
+	a.Received = b.Received
+	a.DeliveredDelta += b.DeliveredDelta
+	a.ReceivedDelta += b.ReceivedDelta
+
+	a.Price = b.Price
+	a.Currency = b.Currency
+}
+
+//CounterDelta is the increase of a summation counter.  A decrease means the counter was reset (meter replaced or
+//rolled over) and it is assumed to have started again from 0
+func CounterDelta(previous float64, current float64) float64 {
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
+
+//Bucketer returns the start of the bucket t belongs in
+type Bucketer func(t time.Time) time.Time
+
+//Step buckets into fixed size steps aligned to the unix epoch
+func Step(step time.Duration) Bucketer {
+	return func(t time.Time) time.Time {
+		return t.Truncate(step)
+	}
+}
+
+//Period is a calendar period used to bucket energy usage
+type Period string
+
+const (
+	//Day runs from midnight to midnight
+	Day Period = "day"
+	//Week runs from midnight on Monday
+	Week Period = "week"
+	//Month runs from midnight on the first of the month
+	Month Period = "month"
+)
+
+//ParsePeriod returns the Period for s
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case Day, Week, Month:
+		return p, nil
+	default:
+		return Day, fmt.Errorf("unknown period: %s", s)
+	}
+}
+
+//Start returns the start of the period t is in, in the provided location
+func (p Period) Start(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	switch p {
+	case Week:
+		//time.Weekday starts on Sunday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case Month:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+//Next returns the start of the period after the one starting at start
+func (p Period) Next(start time.Time) time.Time {
+	switch p {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+//Bucketer buckets by the calendar period in the provided location
+func (p Period) Bucketer(loc *time.Location) Bucketer {
+	return func(t time.Time) time.Time {
+		return p.Start(t, loc)
+	}
+}
+
+//Rebucket merges the (oldest first) aggregates into coarser buckets
+func Rebucket(aggregates []Aggregate, bucket Bucketer) []Aggregate {
+	var results []Aggregate
+	var current *Aggregate
+	for _, a := range aggregates {
+		start := bucket(a.Start)
+		if current == nil || !current.Start.Equal(start) {
+			if current != nil {
+				results = append(results, *current)
+			}
+
+			current = newAggregate(start)
+		}
+
+		current.merge(a)
+	}
+
+	if current != nil {
+		results = append(results, *current)
+	}
+
+	return results
+}
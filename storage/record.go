@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+//records are framed as a 4 byte big endian payload length, a 4 byte crc32 (IEEE) of the payload and then the payload.
+//a crash mid write leaves a short or corrupt record at the end of the file which is detected and truncated on open
+const headerSize = 8
+
+//maxRecordSize protects against a corrupt length header asking for an enormous allocation
+const maxRecordSize = 1 << 16
+
+func writeRecord(w io.Writer, payload []byte) error {
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+//readRecords calls fn for every intact record and returns the offset just past the last intact one.  It stops without
+//error at the first torn or corrupt record since anything after it can not be trusted
+func readRecords(r io.Reader, fn func([]byte) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+
+	var valid int64
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err != nil {
+			return valid, nil
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return valid, nil
+		}
+
+		payload := make([]byte, size)
+		_, err = io.ReadFull(reader, payload)
+		if err != nil {
+			return valid, nil
+		}
+
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return valid, nil
+		}
+
+		if err := fn(payload); err != nil {
+			return valid, err
+		}
+
+		valid += int64(headerSize) + int64(size)
+	}
+}
+
+//repairFile truncates anything after the last intact record
+func repairFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	valid, err := readRecords(f, func([]byte) error { return nil })
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == valid {
+		return nil
+	}
+
+	return f.Truncate(valid)
+}
@@ -0,0 +1,319 @@
+/*
+Package storage keeps a history of meter readings on local disk so that reagled is useful without a Prometheus server.
+
+Format
+
+Each Resolution is a directory of append only segment files.  Every record is length and checksum framed so a crash
+mid write is detected and truncated the next time the store is opened.
+
+Downsampling
+
+Raw points are rolled up into one minute buckets and the minute buckets into one hour buckets as they are appended.  A
+bucket is written when the first point of the next bucket arrives, so the buckets in progress are only in memory.  On
+open they are rebuilt from the raw points, which is why raw retention has to be at least an hour.
+
+Retention
+
+Each Resolution has its own retention.  Whole segments are removed once they are entirely older than it.
+*/
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//ErrOutOfOrder is returned when appending a Point that is not newer than the last one appended
+var ErrOutOfOrder = errors.New("point is not newer than the last point stored")
+
+//Retention is how long each Resolution is kept, 0 keeps it forever
+type Retention struct {
+	Raw    time.Duration `json:"raw"`
+	Minute time.Duration `json:"minute"`
+	Hour   time.Duration `json:"hour"`
+}
+
+//Options configure a Store
+type Options struct {
+	Retention Retention `json:"retention"`
+
+	//NoSync skips the fsync after every write, faster but a crash can lose recently appended points
+	NoSync bool `json:"no_sync"`
+}
+
+//DefaultOptions keeps two days of raw points, 30 days of minutes and 5 years of hours
+func DefaultOptions() Options {
+	return Options{
+		Retention: Retention{
+			Raw:    time.Hour * 48,
+			Minute: time.Hour * 24 * 30,
+			Hour:   time.Hour * 24 * 365 * 5,
+		},
+	}
+}
+
+//Store is an embedded time series of Points, it is safe for concurrent use
+type Store struct {
+	mu    sync.Mutex
+	tiers map[Resolution]*tier
+
+	last   *Point
+	minute *Aggregate
+	hour   *Aggregate
+}
+
+//Open opens (creating if needed) the Store in dir
+func Open(dir string, options Options) (*Store, error) {
+	retention := options.Retention
+	if retention.Raw != 0 && retention.Raw < time.Hour {
+		return nil, fmt.Errorf("raw retention must be at least an hour to rebuild rollups: %v", retention.Raw)
+	}
+
+	s := &Store{tiers: make(map[Resolution]*tier)}
+	for _, r := range Resolutions {
+		keep := map[Resolution]time.Duration{Raw: retention.Raw, Minute: retention.Minute, Hour: retention.Hour}[r]
+
+		t, err := openTier(dir, r, keep, !options.NoSync)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		s.tiers[r] = t
+	}
+
+	if err := s.rebuild(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	for _, t := range s.tiers {
+		if err := t.expire(time.Now()); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+//rebuild replays the raw points of the last hour to recover the buckets that were in progress
+func (s *Store) rebuild() error {
+	raw := s.tiers[Raw]
+	segments, err := raw.segments()
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+
+	var points []Point
+	err = raw.readSegment(segments[len(segments)-1], func(payload []byte) error {
+		p := Point{}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+
+		points = append(points, p)
+		return nil
+	})
+	if err != nil || len(points) == 0 {
+		return err
+	}
+
+	last := points[len(points)-1]
+
+	//an hour can straddle midnight so look at the previous day's segment as well
+	start := Hour.bucket(last.Time)
+	if start.Before(points[0].Time) && len(segments) > 1 {
+		var earlier []Point
+		err = raw.readSegment(segments[len(segments)-2], func(payload []byte) error {
+			p := Point{}
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+
+			earlier = append(earlier, p)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		points = append(earlier, points...)
+	}
+
+	for _, p := range points {
+		if p.Time.Before(start) {
+			continue
+		}
+
+		//everything before the last point's minute was written before the crash, so roll without writing
+		s.roll(p.Time, false)
+		s.accumulate(p)
+	}
+
+	s.last = &last
+	return nil
+}
+
+//Append stores the Point, filling in its deltas from the previous one
+func (s *Store) Append(p Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last != nil {
+		if !p.Time.After(s.last.Time) {
+			return ErrOutOfOrder
+		}
+
+		p.DeliveredDelta = CounterDelta(s.last.Delivered, p.Delivered)
+		p.ReceivedDelta = CounterDelta(s.last.Received, p.Received)
+	}
+
+	//finished buckets are written before the raw point so a crash never leaves a raw point whose earlier minute is missing
+	if err := s.roll(p.Time, true); err != nil {
+		return err
+	}
+
+	if err := s.tiers[Raw].append(p.Time, p); err != nil {
+		return err
+	}
+
+	s.accumulate(p)
+	s.last = &p
+	return nil
+}
+
+//roll closes out the buckets in progress if t is in a later bucket
+func (s *Store) roll(t time.Time, write bool) error {
+	if s.minute != nil && !Minute.bucket(t).Equal(s.minute.Start) {
+		if write {
+			if err := s.tiers[Minute].append(s.minute.Start, s.minute); err != nil {
+				return err
+			}
+		}
+
+		if s.hour == nil {
+			s.hour = newAggregate(Hour.bucket(s.minute.Start))
+		}
+
+		s.hour.merge(*s.minute)
+		s.minute = nil
+	}
+
+	if s.hour != nil && !Hour.bucket(t).Equal(s.hour.Start) {
+		if write {
+			if err := s.tiers[Hour].append(s.hour.Start, s.hour); err != nil {
+				return err
+			}
+		}
+
+		s.hour = nil
+	}
+
+	return nil
+}
+
+func (s *Store) accumulate(p Point) {
+	if s.minute == nil {
+		s.minute = newAggregate(Minute.bucket(p.Time))
+	}
+
+	if s.hour == nil {
+		s.hour = newAggregate(Hour.bucket(p.Time))
+	}
+
+	s.minute.add(p)
+}
+
+//Last returns the most recently appended Point
+func (s *Store) Last() (Point, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		return Point{}, false
+	}
+
+	return *s.last, true
+}
+
+//Query returns the Aggregates at the Resolution that start in [start, end), oldest first.  At Raw every Point is its own
+//Aggregate.  The buckets still in progress are included so the most recent data is always available.
+func (s *Store) Query(r Resolution, start time.Time, end time.Time) ([]Aggregate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tiers[r]
+	if !ok {
+		return nil, fmt.Errorf("unknown resolution: %v", r)
+	}
+
+	var results []Aggregate
+	inRange := func(a Aggregate) bool {
+		return !a.Start.Before(start) && a.Start.Before(end)
+	}
+
+	err := t.read(start, end, func(payload []byte) error {
+		var a Aggregate
+		if r == Raw {
+			p := Point{}
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+
+			a = pointAggregate(p)
+		} else if err := json.Unmarshal(payload, &a); err != nil {
+			return err
+		}
+
+		if inRange(a) {
+			results = append(results, a)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if progress := s.inProgress(r); progress != nil && inRange(*progress) {
+		results = append(results, *progress)
+	}
+
+	return results, nil
+}
+
+func (s *Store) inProgress(r Resolution) *Aggregate {
+	switch {
+	case r == Minute && s.minute != nil:
+		minute := *s.minute
+		return &minute
+	case r == Hour && s.hour != nil:
+		hour := *s.hour
+		if s.minute != nil {
+			hour.merge(*s.minute)
+		}
+
+		return &hour
+	default:
+		return nil
+	}
+}
+
+//Close closes the underlying files, buckets in progress are rebuilt from the raw points on the next Open
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result error
+	for _, t := range s.tiers {
+		if err := t.close(); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempStore(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "reagle-storage")
+	require.NoError(t, err)
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+var epoch = time.Date(2018, 10, 20, 23, 58, 0, 0, time.UTC)
+
+func appendPoints(t *testing.T, s *Store, points ...Point) {
+	for _, p := range points {
+		require.NoError(t, s.Append(p))
+	}
+}
+
+func TestRollups(t *testing.T) {
+	dir, clean := tempStore(t)
+	defer clean()
+
+	s, err := Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	appendPoints(t, s,
+		Point{Time: epoch, Demand: 1, Delivered: 100},
+		Point{Time: epoch.Add(time.Second * 30), Demand: 3, Delivered: 101},
+		Point{Time: epoch.Add(time.Minute), Demand: 2, Delivered: 102},
+		//meter replaced, counter starts again
+		Point{Time: epoch.Add(time.Minute * 3), Demand: 4, Delivered: 1},
+	)
+
+	assert.Equal(t, ErrOutOfOrder, s.Append(Point{Time: epoch}))
+
+	minutes, err := s.Query(Minute, epoch, epoch.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, minutes, 3)
+
+	assert.Equal(t, 2, minutes[0].Count)
+	assert.Equal(t, 1.0, minutes[0].DemandMin)
+	assert.Equal(t, 3.0, minutes[0].DemandMax)
+	assert.Equal(t, 2.0, minutes[0].DemandAvg())
+	assert.Equal(t, 1.0, minutes[0].DeliveredDelta)
+	assert.Equal(t, 1.0, minutes[1].DeliveredDelta)
+	assert.Equal(t, 1.0, minutes[2].DeliveredDelta, "counter reset counts from 0")
+
+	hours, err := s.Query(Hour, epoch.Add(-time.Hour), epoch.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hours, 2)
+	assert.Equal(t, 3, hours[0].Count)
+	assert.Equal(t, 2.0, hours[0].DeliveredDelta)
+	assert.Equal(t, 1, hours[1].Count, "in progress hour is included")
+
+	raw, err := s.Query(Raw, epoch, epoch.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, raw, 2)
+}
+
+func TestReopen(t *testing.T) {
+	dir, clean := tempStore(t)
+	defer clean()
+
+	s, err := Open(dir, DefaultOptions())
+	require.NoError(t, err)
+
+	appendPoints(t, s,
+		Point{Time: epoch.Add(time.Minute * 2), Demand: 1, Delivered: 100},
+		Point{Time: epoch.Add(time.Minute*2 + time.Second), Demand: 2, Delivered: 101},
+	)
+	require.NoError(t, s.Close())
+
+	//simulate a crash mid write
+	segment := filepath.Join(dir, Raw.String(), "20181021.log")
+	f, err := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 40, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	last, ok := s.Last()
+	require.True(t, ok)
+	assert.Equal(t, 101.0, last.Delivered)
+
+	appendPoints(t, s, Point{Time: epoch.Add(time.Minute * 3), Demand: 3, Delivered: 103})
+
+	minutes, err := s.Query(Minute, epoch, epoch.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, minutes, 2)
+	assert.Equal(t, 2, minutes[0].Count, "in progress minute was rebuilt")
+	assert.Equal(t, 2.0, minutes[1].DeliveredDelta)
+}
+
+func TestRetention(t *testing.T) {
+	dir, clean := tempStore(t)
+	defer clean()
+
+	options := DefaultOptions()
+	options.Retention.Raw = time.Hour * 24
+
+	s, err := Open(dir, options)
+	require.NoError(t, err)
+	defer s.Close()
+
+	appendPoints(t, s,
+		Point{Time: epoch.AddDate(0, 0, -3)},
+		Point{Time: epoch.AddDate(0, 0, -2)},
+		Point{Time: epoch},
+	)
+
+	raw, err := s.Query(Raw, epoch.AddDate(0, 0, -4), epoch.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, raw, 1)
+
+	hours, err := s.Query(Hour, epoch.AddDate(0, 0, -4), epoch.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, hours, 3)
+}
+
+func TestOpenRejectsShortRawRetention(t *testing.T) {
+	dir, clean := tempStore(t)
+	defer clean()
+
+	options := DefaultOptions()
+	options.Retention.Raw = time.Minute
+
+	_, err := Open(dir, options)
+	assert.Error(t, err)
+}
+
+func TestRebucket(t *testing.T) {
+	loc := time.FixedZone("test", -5*60*60)
+	monday := time.Date(2018, 10, 15, 0, 0, 0, 0, loc)
+
+	aggregates := []Aggregate{
+		pointAggregate(Point{Time: monday.Add(time.Hour), Demand: 1, DeliveredDelta: 1}),
+		pointAggregate(Point{Time: monday.AddDate(0, 0, 6).Add(time.Hour * 23), Demand: 3, DeliveredDelta: 2}),
+		pointAggregate(Point{Time: monday.AddDate(0, 0, 7), Demand: 5, DeliveredDelta: 4}),
+	}
+
+	weeks := Rebucket(aggregates, Week.Bucketer(loc))
+	require.Len(t, weeks, 2)
+	assert.True(t, monday.Equal(weeks[0].Start))
+	assert.Equal(t, 3.0, weeks[0].DeliveredDelta)
+	assert.Equal(t, 2.0, weeks[0].DemandAvg())
+	assert.Equal(t, 4.0, weeks[1].DeliveredDelta)
+
+	months := Rebucket(aggregates, Month.Bucketer(loc))
+	require.Len(t, months, 1)
+	assert.Equal(t, 7.0, months[0].DeliveredDelta)
+	assert.Equal(t, 1.0, months[0].DemandMin)
+	assert.Equal(t, 5.0, months[0].DemandMax)
+
+	steps := Rebucket(aggregates, Step(time.Hour))
+	assert.Len(t, steps, 3)
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const segmentExtension = ".log"
+
+//a tier is one resolution of storage, kept as a directory of append only segment files each covering a fixed period
+type tier struct {
+	resolution Resolution
+	dir        string
+	retention  time.Duration
+	sync       bool
+
+	segment string
+	file    *os.File
+}
+
+func openTier(root string, resolution Resolution, retention time.Duration, sync bool) (*tier, error) {
+	t := &tier{
+		resolution: resolution,
+		dir:        filepath.Join(root, resolution.String()),
+		retention:  retention,
+		sync:       sync,
+	}
+
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	segments, err := t.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	//only the newest segment can have been mid write during a crash
+	if len(segments) > 0 {
+		if err := repairFile(filepath.Join(t.dir, segments[len(segments)-1])); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+//raw and minute data is kept in daily segments, hourly data in monthly ones
+func (t *tier) layout() string {
+	if t.resolution == Hour {
+		return "200601"
+	}
+
+	return "20060102"
+}
+
+func (t *tier) segmentName(ts time.Time) string {
+	return ts.UTC().Format(t.layout()) + segmentExtension
+}
+
+func (t *tier) segmentPeriod(name string) (time.Time, time.Time, error) {
+	start, err := time.Parse(t.layout(), strings.TrimSuffix(name, segmentExtension))
+	if err != nil {
+		return start, start, err
+	}
+
+	if t.resolution == Hour {
+		return start, start.AddDate(0, 1, 0), nil
+	}
+
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+//segments returns the segment file names oldest first
+func (t *tier) segments() ([]string, error) {
+	infos, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), segmentExtension) {
+			continue
+		}
+
+		if _, _, err := t.segmentPeriod(info.Name()); err != nil {
+			continue
+		}
+
+		names = append(names, info.Name())
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (t *tier) append(ts time.Time, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	name := t.segmentName(ts)
+	if name != t.segment {
+		if err := t.rotate(name, ts); err != nil {
+			return err
+		}
+	}
+
+	if err := writeRecord(t.file, payload); err != nil {
+		return err
+	}
+
+	if t.sync {
+		return t.file.Sync()
+	}
+
+	return nil
+}
+
+func (t *tier) rotate(name string, now time.Time) error {
+	if err := t.close(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(t.dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	t.segment = name
+	t.file = f
+
+	return t.expire(now)
+}
+
+//expire removes segments that ended before the retention period
+func (t *tier) expire(now time.Time) error {
+	if t.retention <= 0 {
+		return nil
+	}
+
+	segments, err := t.segments()
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-t.retention)
+	for _, name := range segments {
+		_, end, err := t.segmentPeriod(name)
+		if err != nil || !end.Before(cutoff) || name == t.segment {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(t.dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//read calls fn with the payload of every record in segments that overlap [start, end)
+func (t *tier) read(start time.Time, end time.Time, fn func([]byte) error) error {
+	segments, err := t.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range segments {
+		segmentStart, segmentEnd, err := t.segmentPeriod(name)
+		if err != nil || !segmentEnd.After(start) || !segmentStart.Before(end) {
+			continue
+		}
+
+		if err := t.readSegment(name, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *tier) readSegment(name string, fn func([]byte) error) error {
+	f, err := os.Open(filepath.Join(t.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = readRecords(f, fn)
+	return err
+}
+
+func (t *tier) close() error {
+	if t.file == nil {
+		return nil
+	}
+
+	err := t.file.Close()
+	t.file = nil
+	t.segment = ""
+	return err
+}
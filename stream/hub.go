@@ -0,0 +1,246 @@
+/*
+Package stream pushes events to subscribers as Server-Sent Events.
+
+Resuming
+
+Every event has an id and the Hub keeps the most recent, so a client that reconnects with a Last-Event-ID header is sent
+what it missed before anything new.  Ids start from the time the Hub was created so they keep increasing across
+restarts, a client resuming from before a restart gets everything the new Hub has.
+
+Backpressure
+
+Each subscriber has a bounded buffer.  When a slow subscriber's buffer is full the Policy either drops its oldest
+buffered event, so it stays connected and sees a gap in the ids, or disconnects it so it can reconnect and resume.
+*/
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//Policy is what happens to a subscriber that is not keeping up
+type Policy string
+
+//Policies
+const (
+	DropOldest Policy = "drop_oldest"
+	Disconnect Policy = "disconnect"
+)
+
+var (
+	//ErrTooManySubscribers is returned by Subscribe when the Hub is at MaxSubscribers
+	ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+	//ErrClosed is returned by Subscribe after the Hub is closed
+	ErrClosed = errors.New("stream closed")
+)
+
+type (
+	//Config is the limits of a Hub
+	Config struct {
+		MaxSubscribers int           `json:"max_subscribers"`
+		History        int           `json:"history"`
+		Buffer         int           `json:"buffer"`
+		Policy         Policy        `json:"policy"`
+		Heartbeat      time.Duration `json:"heartbeat"`
+	}
+
+	//Event is a message to subscribers, Data is json
+	Event struct {
+		ID   uint64
+		Type string
+		Data []byte
+	}
+
+	//Hub publishes events to every subscriber
+	Hub struct {
+		config Config
+
+		mu          sync.Mutex
+		next        uint64
+		history     []Event
+		subscribers map[*Subscription]bool
+		closed      bool
+
+		//Dropped is called each time a subscriber falls behind, with the policy applied to it
+		Dropped func(policy Policy)
+	}
+
+	//Subscription is one subscriber's events, Events is closed when it is over
+	Subscription struct {
+		hub    *Hub
+		events chan Event
+		closed bool
+	}
+)
+
+//ValidateConfig returns an error if the Hub can not be created with the config
+func ValidateConfig(config Config) error {
+	switch {
+	case config.MaxSubscribers <= 0:
+		return fmt.Errorf("max subscribers must be positive, not %v", config.MaxSubscribers)
+	case config.Buffer <= 0:
+		return fmt.Errorf("buffer must be positive, not %v", config.Buffer)
+	case config.History < 0:
+		return fmt.Errorf("history can not be negative, not %v", config.History)
+	case config.Heartbeat <= 0:
+		return fmt.Errorf("heartbeat must be positive, not %v", config.Heartbeat)
+	case config.Policy != DropOldest && config.Policy != Disconnect:
+		return fmt.Errorf("policy must be %s or %s, not %q", DropOldest, Disconnect, config.Policy)
+	}
+
+	return nil
+}
+
+//NewHub creates a Hub
+func NewHub(config Config) (*Hub, error) {
+	err := ValidateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hub{
+		config:      config,
+		next:        uint64(time.Now().UnixNano()),
+		subscribers: make(map[*Subscription]bool),
+	}, nil
+}
+
+//Publish sends the data as json to every subscriber, it never blocks on a subscriber
+func (h *Hub) Publish(eventType string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrClosed
+	}
+
+	h.next++
+	event := Event{ID: h.next, Type: eventType, Data: b}
+
+	if h.config.History > 0 {
+		h.history = append(h.history, event)
+		if len(h.history) > h.config.History {
+			h.history = h.history[len(h.history)-h.config.History:]
+		}
+	}
+
+	for sub := range h.subscribers {
+		h.send(sub, event)
+	}
+
+	return nil
+}
+
+//send must be called with the lock held
+func (h *Hub) send(sub *Subscription, event Event) {
+	select {
+	case sub.events <- event:
+		return
+	default:
+	}
+
+	h.dropped()
+	if h.config.Policy == Disconnect {
+		h.remove(sub)
+		return
+	}
+
+	//if the subscriber took an event since the send above there is already room, either way only the lock holder sends
+	select {
+	case <-sub.events:
+	default:
+	}
+
+	sub.events <- event
+}
+
+func (h *Hub) dropped() {
+	if h.Dropped != nil {
+		h.Dropped(h.config.Policy)
+	}
+}
+
+//Subscribe starts a subscription, with the events after lastID that the Hub still has.  lastID of 0 is a new subscriber
+func (h *Hub) Subscribe(lastID uint64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	if len(h.subscribers) >= h.config.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	var missed []Event
+	if lastID > 0 {
+		for _, event := range h.history {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	//room for what was missed and the usual buffer on top of it
+	sub := &Subscription{hub: h, events: make(chan Event, len(missed)+h.config.Buffer)}
+	for _, event := range missed {
+		sub.events <- event
+	}
+
+	h.subscribers[sub] = true
+	return sub, nil
+}
+
+//Subscribers is how many subscriptions there are
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+//Close ends every subscription, nothing can be published or subscribed to after
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+//remove must be called with the lock held
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+//Events are the subscription's events, it is closed when the subscriber is disconnected or the Hub closed
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+//Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
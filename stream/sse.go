@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//retryMillis is how long browsers wait before reconnecting
+const retryMillis = 3000
+
+//ServeHTTP streams events to the client until it goes away, it is disconnected or the Hub is closed.  A client that
+//reconnects with a Last-Event-ID header is sent what it missed first.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.Subscribe(lastID)
+	if err != nil {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	//stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.config.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+
+			_, err = w.Write(encode(event))
+		case <-heartbeat.C:
+			//a comment, which clients ignore, so proxies and clients can tell the connection is alive
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func lastEventID(r *http.Request) (uint64, error) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		//EventSource can not set headers, so clients resuming by hand can use the query string
+		raw = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+
+	if raw == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event id %q", raw)
+	}
+
+	return id, nil
+}
+
+//encode writes the event in the text/event-stream format, data can not have bare newlines so each line is its own data
+func encode(event Event) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "id: %d\n", event.ID)
+	if event.Type != "" {
+		fmt.Fprintf(buf, "event: %s\n", event.Type)
+	}
+
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	return Config{MaxSubscribers: 2, History: 3, Buffer: 2, Policy: DropOldest, Heartbeat: time.Hour}
+}
+
+func ids(t *testing.T, sub *Subscription, n int) []uint64 {
+	var all []uint64
+	for i := 0; i < n; i++ {
+		select {
+		case event, ok := <-sub.Events():
+			require.True(t, ok, "closed after %v events", i)
+			all = append(all, event.ID)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for event")
+		}
+	}
+
+	return all
+}
+
+func TestHubResume(t *testing.T) {
+	hub, err := NewHub(testConfig())
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, hub.Publish("reading", i))
+	}
+
+	first := hub.history[0].ID
+	assert.Len(t, hub.history, 3, "only the most recent are kept")
+
+	sub, err := hub.Subscribe(first)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{first + 1, first + 2}, ids(t, sub, 2))
+
+	require.NoError(t, hub.Publish("reading", 5))
+	assert.Equal(t, []uint64{first + 3}, ids(t, sub, 1), "new events follow the missed ones")
+
+	fresh, err := hub.Subscribe(0)
+	require.NoError(t, err)
+	assert.Len(t, fresh.Events(), 0, "a new subscriber does not get history")
+
+	_, err = hub.Subscribe(0)
+	assert.Equal(t, ErrTooManySubscribers, err)
+
+	fresh.Close()
+	assert.Equal(t, 1, hub.Subscribers())
+
+	hub.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.Equal(t, ErrClosed, hub.Publish("reading", 6))
+}
+
+func TestHubBackpressure(t *testing.T) {
+	config := testConfig()
+	var dropped []Policy
+
+	hub, err := NewHub(config)
+	require.NoError(t, err)
+	hub.Dropped = func(p Policy) { dropped = append(dropped, p) }
+
+	sub, err := hub.Subscribe(0)
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, hub.Publish("reading", i))
+	}
+
+	last := hub.history[len(hub.history)-1].ID
+	assert.Equal(t, []uint64{last - 1, last}, ids(t, sub, 2), "the oldest are dropped")
+	assert.Equal(t, []Policy{DropOldest, DropOldest}, dropped)
+
+	config.Policy = Disconnect
+	hub, err = NewHub(config)
+	require.NoError(t, err)
+
+	slow, err := hub.Subscribe(0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, hub.Publish("reading", i))
+	}
+
+	assert.Len(t, ids(t, slow, 2), 2, "what was buffered is still delivered")
+	_, ok := <-slow.Events()
+	assert.False(t, ok, "then it is disconnected")
+	assert.Equal(t, 0, hub.Subscribers())
+}
+
+func TestValidateConfig(t *testing.T) {
+	assert.NoError(t, ValidateConfig(testConfig()))
+
+	bad := testConfig()
+	bad.Policy = "block"
+	assert.Error(t, ValidateConfig(bad))
+
+	bad = testConfig()
+	bad.MaxSubscribers = 0
+	assert.Error(t, ValidateConfig(bad))
+}
+
+func TestServeHTTP(t *testing.T) {
+	config := testConfig()
+	config.Heartbeat = time.Millisecond * 20
+
+	hub, err := NewHub(config)
+	require.NoError(t, err)
+	require.NoError(t, hub.Publish("reading", map[string]float64{"demand": 1.5}))
+	published := hub.history[0].ID
+
+	ts := httptest.NewServer(hub)
+	defer ts.Close()
+
+	ctx, clean := context.WithTimeout(context.Background(), time.Second*5)
+	defer clean()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	var read []string
+	for lines.Scan() && !strings.HasPrefix(lines.Text(), ": heartbeat") {
+		read = append(read, lines.Text())
+	}
+
+	assert.Equal(t, []string{
+		"retry: 3000", "",
+		"id: " + strconv.FormatUint(published, 10), "event: reading", `data: {"demand":1.5}`, "",
+	}, read)
+
+	req, err = http.NewRequestWithContext(ctx, "GET", ts.URL+"?last_event_id=nope", nil)
+	require.NoError(t, err)
+	bad, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, bad.StatusCode)
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "id: 7\nevent: device\ndata: {\ndata: }\n\n", string(encode(Event{ID: 7, Type: "device", Data: []byte("{\n}")})))
+}
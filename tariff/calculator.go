@@ -0,0 +1,154 @@
+package tariff
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+)
+
+//Totals are the costs a Calculator has accumulated
+type Totals struct {
+	Currency string `json:"currency"`
+
+	//Cost of all energy seen since the calculator was created
+	EnergyCost float64 `json:"energy_cost"`
+
+	//the billing period the rest of the totals are for
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnergy     float64   `json:"period_energy_kwh"`
+	PeriodEnergyCost float64   `json:"period_energy_cost"`
+	PeriodPeakDemand float64   `json:"period_peak_demand_kw"`
+	PeriodDemandCost float64   `json:"period_demand_cost"`
+
+	//the per kWh rate for the next kWh used
+	CurrentRate float64 `json:"current_rate"`
+}
+
+//PeriodCost is the energy and demand cost of the billing period so far
+func (t Totals) PeriodCost() float64 {
+	return t.PeriodEnergyCost + t.PeriodDemandCost
+}
+
+//Calculator turns a stream of summation readings into a running cost, it is safe for concurrent use
+type Calculator struct {
+	tariff Tariff
+
+	mu        sync.Mutex
+	last      time.Time
+	delivered float64
+	totals    Totals
+}
+
+//NewCalculator creates a Calculator for the Tariff
+func NewCalculator(t Tariff) *Calculator {
+	return &Calculator{tariff: t, totals: Totals{Currency: t.Currency}}
+}
+
+//Tariff returns the tariff the calculator uses
+func (c *Calculator) Tariff() Tariff {
+	return c.tariff
+}
+
+//Add accounts for the energy used since the previous reading.  delivered is the summation counter in kWh and demand is
+//the demand in kW that is used for the demand charge.  Readings older than the last one are ignored
+func (c *Calculator) Add(ts time.Time, delivered float64, demand float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.last.IsZero() && !ts.After(c.last) {
+		return
+	}
+
+	kwh := 0.0
+	if !c.last.IsZero() {
+		kwh = storage.CounterDelta(c.delivered, delivered)
+	}
+
+	c.last = ts
+	c.delivered = delivered
+	c.add(ts, kwh, demand)
+}
+
+//AddEnergy accounts for kwh used at ts, for callers that already have the energy rather than the summation counter.
+//It must be called in time order and not mixed with Add
+func (c *Calculator) AddEnergy(ts time.Time, kwh float64, demand float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(ts, kwh, demand)
+}
+
+func (c *Calculator) add(ts time.Time, kwh float64, demand float64) {
+	start := c.tariff.BillingPeriodStart(ts)
+	if !start.Equal(c.totals.PeriodStart) {
+		c.totals = Totals{Currency: c.tariff.Currency, EnergyCost: c.totals.EnergyCost, PeriodStart: start}
+	}
+
+	c.addEnergy(ts, kwh)
+	c.addDemand(ts, demand)
+	c.totals.CurrentRate, _ = c.tariff.EnergyRate(ts, c.totals.PeriodEnergy)
+}
+
+//Seed sets the billing period's energy totals from stored history so they survive a restart.  aggregates are oldest
+//first and each is charged at the rate in effect at its start, as billing reports do.  EnergyCost is since the
+//calculator was created so it is not changed, and demand is seeded with AddPeakDemand
+func (c *Calculator) Seed(aggregates []storage.Aggregate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	energyCost := c.totals.EnergyCost
+	for _, a := range aggregates {
+		c.add(a.Start, a.DeliveredDelta, 0)
+	}
+
+	c.totals.EnergyCost = energyCost
+}
+
+//AddPeakDemand raises the billing period's peak demand if demand is higher, for callers that calculate the peak
+//over a window rather than passing instantaneous demand to Add
+func (c *Calculator) AddPeakDemand(ts time.Time, demand float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tariff.BillingPeriodStart(ts).Equal(c.totals.PeriodStart) {
+		c.addDemand(ts, demand)
+	}
+}
+
+//addEnergy charges kwh at the rate in effect at ts, splitting it at tier boundaries
+func (c *Calculator) addEnergy(ts time.Time, kwh float64) {
+	for kwh > 0 {
+		rate, remaining := c.tariff.EnergyRate(ts, c.totals.PeriodEnergy)
+
+		used := kwh
+		if remaining > 0 && remaining < kwh {
+			used = remaining
+		}
+
+		cost := used * rate
+		c.totals.EnergyCost += cost
+		c.totals.PeriodEnergyCost += cost
+		c.totals.PeriodEnergy += used
+		kwh -= used
+	}
+}
+
+func (c *Calculator) addDemand(ts time.Time, demand float64) {
+	if demand <= c.totals.PeriodPeakDemand {
+		return
+	}
+
+	c.totals.PeriodPeakDemand = demand
+	if charge, ok := c.tariff.DemandCharge(ts); ok {
+		c.totals.PeriodDemandCost = demand * charge.RatePerKW
+	}
+}
+
+//Totals returns the costs so far
+func (c *Calculator) Totals() Totals {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.totals
+}
@@ -0,0 +1,398 @@
+/*
+Package tariff calculates the cost of energy from a utility's published rates rather than trusting the price the meter
+reports, which many utilities do not send or send incorrectly.
+
+Tariffs
+
+A Tariff is a list of Seasons, the first Season whose months include the time of a reading is used.  Each Season has an
+energy charge, which is flat, tiered by the energy used so far in the billing period, or time of use, and optionally a
+demand charge on the peak demand of the billing period.
+
+Time of use periods can be limited to weekdays, weekends or holidays.  Holidays are treated as weekend days by periods
+that apply to weekends.
+*/
+package tariff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//Flat charges the same rate for every kWh
+	Flat = "flat"
+	//Tiered charges by how much energy has been used in the billing period
+	Tiered = "tiered"
+	//TimeOfUse charges by the time of day and type of day
+	TimeOfUse = "tou"
+)
+
+const (
+	//Weekday periods apply monday to friday, except holidays
+	Weekday = "weekday"
+	//Weekend periods apply saturday, sunday and holidays
+	Weekend = "weekend"
+	//Holiday periods only apply on holidays
+	Holiday = "holiday"
+	//All periods apply every day
+	All = "all"
+)
+
+type (
+	//Tariff is a utility rate schedule
+	Tariff struct {
+		Name     string `json:"name"`
+		Currency string `json:"currency"`
+
+		//IANA time zone the utility uses for time of use periods and billing, defaults to the local zone
+		Timezone string `json:"timezone"`
+
+		//day of the month the billing period starts, 1-28, defaults to 1
+		BillingDay int `json:"billing_day"`
+
+		//YYYY-MM-DD for a specific date, or MM-DD for a holiday on the same date every year
+		Holidays []string `json:"holidays"`
+
+		Seasons []Season `json:"seasons"`
+
+		loc *time.Location
+	}
+
+	//Season is the charges for a set of months
+	Season struct {
+		Name string `json:"name"`
+
+		//1-12, empty means every month
+		Months []int `json:"months"`
+
+		Energy EnergyCharge  `json:"energy"`
+		Demand *DemandCharge `json:"demand,omitempty"`
+	}
+
+	//EnergyCharge is the per kWh charge
+	EnergyCharge struct {
+		Type string `json:"type"`
+
+		//the rate for flat charges, and for time of use when no period matches
+		Rate float64 `json:"rate"`
+
+		Tiers   []Tier   `json:"tiers,omitempty"`
+		Periods []Period `json:"periods,omitempty"`
+	}
+
+	//Tier is the rate until the billing period's energy reaches UpTo, the last tier should have an UpTo of 0 for unbounded
+	Tier struct {
+		UpTo float64 `json:"up_to_kwh"`
+		Rate float64 `json:"rate"`
+	}
+
+	//Period is a time of use rate.  Start and End are HH:MM, an End before Start wraps past midnight
+	Period struct {
+		Name  string  `json:"name"`
+		Days  string  `json:"days"`
+		Start string  `json:"start"`
+		End   string  `json:"end"`
+		Rate  float64 `json:"rate"`
+
+		start, end time.Duration
+	}
+
+	//DemandCharge is charged on the peak demand of the billing period
+	DemandCharge struct {
+		RatePerKW float64 `json:"rate_per_kw"`
+	}
+)
+
+//Load reads and validates a json Tariff from the file
+func Load(path string) (Tariff, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Tariff{}, err
+	}
+
+	return Parse(b)
+}
+
+//Parse unmarshals and validates a json Tariff
+func Parse(b []byte) (Tariff, error) {
+	t := Tariff{}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return t, err
+	}
+
+	return t, t.validate()
+}
+
+func (t *Tariff) validate() error {
+	var err error
+	t.loc, err = time.LoadLocation(t.Timezone)
+	if err != nil {
+		return err
+	}
+
+	if t.Timezone == "" {
+		t.loc = time.Local
+	}
+
+	if t.BillingDay == 0 {
+		t.BillingDay = 1
+	}
+
+	if t.BillingDay < 1 || t.BillingDay > 28 {
+		return fmt.Errorf("billing_day must be between 1 and 28: %v", t.BillingDay)
+	}
+
+	for _, holiday := range t.Holidays {
+		if _, err := parseHoliday(holiday); err != nil {
+			return err
+		}
+	}
+
+	if len(t.Seasons) == 0 {
+		return fmt.Errorf("tariff %s has no seasons", t.Name)
+	}
+
+	for i := range t.Seasons {
+		if err := t.Seasons[i].validate(); err != nil {
+			return fmt.Errorf("season %s: %v", t.Seasons[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Season) validate() error {
+	for _, m := range s.Months {
+		if m < 1 || m > 12 {
+			return fmt.Errorf("invalid month: %v", m)
+		}
+	}
+
+	switch s.Energy.Type {
+	case Flat:
+	case Tiered:
+		if len(s.Energy.Tiers) == 0 {
+			return fmt.Errorf("tiered energy charge has no tiers")
+		}
+
+		for i, tier := range s.Energy.Tiers {
+			last := i == len(s.Energy.Tiers)-1
+			if !last && (tier.UpTo <= 0 || (i > 0 && tier.UpTo <= s.Energy.Tiers[i-1].UpTo)) {
+				return fmt.Errorf("tiers must have increasing up_to_kwh: %v", s.Energy.Tiers)
+			}
+		}
+	case TimeOfUse:
+		for i := range s.Energy.Periods {
+			if err := s.Energy.Periods[i].validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown energy charge type: %s", s.Energy.Type)
+	}
+
+	return nil
+}
+
+func (p *Period) validate() error {
+	switch p.Days {
+	case "":
+		p.Days = All
+	case Weekday, Weekend, Holiday, All:
+	default:
+		return fmt.Errorf("period %s: unknown days %s", p.Name, p.Days)
+	}
+
+	var err error
+	p.start, err = parseClock(p.Start)
+	if err != nil {
+		return fmt.Errorf("period %s: %v", p.Name, err)
+	}
+
+	p.end, err = parseClock(p.End)
+	if err != nil {
+		return fmt.Errorf("period %s: %v", p.Name, err)
+	}
+
+	return nil
+}
+
+//Location is the time zone of the tariff
+func (t Tariff) Location() *time.Location {
+	if t.loc == nil {
+		return time.Local
+	}
+
+	return t.loc
+}
+
+//Season returns the season in effect at ts
+func (t Tariff) Season(ts time.Time) (Season, bool) {
+	month := int(ts.In(t.Location()).Month())
+	for _, s := range t.Seasons {
+		if len(s.Months) == 0 {
+			return s, true
+		}
+
+		for _, m := range s.Months {
+			if m == month {
+				return s, true
+			}
+		}
+	}
+
+	return Season{}, false
+}
+
+//IsHoliday returns true if ts falls on one of the tariff's holidays
+func (t Tariff) IsHoliday(ts time.Time) bool {
+	local := ts.In(t.Location())
+	for _, raw := range t.Holidays {
+		h, err := parseHoliday(raw)
+		if err != nil {
+			continue
+		}
+
+		if h.Day() == local.Day() && h.Month() == local.Month() && (h.Year() == 0 || h.Year() == local.Year()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//BillingPeriodStart returns the start of the billing period that ts is in
+func (t Tariff) BillingPeriodStart(ts time.Time) time.Time {
+	return BillingPeriodStart(ts, t.BillingDay, t.Location())
+}
+
+//BillingPeriodStart returns midnight on the most recent day of the month matching billingDay, in loc
+func BillingPeriodStart(ts time.Time, billingDay int, loc *time.Location) time.Time {
+	local := ts.In(loc)
+	start := time.Date(local.Year(), local.Month(), billingDay, 0, 0, 0, 0, loc)
+	if local.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+
+	return start
+}
+
+//EnergyRate is the per kWh rate at ts given the energy already used in the billing period, and the kWh until the rate
+//changes because of a tier boundary (0 if it won't)
+func (t Tariff) EnergyRate(ts time.Time, periodEnergy float64) (float64, float64) {
+	season, ok := t.Season(ts)
+	if !ok {
+		return 0, 0
+	}
+
+	charge := season.Energy
+	switch charge.Type {
+	case Tiered:
+		for _, tier := range charge.Tiers {
+			if tier.UpTo <= 0 || periodEnergy < tier.UpTo {
+				remaining := 0.0
+				if tier.UpTo > 0 {
+					remaining = tier.UpTo - periodEnergy
+				}
+
+				return tier.Rate, remaining
+			}
+		}
+
+		return charge.Tiers[len(charge.Tiers)-1].Rate, 0
+	case TimeOfUse:
+		if period, ok := t.period(charge, ts); ok {
+			return period.Rate, 0
+		}
+
+		return charge.Rate, 0
+	default:
+		return charge.Rate, 0
+	}
+}
+
+//period returns the first time of use period that contains ts
+func (t Tariff) period(charge EnergyCharge, ts time.Time) (Period, bool) {
+	local := ts.In(t.Location())
+	holiday := t.IsHoliday(local)
+	weekend := local.Weekday() == time.Saturday || local.Weekday() == time.Sunday
+
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	clock := local.Sub(midnight)
+
+	for _, p := range charge.Periods {
+		switch {
+		case p.Days == Weekday && (weekend || holiday):
+			continue
+		case p.Days == Weekend && !(weekend || holiday):
+			continue
+		case p.Days == Holiday && !holiday:
+			continue
+		}
+
+		if p.contains(clock) {
+			return p, true
+		}
+	}
+
+	return Period{}, false
+}
+
+func (p Period) contains(clock time.Duration) bool {
+	if p.end <= p.start {
+		return clock >= p.start || clock < p.end
+	}
+
+	return clock >= p.start && clock < p.end
+}
+
+//DemandCharge returns the demand charge in effect at ts
+func (t Tariff) DemandCharge(ts time.Time) (DemandCharge, bool) {
+	season, ok := t.Season(ts)
+	if !ok || season.Demand == nil {
+		return DemandCharge{}, false
+	}
+
+	return *season.Demand, true
+}
+
+func parseClock(raw string) (time.Duration, error) {
+	parts := strings.Split(raw, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %s, must be HH:MM", raw)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, fmt.Errorf("invalid time %s, must be HH:MM", raw)
+	}
+
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time %s, must be HH:MM", raw)
+	}
+
+	clock := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if clock > time.Hour*24 {
+		return 0, fmt.Errorf("invalid time %s, must be no later than 24:00", raw)
+	}
+
+	return clock, nil
+}
+
+func parseHoliday(raw string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("01-02", raw)
+	if err != nil {
+		return t, fmt.Errorf("invalid holiday %s, must be YYYY-MM-DD or MM-DD", raw)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,141 @@
+package tariff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const example = `{
+	"name": "example",
+	"currency": "USD",
+	"timezone": "America/Chicago",
+	"billing_day": 15,
+	"holidays": ["07-04", "2018-11-22"],
+	"seasons": [
+		{
+			"name": "summer",
+			"months": [6, 7, 8, 9],
+			"energy": {
+				"type": "tou",
+				"rate": 0.10,
+				"periods": [
+					{"name": "peak", "days": "weekday", "start": "14:00", "end": "19:00", "rate": 0.30},
+					{"name": "overnight", "start": "22:00", "end": "06:00", "rate": 0.05}
+				]
+			},
+			"demand": {"rate_per_kw": 10}
+		},
+		{
+			"name": "winter",
+			"energy": {
+				"type": "tiered",
+				"tiers": [{"up_to_kwh": 10, "rate": 0.10}, {"rate": 0.20}]
+			}
+		}
+	]
+}`
+
+func TestParseInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"no_seasons":   `{"name": "empty"}`,
+		"bad_type":     `{"seasons": [{"energy": {"type": "free"}}]}`,
+		"bad_clock":    `{"seasons": [{"energy": {"type": "tou", "periods": [{"start": "2pm", "end": "19:00"}]}}]}`,
+		"late_clock":   `{"seasons": [{"energy": {"type": "tou", "periods": [{"start": "14:00", "end": "24:59"}]}}]}`,
+		"bad_tiers":    `{"seasons": [{"energy": {"type": "tiered", "tiers": [{"up_to_kwh": 10}, {"up_to_kwh": 5}, {}]}}]}`,
+		"bad_billing":  `{"billing_day": 31, "seasons": [{"energy": {"type": "flat"}}]}`,
+		"bad_timezone": `{"timezone": "Mars/Olympus", "seasons": [{"energy": {"type": "flat"}}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(body))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEnergyRate(t *testing.T) {
+	tariff, err := Parse([]byte(example))
+	require.NoError(t, err)
+
+	loc := tariff.Location()
+	for _, tc := range []struct {
+		name string
+		at   time.Time
+		used float64
+		rate float64
+	}{
+		{"summer_weekday_peak", time.Date(2018, 7, 5, 15, 0, 0, 0, loc), 0, 0.30},
+		{"summer_weekend_afternoon", time.Date(2018, 7, 7, 15, 0, 0, 0, loc), 0, 0.10},
+		{"summer_holiday_afternoon", time.Date(2018, 7, 4, 15, 0, 0, 0, loc), 0, 0.10},
+		{"summer_overnight_wraps", time.Date(2018, 7, 5, 2, 0, 0, 0, loc), 0, 0.05},
+		{"winter_first_tier", time.Date(2018, 12, 5, 15, 0, 0, 0, loc), 5, 0.10},
+		{"winter_second_tier", time.Date(2018, 12, 5, 15, 0, 0, 0, loc), 10, 0.20},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, _ := tariff.EnergyRate(tc.at, tc.used)
+			assert.Equal(t, tc.rate, rate)
+		})
+	}
+}
+
+func TestCalculator(t *testing.T) {
+	tariff, err := Parse([]byte(example))
+	require.NoError(t, err)
+
+	loc := tariff.Location()
+	start := time.Date(2018, 12, 5, 12, 0, 0, 0, loc)
+
+	c := NewCalculator(tariff)
+	c.Add(start, 100, 1)
+	c.Add(start.Add(time.Hour), 108, 1)
+	//crosses the tier boundary, 2 kWh at 0.10 and 4 at 0.20
+	c.Add(start.Add(time.Hour*2), 114, 1)
+	//ignored, out of order
+	c.Add(start.Add(time.Hour), 200, 1)
+
+	totals := c.Totals()
+	assert.Equal(t, time.Date(2018, 11, 15, 0, 0, 0, 0, loc), totals.PeriodStart)
+	assert.InDelta(t, 14.0, totals.PeriodEnergy, 0.0001)
+	assert.InDelta(t, 1.8, totals.PeriodEnergyCost, 0.0001)
+	assert.Equal(t, 0.20, totals.CurrentRate)
+	assert.Equal(t, 0.0, totals.PeriodDemandCost, "winter has no demand charge")
+
+	//new billing period on a summer weekday afternoon, meter counter reset
+	summer := time.Date(2019, 7, 15, 15, 0, 0, 0, loc)
+	c.Add(summer, 2, 5)
+	c.Add(summer.Add(time.Hour), 4, 3)
+
+	totals = c.Totals()
+	assert.InDelta(t, 1.8+1.2, totals.EnergyCost, 0.0001, "reset counter counts from 0")
+	assert.InDelta(t, 4.0, totals.PeriodEnergy, 0.0001)
+	assert.Equal(t, 5.0, totals.PeriodPeakDemand)
+	assert.Equal(t, 50.0, totals.PeriodDemandCost)
+	assert.InDelta(t, 51.2, totals.PeriodCost(), 0.0001)
+}
+
+func TestSeed(t *testing.T) {
+	tariff, err := Parse([]byte(example))
+	require.NoError(t, err)
+
+	loc := tariff.Location()
+	start := time.Date(2018, 12, 5, 12, 0, 0, 0, loc)
+
+	c := NewCalculator(tariff)
+	c.Seed([]storage.Aggregate{
+		{Start: start, DeliveredDelta: 8},
+		{Start: start.Add(time.Hour), DeliveredDelta: 6},
+	})
+
+	totals := c.Totals()
+	assert.InDelta(t, 14.0, totals.PeriodEnergy, 0.0001)
+	assert.InDelta(t, 1.8, totals.PeriodEnergyCost, 0.0001)
+	assert.Equal(t, 0.0, totals.EnergyCost, "only energy since startup")
+
+	//live readings carry on in the next tier
+	c.Add(start.Add(time.Hour*2), 100, 0)
+	c.Add(start.Add(time.Hour*3), 101, 0)
+	assert.InDelta(t, 2.0, c.Totals().PeriodEnergyCost, 0.0001)
+}
@@ -0,0 +1,220 @@
+/*
+Package tlsreload serves tls from certificate files that are reloaded when they change, so renewing a certificate does
+not need a restart.
+
+The files are checked on an interval rather than watched, which works the same for files that are replaced, rewritten
+in place or are symlinks swapped by kubernetes.  A change that can't be loaded, such as a certificate written before
+its key, keeps the current files in use until a later check can load them.
+*/
+package tlsreload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	//Config is the files to serve tls from
+	Config struct {
+		CertFile string `json:"cert_file"`
+		KeyFile  string `json:"key_file"`
+
+		//ClientCAFile verifies client certificates that are presented, none are asked for if it is empty
+		ClientCAFile string `json:"client_ca_file,omitempty"`
+		//RequireClientCert refuses connections without a verified client certificate
+		RequireClientCert bool `json:"require_client_cert,omitempty"`
+	}
+
+	//Reloader is the tls config from the current files
+	Reloader struct {
+		config Config
+
+		mu       sync.RWMutex
+		current  *tls.Config
+		leaf     *x509.Certificate
+		versions map[string]version
+
+		//Reloaded is told about every reload that Watch does or fails to do
+		Reloaded func(err error)
+	}
+
+	//version is how a change is noticed, both are used as a copy can keep the modification time
+	version struct {
+		modTime time.Time
+		size    int64
+	}
+)
+
+//New loads the files, which have to be valid to start with
+func New(config Config) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("tls needs a cert_file and key_file")
+	}
+
+	if config.RequireClientCert && config.ClientCAFile == "" {
+		return nil, errors.New("require_client_cert needs a client_ca_file to verify them with")
+	}
+
+	r := &Reloader{config: config}
+	_, err := r.Reload()
+	return r, err
+}
+
+//TLSConfig is for a server, every handshake uses the files loaded at the time
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.loaded().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.loaded(), nil
+		},
+	}
+}
+
+//NotAfter is when the current certificate expires
+func (r *Reloader) NotAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.leaf.NotAfter
+}
+
+//Watch reloads the files when they change until the context is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if (reloaded || err != nil) && r.Reloaded != nil {
+				r.Reloaded(err)
+			}
+		}
+	}
+}
+
+//Reload loads the files if they have changed since they were last loaded, it is false if they have not
+func (r *Reloader) Reload() (bool, error) {
+	versions, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	changed := !sameVersions(r.versions, versions)
+	r.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	config, leaf, err := r.load()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = config
+	r.leaf = leaf
+	r.versions = versions
+	return true, nil
+}
+
+func (r *Reloader) loaded() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+
+	return files
+}
+
+func (r *Reloader) stat() (map[string]version, error) {
+	versions := make(map[string]version)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		versions[file] = version{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return versions, nil
+}
+
+func (r *Reloader) load() (*tls.Config, *x509.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if r.config.ClientCAFile == "" {
+		return config, leaf, nil
+	}
+
+	pem, err := ioutil.ReadFile(r.config.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("no certificates in %s", r.config.ClientCAFile)
+	}
+
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if r.config.RequireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, leaf, nil
+}
+
+func sameVersions(a map[string]version, b map[string]version) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for file, v := range a {
+		other, ok := b[file]
+		if !ok || !v.modTime.Equal(other.modTime) || v.size != other.size {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,220 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+//newCert is signed by parent, or self signed if it is nil
+func newCert(t *testing.T, name string, parent *testCert, ca bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile string, keyFile string) {
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	}
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func serve(t *testing.T, r *Reloader) (string, func()) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	require.NoError(t, err)
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(req.TLS.VerifiedChains) > 0 {
+				w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+			}
+		}),
+		//failed handshakes are expected
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go srv.Serve(listener)
+
+	return "https://" + listener.Addr().String(), func() { srv.Close() }
+}
+
+//get presents the certificate if there is one, even when the server would not accept it
+func get(url string, roots *x509.Certificate, certs ...tls.Certificate) (string, string, error) {
+	pool := x509.NewCertPool()
+	pool.AddCert(roots)
+
+	config := &tls.Config{
+		RootCAs: pool,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if len(certs) == 0 {
+				return &tls.Certificate{}, nil
+			}
+			return &certs[0], nil
+		},
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, string(body), err
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := Config{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+
+	_, err = New(config)
+	assert.Error(t, err, "the files have to exist to start")
+
+	ca := newCert(t, "ca", nil, true)
+	first := newCert(t, "first", ca, false)
+	first.write(t, config.CertFile, config.KeyFile)
+
+	r, err := New(config)
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.NotAfter, r.NotAfter())
+
+	url, stop := serve(t, r)
+	defer stop()
+
+	name, _, err := get(url, ca.cert)
+	require.NoError(t, err)
+	assert.Equal(t, "first", name)
+
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "nothing changed")
+
+	//a certificate without its key is not used
+	second := newCert(t, "second", ca, false)
+	second.write(t, config.CertFile, "")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(config.CertFile, later, later))
+
+	_, err = r.Reload()
+	assert.Error(t, err)
+
+	name, _, err = get(url, ca.cert)
+	require.NoError(t, err)
+	assert.Equal(t, "first", name, "kept until the files can be loaded")
+
+	second.write(t, config.CertFile, config.KeyFile)
+	require.NoError(t, os.Chtimes(config.KeyFile, later, later))
+
+	reloaded, err = r.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	name, _, err = get(url, ca.cert)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name)
+}
+
+func TestClientCerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := Config{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+
+	ca := newCert(t, "ca", nil, true)
+	ca.write(t, config.ClientCAFile, "")
+	newCert(t, "server", ca, false).write(t, config.CertFile, config.KeyFile)
+
+	client := newCert(t, "client", ca, false)
+	stranger := newCert(t, "stranger", newCert(t, "other ca", nil, true), false)
+
+	r, err := New(config)
+	require.NoError(t, err)
+
+	url, stop := serve(t, r)
+	defer stop()
+
+	_, verified, err := get(url, ca.cert, client.tls())
+	require.NoError(t, err)
+	assert.Equal(t, "client", verified)
+
+	_, verified, err = get(url, ca.cert)
+	require.NoError(t, err)
+	assert.Equal(t, "", verified, "certificates are optional")
+
+	_, _, err = get(url, ca.cert, stranger.tls())
+	assert.Error(t, err, "certificates that are presented have to verify")
+
+	config.RequireClientCert = true
+	r, err = New(config)
+	require.NoError(t, err)
+
+	url, stop = serve(t, r)
+	defer stop()
+
+	_, _, err = get(url, ca.cert)
+	assert.Error(t, err)
+
+	_, verified, err = get(url, ca.cert, client.tls())
+	require.NoError(t, err)
+	assert.Equal(t, "client", verified)
+}
@@ -0,0 +1,181 @@
+package uploader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kklipsch/reagle/local"
+)
+
+//zigbeeEpoch is what fragment timestamps count seconds from
+var zigbeeEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type (
+	//Update is what a push contained, the readings it did not contain are nil
+	Update struct {
+		MacID      string
+		MeterMacID string
+		Time       time.Time
+
+		//Demand is in kW, Delivered and Received in kWh
+		Demand    *float64
+		Delivered *float64
+		Received  *float64
+
+		Price    *float64
+		Currency string
+	}
+
+	//message is a push, the root element is rainforest or rainForest depending on the firmware so it is not checked
+	message struct {
+		MacID     string `xml:"macId,attr"`
+		Timestamp string `xml:"timestamp,attr"`
+
+		InstantaneousDemand *instantaneousDemand `xml:"InstantaneousDemand"`
+		CurrentSummation    *currentSummation    `xml:"CurrentSummationDelivered"`
+		PriceCluster        *priceCluster        `xml:"PriceCluster"`
+	}
+
+	fragment struct {
+		DeviceMacID string       `xml:"DeviceMacId"`
+		MeterMacID  string       `xml:"MeterMacId"`
+		TimeStamp   local.HexInt `xml:"TimeStamp"`
+	}
+
+	instantaneousDemand struct {
+		fragment
+		//demand is a signed 24 bit integer, so it has to be parsed knowing its width
+		Demand     string       `xml:"Demand"`
+		Multiplier local.HexInt `xml:"Multiplier"`
+		Divisor    local.HexInt `xml:"Divisor"`
+	}
+
+	currentSummation struct {
+		fragment
+		SummationDelivered local.HexInt `xml:"SummationDelivered"`
+		SummationReceived  local.HexInt `xml:"SummationReceived"`
+		Multiplier         local.HexInt `xml:"Multiplier"`
+		Divisor            local.HexInt `xml:"Divisor"`
+	}
+
+	priceCluster struct {
+		fragment
+		Price          local.HexInt `xml:"Price"`
+		Currency       local.HexInt `xml:"Currency"`
+		TrailingDigits local.HexInt `xml:"TrailingDigits"`
+	}
+)
+
+//Parse reads a push.  Fragments other than InstantaneousDemand, CurrentSummationDelivered and PriceCluster are ignored
+func Parse(r io.Reader) (Update, error) {
+	m := message{}
+	err := xml.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return Update{}, fmt.Errorf("invalid push: %w", err)
+	}
+
+	u := Update{MacID: m.MacID, Time: rootTime(m.Timestamp)}
+
+	if d := m.InstantaneousDemand; d != nil {
+		raw, err := parseSigned(d.Demand)
+		if err != nil {
+			return u, fmt.Errorf("invalid demand %s: %w", d.Demand, err)
+		}
+
+		demand := scale(raw, d.Multiplier, d.Divisor)
+		u.Demand = &demand
+		u.observe(d.fragment)
+	}
+
+	if s := m.CurrentSummation; s != nil {
+		delivered := scale(int64(s.SummationDelivered), s.Multiplier, s.Divisor)
+		received := scale(int64(s.SummationReceived), s.Multiplier, s.Divisor)
+		u.Delivered, u.Received = &delivered, &received
+		u.observe(s.fragment)
+	}
+
+	if p := m.PriceCluster; p != nil {
+		price := float64(p.Price)
+		for i := int64(0); i < int64(p.TrailingDigits); i++ {
+			price /= 10
+		}
+
+		u.Price = &price
+		u.Currency = currency(int64(p.Currency))
+		u.observe(p.fragment)
+	}
+
+	return u, nil
+}
+
+//observe takes the meter and time from the fragment, the fragment's time is when the meter read it so it is
+//preferred over when the eagle sent it
+func (u *Update) observe(f fragment) {
+	if f.MeterMacID != "" {
+		u.MeterMacID = f.MeterMacID
+	}
+
+	if f.TimeStamp > 0 {
+		u.Time = zigbeeEpoch.Add(time.Duration(f.TimeStamp) * time.Second)
+	}
+}
+
+//rootTime parses the root element's timestamp, unix seconds with an s suffix (1485900958s)
+func rootTime(raw string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(raw), "s"), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
+
+//scale applies the multiplier and divisor, a zero for either is treated as 1 as the zigbee spec says
+func scale(raw int64, multiplier local.HexInt, divisor local.HexInt) float64 {
+	if multiplier == 0 {
+		multiplier = 1
+	}
+
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	return float64(raw) * float64(multiplier) / float64(divisor)
+}
+
+//parseSigned parses a two's complement hex integer whose width is its number of digits, 0xffff9c is -100
+func parseSigned(raw string) (int64, error) {
+	digits := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "0x")
+	if digits == "" {
+		return 0, nil
+	}
+
+	if len(digits) > 16 {
+		return 0, fmt.Errorf("too many digits")
+	}
+
+	u, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	bits := uint(len(digits) * 4)
+	if bits < 64 && u >= 1<<(bits-1) {
+		return int64(u) - 1<<bits, nil
+	}
+
+	return int64(u), nil
+}
+
+//currency is the alphabetic code when local knows it, others are kept as the number
+func currency(code int64) string {
+	if c, ok := local.CurrencyCode(code); ok {
+		return c
+	}
+
+	return strconv.FormatInt(code, 10)
+}
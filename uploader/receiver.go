@@ -0,0 +1,155 @@
+/*
+Package uploader receives the readings an Eagle pushes to its cloud uploader.
+
+Pushes
+
+When the eagle's uploader is pointed at a url it posts an XML document for each fragment the meter sends, such as
+
+	<rainforest macId="0xd8d5b9000000103f" timestamp="1485900958s">
+	  <InstantaneousDemand>
+	    <MeterMacId>0x000781000086d0fe</MeterMacId>
+	    <TimeStamp>0x2019d06a</TimeStamp>
+	    <Demand>0x000104</Demand>
+	    <Multiplier>0x00000001</Multiplier>
+	    <Divisor>0x000003e8</Divisor>
+	  </InstantaneousDemand>
+	</rainforest>
+
+Values are hex and have the multiplier and divisor applied, fragment timestamps are seconds since 2000-01-01 UTC.
+Demand and summation arrive in separate pushes so the Receiver keeps the latest of each, and publishes a Sample once it
+has seen both.
+*/
+package uploader
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+)
+
+//maxPushBytes is far more than any fragment, it only stops a misbehaving sender
+const maxPushBytes = 64 * 1024
+
+type (
+	//Config is the basic auth credentials the eagle's uploader is configured with
+	Config struct {
+		Username string `json:"username"`
+		Password string `json:"-"`
+	}
+
+	//Receiver is an http.Handler for pushes, it publishes a Sample for every push that changes demand or summation
+	Receiver struct {
+		config  Config
+		publish client.Sink
+
+		mu            sync.Mutex
+		metrics       client.BaseMetrics
+		haveDemand    bool
+		haveSummation bool
+		last          time.Time
+
+		//Result is called with every push, whether it was published and any error
+		Result func(u Update, published bool, err error)
+
+		now func() time.Time
+	}
+)
+
+//NewReceiver creates a Receiver that only accepts pushes with the config's credentials
+func NewReceiver(config Config, publish client.Sink) (*Receiver, error) {
+	if config.Password == "" {
+		return nil, fmt.Errorf("the uploader receiver needs a password")
+	}
+
+	return &Receiver{config: config, publish: publish, now: time.Now}, nil
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "pushes must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.authorized(req) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="reagled"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	u, err := Parse(io.LimitReader(req.Body, maxPushBytes))
+	if err != nil {
+		r.result(u, false, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	published := r.Apply(req.Context(), u)
+	r.result(u, published, nil)
+	w.WriteHeader(http.StatusOK)
+}
+
+//Apply updates the latest readings with the update and publishes them if it is a new demand or summation reading
+func (r *Receiver) Apply(ctx context.Context, u Update) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u.Time.IsZero() {
+		u.Time = r.now()
+	}
+
+	if u.Price != nil {
+		r.metrics.Price = *u.Price
+		r.metrics.Currency = u.Currency
+	}
+
+	if u.Demand != nil {
+		r.metrics.Demand = *u.Demand
+		r.haveDemand = true
+	}
+
+	if u.Delivered != nil {
+		r.metrics.Delivered = *u.Delivered
+		r.metrics.Received = *u.Received
+		r.haveSummation = true
+	}
+
+	//price alone does not make a reading and the eagle can resend old fragments after reconnecting
+	if u.Demand == nil && u.Delivered == nil {
+		return false
+	}
+
+	if !r.haveDemand || !r.haveSummation || !u.Time.After(r.last) {
+		return false
+	}
+
+	r.last = u.Time
+
+	//published under the lock so sinks see samples in order
+	r.publish(ctx, client.Sample{Time: u.Time, Metrics: r.metrics})
+	return true
+}
+
+func (r *Receiver) authorized(req *http.Request) bool {
+	user, password, ok := req.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	//both are compared so the time does not say which was wrong
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(r.config.Username)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(r.config.Password)) == 1
+	return userOK && passwordOK
+}
+
+func (r *Receiver) result(u Update, published bool, err error) {
+	if r.Result != nil {
+		r.Result(u, published, err)
+	}
+}
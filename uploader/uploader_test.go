@@ -0,0 +1,149 @@
+package uploader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/reagle/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	demandPush = `<?xml version="1.0"?>
+<rainForest macId="0xd8d5b9000000103f" version="undefined" timestamp="1485900958s">
+<InstantaneousDemand>
+  <DeviceMacId>0xd8d5b9000000103f</DeviceMacId>
+  <MeterMacId>0x000781000086d0fe</MeterMacId>
+  <TimeStamp>0x2019d06a</TimeStamp>
+  <Demand>0x000104</Demand>
+  <Multiplier>0x00000001</Multiplier>
+  <Divisor>0x000003e8</Divisor>
+  <DigitsRight>0x03</DigitsRight>
+  <DigitsLeft>0x06</DigitsLeft>
+  <SuppressLeadingZero>Y</SuppressLeadingZero>
+</InstantaneousDemand>
+</rainForest>`
+
+	summationPush = `<rainforest macId="0xd8d5b9000000103f" timestamp="1485900960s">
+<CurrentSummationDelivered>
+  <MeterMacId>0x000781000086d0fe</MeterMacId>
+  <TimeStamp>0x2019d06c</TimeStamp>
+  <SummationDelivered>0x0000000001321a5f</SummationDelivered>
+  <SummationReceived>0x00000000000003e8</SummationReceived>
+  <Multiplier>0x00000001</Multiplier>
+  <Divisor>0x000003e8</Divisor>
+</CurrentSummationDelivered>
+</rainforest>`
+
+	pricePush = `<rainforest macId="0xd8d5b9000000103f" timestamp="1485900961s">
+<PriceCluster>
+  <TimeStamp>0x2019d06d</TimeStamp>
+  <Price>0x0000045c</Price>
+  <Currency>0x0348</Currency>
+  <TrailingDigits>0x04</TrailingDigits>
+  <Tier>0x01</Tier>
+</PriceCluster>
+</rainforest>`
+)
+
+func TestParse(t *testing.T) {
+	u, err := Parse(strings.NewReader(demandPush))
+	require.NoError(t, err)
+	assert.Equal(t, "0xd8d5b9000000103f", u.MacID)
+	assert.Equal(t, "0x000781000086d0fe", u.MeterMacID)
+	assert.Equal(t, zigbeeEpoch.Add(0x2019d06a*time.Second), u.Time, "the fragment's time, not the root's")
+	require.NotNil(t, u.Demand)
+	assert.InDelta(t, 0.26, *u.Demand, 0.0001)
+	assert.Nil(t, u.Delivered)
+
+	u, err = Parse(strings.NewReader(summationPush))
+	require.NoError(t, err)
+	assert.Nil(t, u.Demand)
+	require.NotNil(t, u.Delivered)
+	assert.InDelta(t, 20060.767, *u.Delivered, 0.0001)
+	assert.InDelta(t, 1, *u.Received, 0.0001)
+
+	u, err = Parse(strings.NewReader(pricePush))
+	require.NoError(t, err)
+	require.NotNil(t, u.Price)
+	assert.InDelta(t, 0.1116, *u.Price, 0.00001)
+	assert.Equal(t, "USD", u.Currency)
+
+	u, err = Parse(strings.NewReader(`<rainforest timestamp="1485900958s"><NetworkInfo><Status>Connected</Status></NetworkInfo></rainforest>`))
+	require.NoError(t, err, "unknown fragments are ignored")
+	assert.Equal(t, time.Unix(1485900958, 0).UTC(), u.Time)
+	assert.Nil(t, u.Demand)
+
+	_, err = Parse(strings.NewReader(`<rainforest><InstantaneousDemand><Demand>0xzz</Demand></InstantaneousDemand></rainforest>`))
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader(`not xml`))
+	assert.Error(t, err)
+}
+
+func TestParseSigned(t *testing.T) {
+	cases := map[string]int64{
+		"0x000104":   260,
+		"0xffff9c":   -100,
+		"0x7fffff":   8388607,
+		"0xffffff9c": -100,
+		"0x00ffff9c": 16777116,
+		"":           0,
+	}
+
+	for raw, expected := range cases {
+		actual, err := parseSigned(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, actual, raw)
+	}
+}
+
+func push(t *testing.T, r *Receiver, user string, password string, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/uploader", strings.NewReader(body))
+	if password != "" {
+		req.SetBasicAuth(user, password)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestReceiver(t *testing.T) {
+	var samples []client.Sample
+	r, err := NewReceiver(Config{Username: "eagle", Password: "secret"}, func(ctx context.Context, s client.Sample) {
+		samples = append(samples, s)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, push(t, r, "", "", demandPush))
+	assert.Equal(t, http.StatusUnauthorized, push(t, r, "eagle", "wrong", demandPush))
+	assert.Equal(t, http.StatusBadRequest, push(t, r, "eagle", "secret", "not xml"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/uploader", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	assert.Equal(t, http.StatusOK, push(t, r, "eagle", "secret", pricePush))
+	assert.Equal(t, http.StatusOK, push(t, r, "eagle", "secret", demandPush))
+	assert.Empty(t, samples, "nothing is published until summation has been seen too")
+
+	assert.Equal(t, http.StatusOK, push(t, r, "eagle", "secret", summationPush))
+	require.Len(t, samples, 1)
+	assert.Equal(t, zigbeeEpoch.Add(0x2019d06c*time.Second), samples[0].Time)
+	assert.InDelta(t, 0.26, samples[0].Metrics.Demand, 0.0001)
+	assert.InDelta(t, 20060.767, samples[0].Metrics.Delivered, 0.0001)
+	assert.InDelta(t, 0.1116, samples[0].Metrics.Price, 0.00001)
+	assert.Equal(t, "USD", samples[0].Metrics.Currency)
+
+	assert.Equal(t, http.StatusOK, push(t, r, "eagle", "secret", demandPush))
+	assert.Len(t, samples, 1, "an older fragment is not published")
+
+	_, err = NewReceiver(Config{Username: "eagle"}, nil)
+	assert.Error(t, err)
+}